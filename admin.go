@@ -0,0 +1,150 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/CADawg/hive-swap-calculator/engine"
+	"github.com/CADawg/hive-swap-calculator/gateway"
+	"github.com/CADawg/hive-swap-calculator/market"
+	"github.com/CADawg/hive-swap-calculator/pricing"
+	"github.com/goccy/go-json"
+)
+
+const (
+	// StagePrices fetches the prices from CoinGecko
+	StagePrices = "prices"
+	// StageFees looks up the withdrawal fees and works out every token's fee
+	StageFees = "fees"
+)
+
+// APIAdminRefresh is what the refresh endpoint sends back
+type APIAdminRefresh struct {
+	Stages []string `json:"stages" doc:"Sources that will be polled now"`
+}
+
+// APIAdminGatewayTokens is what the gateway token reload endpoint sends back
+type APIAdminGatewayTokens struct {
+	Tokens int `json:"tokens" doc:"Number of tokens in the reloaded gateway lists (including the built in ones)"`
+}
+
+// APIAdminState is a dump of the caches behind the api, it's for debugging so it isn't covered by any compatibility promise
+type APIAdminState struct {
+	Ready             bool   `json:"ready" doc:"Whether the withdrawal fees have been looked up (or loaded from disk) at least once"`
+	SnapshotVersion   uint64 `json:"snapshot_version" doc:"Current snapshot version"`
+	SnapshotUpdatedAt int64  `json:"snapshot_updated_at" doc:"Unix time the current snapshot was published"`
+	SnapshotStale     bool   `json:"snapshot_stale" doc:"Whether some of the current snapshot is still from the one loaded from disk after a restart"`
+
+	TokenNetworkDataStore []gateway.TokenNetworkData  `json:"token_network_data_store" doc:"Gateway tokens with their withdrawal network and flat fee"`
+	Prices                []pricing.TokenData         `json:"prices" doc:"Prices in the current snapshot, before the HBD rate, fees and orders were added"`
+	HBDRate               string                      `json:"hbd_rate" doc:"HIVE/HBD rate in the current snapshot"`
+	Fees                  map[string]gateway.TokenFee `json:"fees" doc:"Fees in the current snapshot, by symbol"`
+	SellBook              []engine.MarketOrder        `json:"sell_book" doc:"Every SWAP. sell order in the current snapshot (only the ones past the reference price on a replica)"`
+	BuyBook               []engine.MarketOrder        `json:"buy_book" doc:"Every SWAP. buy order in the current snapshot (only the ones past the reference price on a replica)"`
+
+	EventSubscribers []APIEventSubscriber `json:"event_subscribers" doc:"What's listening on the internal event bus, and how it's keeping up"`
+}
+
+// APIEventSubscriber is one subscriber to the internal event bus
+type APIEventSubscriber struct {
+	Name       string   `json:"name" doc:"What the subscriber is"`
+	Types      []string `json:"types" doc:"Events it gets, all of them if empty"`
+	DropPolicy string   `json:"drop_policy" doc:"drop_oldest or drop_newest, what happens to events when its queue is full"`
+	QueueSize  int      `json:"queue_size" doc:"How many events can wait for it"`
+	Queued     int      `json:"queued" doc:"How many events are waiting for it now"`
+	Dropped    uint64   `json:"dropped" doc:"How many events it's missed for being too slow"`
+}
+
+// AdminRoutes are the routes for running the server, they all need the admin scope
+func AdminRoutes() []APIRoute {
+	return []APIRoute{
+		{
+			Path:        APIV1 + "/admin/refresh",
+			Method:      http.MethodPost,
+			Summary:     "Refresh now instead of waiting for the next cycle",
+			Description: "Handy when an upstream has recovered. Returns straight away, the refresh happens in the background.",
+			Parameters: []APIParameter{
+				{Name: "stages", In: "query", Type: "string", Description: "Comma separated sources to poll now (" + strings.Join(AllSources, ", ") + "), all of them if left out"},
+			},
+			Response:       APIAdminRefresh{},
+			Scope:          ScopeAdmin,
+			RateLimitClass: RateLimitDefault,
+			Handler:        handleAdminRefresh,
+		},
+		{
+			Path:           APIV1 + "/admin/state",
+			Summary:        "Dump the internal caches",
+			Response:       APIAdminState{},
+			Scope:          ScopeAdmin,
+			RateLimitClass: RateLimitDefault,
+			Handler:        handleAdminState,
+		},
+		{
+			Path:           APIV1 + "/admin/gateway-tokens/reload",
+			Method:         http.MethodPost,
+			Summary:        "Reload the gateway token lists",
+			Description:    "Fetches the lists again and swaps them in (keeping the fees we already know), then refreshes the fees.",
+			Response:       APIAdminGatewayTokens{},
+			Scope:          ScopeAdmin,
+			RateLimitClass: RateLimitDefault,
+			Handler:        handleAdminReloadGatewayTokens,
+		},
+	}
+}
+
+func handleAdminRefresh(w http.ResponseWriter, r *http.Request) {
+	stages, err := RefreshScheduler.Trigger(market.SplitList(r.URL.Query().Get("stages")))
+
+	if err != nil {
+		WriteAPIError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+
+	_ = json.NewEncoder(w).Encode(APIAdminRefresh{Stages: stages})
+}
+
+func handleAdminState(w http.ResponseWriter, r *http.Request) {
+	snapshot := CurrentSnapshot()
+
+	state := APIAdminState{
+		SnapshotVersion: snapshot.Version,
+		SnapshotStale:   snapshot.Stale,
+		Prices:          snapshot.Prices,
+		HBDRate:         snapshot.HBDRate.String(),
+		Fees:            snapshot.Fees,
+		SellBook:        snapshot.SellBook,
+		BuyBook:         snapshot.BuyBook,
+	}
+
+	if !snapshot.UpdatedAt.IsZero() {
+		state.SnapshotUpdatedAt = snapshot.UpdatedAt.Unix()
+	}
+
+	state.TokenNetworkDataStore, state.Ready = WithdrawalFees.Tokens()
+
+	state.EventSubscribers = []APIEventSubscriber{}
+
+	for _, stats := range Events.Stats() {
+		state.EventSubscribers = append(state.EventSubscribers, APIEventSubscriber(stats))
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+
+	writeJSON(w, state)
+}
+
+func handleAdminReloadGatewayTokens(w http.ResponseWriter, r *http.Request) {
+	count, err := WithdrawalFees.Reload()
+
+	if err != nil {
+		WriteAPIError(w, http.StatusBadGateway, "error reloading gateway tokens: "+err.Error())
+		return
+	}
+
+	_, _ = RefreshScheduler.Trigger([]string{SourceFees})
+
+	writeJSON(w, APIAdminGatewayTokens{Tokens: count})
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/goccy/go-json"
+)
+
+// adminTestMux serves the routes with a key store holding an admin key and a read_prices key
+func adminTestMux(t *testing.T) (mux *http.ServeMux, admin string, prices string) {
+	t.Helper()
+
+	store, path := newTestKeyStore(t)
+
+	admin, _, err := IssueAPIKey(path, "admin", []string{ScopeAdmin}, nil)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	prices, _, err = IssueAPIKey(path, "prices", []string{ScopeReadPrices}, nil)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = store.Reload(); err != nil {
+		t.Fatal(err)
+	}
+
+	previous := APIKeys
+	APIKeys = store
+	t.Cleanup(func() { APIKeys = previous })
+
+	return NewServeMux(NewRateLimiter()), admin, prices
+}
+
+func TestAdminRoutesNeedTheAdminScope(t *testing.T) {
+	mux, admin, prices := adminTestMux(t)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		key    string
+		want   int
+	}{
+		{"state without a key", "GET", "/api/v1/admin/state", "", http.StatusUnauthorized},
+		{"state with a prices key", "GET", "/api/v1/admin/state", prices, http.StatusForbidden},
+		{"state with an admin key", "GET", "/api/v1/admin/state", admin, http.StatusOK},
+		{"refresh without a key", "POST", "/api/v1/admin/refresh", "", http.StatusUnauthorized},
+		{"refresh with a prices key", "POST", "/api/v1/admin/refresh", prices, http.StatusForbidden},
+		{"refresh needs a post", "GET", "/api/v1/admin/refresh", admin, http.StatusMethodNotAllowed},
+		{"reload with a prices key", "POST", "/api/v1/admin/gateway-tokens/reload", prices, http.StatusForbidden},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := httptest.NewRequest(test.method, test.path, nil)
+
+			if test.key != "" {
+				r.Header.Set(APIKeyHeader, test.key)
+			}
+
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, r)
+
+			if w.Code != test.want {
+				t.Fatalf("got %d, want %d: %s", w.Code, test.want, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestAdminRefresh(t *testing.T) {
+	mux, admin, _ := adminTestMux(t)
+
+	refresh := func(query string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("POST", "/api/v1/admin/refresh"+query, nil)
+		r.Header.Set(APIKeyHeader, admin)
+
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+
+		return w
+	}
+
+	if w := refresh("?stages=prices,nope"); w.Code != http.StatusBadRequest {
+		t.Fatalf("unknown stage got %d, want %d", w.Code, http.StatusBadRequest)
+	}
+
+	// push everything out so we can see what the refresh made due
+	now := time.Now()
+
+	for _, source := range AllSources {
+		RefreshScheduler.Done(source, SourceResult{}, false, now)
+	}
+
+	w := refresh("?stages=books")
+
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("got %d, want %d: %s", w.Code, http.StatusAccepted, w.Body.String())
+	}
+
+	var response APIAdminRefresh
+
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(response.Stages) != 1 || response.Stages[0] != SourceBooks {
+		t.Fatalf("stages = %v, want [%s]", response.Stages, SourceBooks)
+	}
+
+	if due := RefreshScheduler.Due(now); len(due) != 1 || due[0] != SourceBooks {
+		t.Fatalf("due = %v, want [%s]", due, SourceBooks)
+	}
+
+	// with no stages everything is polled
+	if err := json.Unmarshal(refresh("").Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(response.Stages) != len(AllSources) {
+		t.Fatalf("stages = %v, want all of them", response.Stages)
+	}
+}
+
+func TestAdminState(t *testing.T) {
+	mux, admin, _ := adminTestMux(t)
+
+	PublishSnapshot(&Snapshot{UpdatedAt: time.Now()})
+	snapshot := CurrentSnapshot()
+
+	r := httptest.NewRequest("GET", "/api/v1/admin/state", nil)
+	r.Header.Set(APIKeyHeader, admin)
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("got %d: %s", w.Code, w.Body.String())
+	}
+
+	if cacheControl := w.Header().Get("Cache-Control"); cacheControl != "no-store" {
+		t.Fatalf("Cache-Control = %q, want no-store", cacheControl)
+	}
+
+	var state APIAdminState
+
+	if err := json.Unmarshal(w.Body.Bytes(), &state); err != nil {
+		t.Fatal(err)
+	}
+
+	if state.SnapshotVersion != snapshot.Version || state.SnapshotUpdatedAt != snapshot.UpdatedAt.Unix() {
+		t.Fatalf("state has version %d at %d, want %d at %d", state.SnapshotVersion, state.SnapshotUpdatedAt, snapshot.Version, snapshot.UpdatedAt.Unix())
+	}
+
+	// always a list, even with nothing subscribed
+	if state.EventSubscribers == nil {
+		t.Fatal("event_subscribers is null")
+	}
+}
@@ -0,0 +1,453 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/CADawg/hive-swap-calculator/market"
+	"github.com/goccy/go-json"
+)
+
+const (
+	ScopeReadPrices    = "read_prices"
+	ScopeComputeRoutes = "compute_routes"
+	ScopeAdmin         = "admin"
+)
+
+var AllScopes = []string{ScopeReadPrices, ScopeComputeRoutes, ScopeAdmin}
+
+// APIKeyHeader is where clients send their api key
+const APIKeyHeader = "X-API-Key"
+
+// the raw key is only ever shown once (when it's issued), we just keep its hash
+const apiKeyPrefix = "hsc_"
+
+// APIKey is a partner's key, with what it's allowed to do and how much it's been used
+type APIKey struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Hash      string    `json:"hash"`
+	Scopes    []string  `json:"scopes"`
+	CreatedAt time.Time `json:"created_at"`
+	// RevokedAt is set when the key is revoked, we keep the record so usage history isn't lost
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+	// RateLimits override the server's limits for this key (by endpoint class)
+	RateLimits map[string]RateLimit `json:"rate_limits,omitempty"`
+
+	Requests int64      `json:"requests"`
+	LastUsed *time.Time `json:"last_used,omitempty"`
+}
+
+func (k *APIKey) HasScope(scope string) bool {
+	return market.ContainsString(k.Scopes, scope)
+}
+
+// APIKeyStore keeps the keys from the keys file in memory, usage is counted here and written back every so often
+type APIKeyStore struct {
+	path string
+
+	lock sync.RWMutex
+	keys map[string]*APIKey // by hash
+	// fileHash is the sha256 of the keys file we last loaded, so we only rebuild the keys when it's really changed
+	fileHash [sha256.Size]byte
+	// usage since we last wrote to the file, by key id
+	pendingRequests map[string]int64
+	pendingLastUsed map[string]time.Time
+}
+
+func NewAPIKeyStore(path string) *APIKeyStore {
+	return &APIKeyStore{
+		path:            path,
+		keys:            map[string]*APIKey{},
+		pendingRequests: map[string]int64{},
+		pendingLastUsed: map[string]time.Time{},
+	}
+}
+
+// APIKeys is the store used by the server (set up in main)
+var APIKeys = NewAPIKeyStore("")
+
+func hashAPIKey(rawKey string) string {
+	sum := sha256.Sum256([]byte(rawKey))
+	return hex.EncodeToString(sum[:])
+}
+
+func readAPIKeysFile(path string) ([]*APIKey, error) {
+	keys, _, err := readAPIKeysFileWithHash(path)
+	return keys, err
+}
+
+// readAPIKeysFileWithHash is readAPIKeysFile plus the hash of what was read (a missing file is no keys)
+func readAPIKeysFileWithHash(path string) ([]*APIKey, [sha256.Size]byte, error) {
+	data, err := os.ReadFile(path)
+
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, [sha256.Size]byte{}, nil
+		}
+
+		return nil, [sha256.Size]byte{}, err
+	}
+
+	var keys []*APIKey
+
+	err = json.Unmarshal(data, &keys)
+
+	return keys, sha256.Sum256(data), err
+}
+
+// lockAPIKeysFile stops the server and the keys cli from changing the keys file at the same time. Each of them reads,
+// changes and rewrites the whole file, so without it one can undo the other (e.g. a usage flush bringing back a key
+// that was just revoked). The lock is on a file next to it since the keys file itself is replaced on every write.
+func lockAPIKeysFile(path string) (func(), error) {
+	file, err := os.OpenFile(path+".lock", os.O_CREATE|os.O_RDWR, 0600)
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = lockFile(file)
+
+	if err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+
+	return func() {
+		_ = unlockFile(file)
+		_ = file.Close()
+	}, nil
+}
+
+// writeAPIKeysFile replaces the file atomically so the server never reads half of it
+func writeAPIKeysFile(path string, keys []*APIKey) error {
+	data, err := json.MarshalIndent(keys, "", "  ")
+
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".apikeys-*")
+
+	if err != nil {
+		return err
+	}
+
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(data)
+
+	if err == nil {
+		err = tmp.Chmod(0600)
+	}
+
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+// Reload reads the keys file again if it's changed (e.g. the cli issued or revoked a key). It goes by what's in the
+// file rather than its mod time, a write can land within the same mod time as the last one.
+func (s *APIKeyStore) Reload() error {
+	if s.path == "" {
+		return nil
+	}
+
+	keys, hash, err := readAPIKeysFileWithHash(s.path)
+
+	if err != nil {
+		return err
+	}
+
+	s.lock.RLock()
+	unchanged := hash == s.fileHash
+	s.lock.RUnlock()
+
+	if unchanged {
+		return nil
+	}
+
+	byHash := make(map[string]*APIKey, len(keys))
+
+	s.lock.Lock()
+
+	for _, key := range keys {
+		// usage we haven't written yet still counts
+		key.Requests += s.pendingRequests[key.ID]
+		byHash[key.Hash] = key
+	}
+
+	s.keys = byHash
+	s.fileHash = hash
+	s.lock.Unlock()
+
+	return nil
+}
+
+// Flush adds the usage counted since the last flush to the keys file
+func (s *APIKeyStore) Flush() error {
+	if s.path == "" {
+		return nil
+	}
+
+	s.lock.Lock()
+	requests, lastUsed := s.pendingRequests, s.pendingLastUsed
+	s.pendingRequests, s.pendingLastUsed = map[string]int64{}, map[string]time.Time{}
+	s.lock.Unlock()
+
+	if len(requests) == 0 {
+		return nil
+	}
+
+	err := s.writeUsage(requests, lastUsed)
+
+	if err != nil {
+		// keep the usage for the next flush rather than losing it
+		s.lock.Lock()
+
+		for id, count := range requests {
+			s.pendingRequests[id] += count
+		}
+
+		for id, used := range lastUsed {
+			if used.After(s.pendingLastUsed[id]) {
+				s.pendingLastUsed[id] = used
+			}
+		}
+
+		s.lock.Unlock()
+
+		return err
+	}
+
+	// pick up the merged counts from our own write
+	return s.Reload()
+}
+
+func (s *APIKeyStore) writeUsage(requests map[string]int64, lastUsed map[string]time.Time) error {
+	unlock, err := lockAPIKeysFile(s.path)
+
+	if err != nil {
+		return err
+	}
+
+	defer unlock()
+
+	// read the file fresh so we don't undo anything the cli has done since we loaded it
+	keys, err := readAPIKeysFile(s.path)
+
+	if err != nil {
+		return err
+	}
+
+	for _, key := range keys {
+		key.Requests += requests[key.ID]
+
+		if used, ok := lastUsed[key.ID]; ok && (key.LastUsed == nil || used.After(*key.LastUsed)) {
+			key.LastUsed = &used
+		}
+	}
+
+	return writeAPIKeysFile(s.path, keys)
+}
+
+// Use looks up a raw key, counting the request against it if it's valid
+func (s *APIKeyStore) Use(rawKey string) (*APIKey, bool) {
+	hash := hashAPIKey(rawKey)
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	key, ok := s.keys[hash]
+
+	// the map lookup already matched, but compare properly anyway so timing doesn't leak anything
+	if !ok || subtle.ConstantTimeCompare([]byte(key.Hash), []byte(hash)) != 1 || key.RevokedAt != nil {
+		return nil, false
+	}
+
+	now := time.Now()
+
+	key.Requests++
+	key.LastUsed = &now
+	s.pendingRequests[key.ID]++
+	s.pendingLastUsed[key.ID] = now
+
+	keyCopy := *key
+
+	return &keyCopy, true
+}
+
+// Start keeps the store in sync with the keys file until the program exits
+func (s *APIKeyStore) Start(interval time.Duration) {
+	go func() {
+		for {
+			time.Sleep(interval)
+
+			err := s.Flush()
+
+			if err == nil {
+				err = s.Reload()
+			}
+
+			if err != nil {
+				fmt.Println("error syncing api keys:", err)
+			}
+		}
+	}()
+}
+
+// IssueAPIKey adds a new key to the keys file and returns the raw key (which can't be recovered later)
+func IssueAPIKey(path string, name string, scopes []string, rateLimits map[string]RateLimit) (string, *APIKey, error) {
+	for _, scope := range scopes {
+		if !market.ContainsString(AllScopes, scope) {
+			return "", nil, errors.New("unknown scope " + scope)
+		}
+	}
+
+	unlock, err := lockAPIKeysFile(path)
+
+	if err != nil {
+		return "", nil, err
+	}
+
+	defer unlock()
+
+	keys, err := readAPIKeysFile(path)
+
+	if err != nil {
+		return "", nil, err
+	}
+
+	secret := make([]byte, 32)
+	id := make([]byte, 6)
+
+	_, err = rand.Read(secret)
+
+	if err == nil {
+		_, err = rand.Read(id)
+	}
+
+	if err != nil {
+		return "", nil, err
+	}
+
+	rawKey := apiKeyPrefix + hex.EncodeToString(secret)
+
+	key := &APIKey{
+		ID:         hex.EncodeToString(id),
+		Name:       name,
+		Hash:       hashAPIKey(rawKey),
+		Scopes:     scopes,
+		CreatedAt:  time.Now().UTC(),
+		RateLimits: rateLimits,
+	}
+
+	err = writeAPIKeysFile(path, append(keys, key))
+
+	if err != nil {
+		return "", nil, err
+	}
+
+	return rawKey, key, nil
+}
+
+// RevokeAPIKey marks the key with id as revoked
+func RevokeAPIKey(path string, id string) error {
+	unlock, err := lockAPIKeysFile(path)
+
+	if err != nil {
+		return err
+	}
+
+	defer unlock()
+
+	keys, err := readAPIKeysFile(path)
+
+	if err != nil {
+		return err
+	}
+
+	for _, key := range keys {
+		if key.ID == id {
+			if key.RevokedAt == nil {
+				now := time.Now().UTC()
+				key.RevokedAt = &now
+			}
+
+			return writeAPIKeysFile(path, keys)
+		}
+	}
+
+	return errors.New("no key with id " + id)
+}
+
+type apiKeyContextKey struct{}
+
+// APIKeyFromContext is the validated key the request was made with, if any
+func APIKeyFromContext(ctx context.Context) (*APIKey, bool) {
+	key, ok := ctx.Value(apiKeyContextKey{}).(*APIKey)
+	return key, ok
+}
+
+// InvalidAPIKey answers a request whose api key isn't valid
+func InvalidAPIKey(w http.ResponseWriter, r *http.Request) {
+	WriteAPIError(w, http.StatusUnauthorized, "invalid or revoked api key")
+}
+
+// Authenticate checks the api key (if one was sent) and puts it in the request's context. A key that isn't valid goes
+// to invalid (normally InvalidAPIKey, rate limited by ip) rather than being quietly treated as anonymous.
+func Authenticate(store *APIKeyStore, invalid http.Handler, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rawKey := r.Header.Get(APIKeyHeader)
+
+		if rawKey == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		key, ok := store.Use(rawKey)
+
+		if !ok {
+			invalid.ServeHTTP(w, r)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), apiKeyContextKey{}, key)))
+	})
+}
+
+// RequireScope only lets requests with a key that has scope through (must be inside Authenticate)
+func RequireScope(scope string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key, ok := APIKeyFromContext(r.Context())
+
+		if !ok {
+			w.Header().Set("WWW-Authenticate", APIKeyHeader)
+			WriteAPIError(w, http.StatusUnauthorized, "an api key is required, send it in the "+APIKeyHeader+" header")
+			return
+		}
+
+		if !key.HasScope(scope) && !key.HasScope(ScopeAdmin) {
+			WriteAPIError(w, http.StatusForbidden, "this api key doesn't have the "+scope+" scope")
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
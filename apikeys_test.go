@@ -0,0 +1,303 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestKeyStore(t *testing.T) (*APIKeyStore, string) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "keys.json")
+	store := NewAPIKeyStore(path)
+
+	return store, path
+}
+
+func TestIssueUseAndRevokeAPIKey(t *testing.T) {
+	store, path := newTestKeyStore(t)
+
+	if _, _, err := IssueAPIKey(path, "partner", []string{"everything"}, nil); err == nil {
+		t.Fatal("issued a key with an unknown scope")
+	}
+
+	rawKey, key, err := IssueAPIKey(path, "partner", []string{ScopeReadPrices}, nil)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.HasPrefix(rawKey, apiKeyPrefix) || key.Hash != hashAPIKey(rawKey) {
+		t.Fatalf("unexpected key %s (%+v)", rawKey, key)
+	}
+
+	// only the hash is kept
+	data, err := os.ReadFile(path)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Contains(string(data), rawKey) {
+		t.Fatal("the raw key was written to the keys file")
+	}
+
+	// a key that's never been used has no last_used at all
+	if strings.Contains(string(data), "last_used") {
+		t.Fatalf("unused key has a last_used: %s", data)
+	}
+
+	if err = store.Reload(); err != nil {
+		t.Fatal(err)
+	}
+
+	used, ok := store.Use(rawKey)
+
+	if !ok || used.ID != key.ID || used.Requests != 1 || used.LastUsed == nil {
+		t.Fatalf("Use() = %+v, %v", used, ok)
+	}
+
+	if _, ok = store.Use(rawKey + "x"); ok {
+		t.Fatal("a wrong key was accepted")
+	}
+
+	if err = RevokeAPIKey(path, "nope"); err == nil {
+		t.Fatal("revoked a key that doesn't exist")
+	}
+
+	if err = RevokeAPIKey(path, key.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = store.Reload(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok = store.Use(rawKey); ok {
+		t.Fatal("a revoked key was accepted")
+	}
+}
+
+func TestAPIKeyFlush(t *testing.T) {
+	store, path := newTestKeyStore(t)
+
+	rawKey, key, err := IssueAPIKey(path, "partner", []string{ScopeReadPrices}, nil)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = store.Reload(); err != nil {
+		t.Fatal(err)
+	}
+
+	store.Use(rawKey)
+	store.Use(rawKey)
+
+	good, err := os.ReadFile(path)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a flush that fails keeps the usage for next time
+	if err = os.WriteFile(path, []byte("{broken"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = store.Flush(); err == nil {
+		t.Fatal("expected the flush to fail")
+	}
+
+	if err = os.WriteFile(path, good, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	store.Use(rawKey)
+
+	if err = store.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	keys, err := readAPIKeysFile(path)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(keys) != 1 || keys[0].ID != key.ID || keys[0].Requests != 3 || keys[0].LastUsed == nil {
+		t.Fatalf("flushed keys = %+v, want 3 requests", keys[0])
+	}
+
+	// and the store doesn't count them twice
+	if used, _ := store.Use(rawKey); used.Requests != 4 {
+		t.Fatalf("store has %d requests, want 4", used.Requests)
+	}
+}
+
+func TestAPIKeyFlushDoesNotUndoARevoke(t *testing.T) {
+	store, path := newTestKeyStore(t)
+
+	rawKey, key, err := IssueAPIKey(path, "partner", []string{ScopeReadPrices}, nil)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = store.Reload(); err != nil {
+		t.Fatal(err)
+	}
+
+	store.Use(rawKey)
+
+	// the cli is revoking while the server flushes, the flush has to wait for it and then build on it
+	unlock, err := lockAPIKeysFile(path)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	flushed := make(chan error)
+
+	go func() {
+		flushed <- store.Flush()
+	}()
+
+	select {
+	case err = <-flushed:
+		t.Fatalf("the flush didn't wait for the lock (%v)", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	keys, err := readAPIKeysFile(path)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	revokedAt := time.Now()
+	keys[0].RevokedAt = &revokedAt
+
+	if err = writeAPIKeysFile(path, keys); err != nil {
+		t.Fatal(err)
+	}
+
+	unlock()
+
+	if err = <-flushed; err != nil {
+		t.Fatal(err)
+	}
+
+	keys, err = readAPIKeysFile(path)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if keys[0].ID != key.ID || keys[0].RevokedAt == nil || keys[0].Requests != 1 {
+		t.Fatalf("after the flush the key is %+v, want revoked with 1 request", keys[0])
+	}
+
+	if _, ok := store.Use(rawKey); ok {
+		t.Fatal("the revoked key works again")
+	}
+}
+
+func TestAPIKeyReloadWithSameModTime(t *testing.T) {
+	store, path := newTestKeyStore(t)
+
+	rawKey, key, err := IssueAPIKey(path, "partner", []string{ScopeReadPrices}, nil)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := os.Stat(path)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = store.Reload(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = RevokeAPIKey(path, key.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	// the revoke lands within the same mod time
+	if err = os.Chtimes(path, info.ModTime(), info.ModTime()); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = store.Reload(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := store.Use(rawKey); ok {
+		t.Fatal("the revoke wasn't picked up")
+	}
+}
+
+func TestRequireScope(t *testing.T) {
+	store, path := newTestKeyStore(t)
+
+	prices, _, err := IssueAPIKey(path, "prices", []string{ScopeReadPrices}, nil)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	routes, _, err := IssueAPIKey(path, "routes", []string{ScopeComputeRoutes}, nil)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	admin, _, err := IssueAPIKey(path, "admin", []string{ScopeAdmin}, nil)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = store.Reload(); err != nil {
+		t.Fatal(err)
+	}
+
+	handler := Authenticate(store, http.HandlerFunc(InvalidAPIKey), RequireScope(ScopeComputeRoutes, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})))
+
+	tests := []struct {
+		name string
+		key  string
+		want int
+	}{
+		{"no key", "", http.StatusUnauthorized},
+		{"invalid key", "hsc_nope", http.StatusUnauthorized},
+		{"wrong scope", prices, http.StatusForbidden},
+		{"right scope", routes, http.StatusOK},
+		{"admin has every scope", admin, http.StatusOK},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/api/v1/quote", nil)
+
+			if test.key != "" {
+				r.Header.Set(APIKeyHeader, test.key)
+			}
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if w.Code != test.want {
+				t.Fatalf("got %d, want %d", w.Code, test.want)
+			}
+		})
+	}
+}
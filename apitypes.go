@@ -0,0 +1,117 @@
+package main
+
+import (
+	"github.com/CADawg/hive-swap-calculator/engine"
+	"github.com/CADawg/hive-swap-calculator/pricing"
+	"github.com/shopspring/decimal"
+)
+
+// The types in this file are what the api promises to send, they're kept apart from the internal structs
+// (pricing.TokenData, engine.MarketOrder) so changing those can't break anyone. Changing these is a breaking api change!
+// The doc tags end up in the OpenAPI document.
+
+// APIToken is a token we can swap, with its prices, fees and the orders priced past its reference price
+type APIToken struct {
+	USDPrice             decimal.Decimal `json:"usd" doc:"Price in USD (from CoinGecko)"`
+	USD24HChange         decimal.Decimal `json:"usd_24h_change" doc:"24 hour USD price change percentage"`
+	BTCPrice             decimal.Decimal `json:"btc" doc:"Price in BTC (from CoinGecko)"`
+	BTC24HChange         decimal.Decimal `json:"btc_24h_change" doc:"24 hour BTC price change percentage"`
+	LastUpdated          int64           `json:"last_updated_at" doc:"Unix time CoinGecko last updated the price"`
+	HIVEPrice            decimal.Decimal `json:"hive" doc:"Reference price in HIVE"`
+	NetworkPercentageFee decimal.Decimal `json:"network_percentage_fee" doc:"Percentage fee taken when depositing or withdrawing through the gateway"`
+	NetworkFlatFee       decimal.Decimal `json:"network_flat_fee" doc:"Flat withdrawal fee in HIVE"`
+	Network              string          `json:"network,omitempty" doc:"Network withdrawals go out on, if it has a flat fee"`
+	Name                 string          `json:"name" doc:"CoinGecko id"`
+	Symbol               string          `json:"symbol" doc:"Symbol, e.g. BTC"`
+	SwapSymbol           string          `json:"swap_symbol" doc:"Hive Engine symbol, e.g. SWAP.BTC"`
+	SellOrders           []APIOrder      `json:"sell_orders,omitempty" doc:"Sell orders priced at or under the reference price"`
+	BuyOrders            []APIOrder      `json:"buy_orders,omitempty" doc:"Buy orders priced at or over the reference price"`
+}
+
+// APIOrder is an order on the Hive Engine market
+type APIOrder struct {
+	Account          string          `json:"account" doc:"Account that placed the order"`
+	Expiration       int64           `json:"expiration" doc:"Unix time the order expires"`
+	Price            decimal.Decimal `json:"price" doc:"Price in SWAP.HIVE"`
+	Quantity         decimal.Decimal `json:"quantity" doc:"Quantity of the token"`
+	Symbol           string          `json:"symbol" doc:"Hive Engine symbol"`
+	Timestamp        int64           `json:"timestamp" doc:"Unix time the order was placed"`
+	TransactionID    string          `json:"txId" doc:"Hive Engine transaction id, unique per order"`
+	ID               int             `json:"_id" doc:"Hive Engine order id"`
+	ProfitPercentage decimal.Decimal `json:"profit_percentage" doc:"How far past the reference price the order is, a percentage for sell orders and a fraction for buy orders"`
+	Side             string          `json:"side,omitempty" doc:"buy or sell (left out when it's clear from where the order is)"`
+}
+
+// APIError is the body of every error response from the versioned api
+type APIError struct {
+	Error string `json:"error" doc:"What went wrong"`
+}
+
+func NewAPIToken(token pricing.TokenData) APIToken {
+	return APIToken{
+		USDPrice:             token.USDPrice,
+		USD24HChange:         token.USD24HChange,
+		BTCPrice:             token.BTCPrice,
+		BTC24HChange:         token.BTC24HChange,
+		LastUpdated:          token.LastUpdated,
+		HIVEPrice:            token.HIVEPrice,
+		NetworkPercentageFee: token.NetworkPercentageFee,
+		NetworkFlatFee:       token.NetworkFlatFee,
+		Network:              token.Network,
+		Name:                 token.CoinGeckoName,
+		Symbol:               token.Symbol,
+		SwapSymbol:           token.SwapSymbol,
+		SellOrders:           NewAPIOrders(token.SellOrders),
+		BuyOrders:            NewAPIOrders(token.BuyOrders),
+	}
+}
+
+func NewAPITokens(tokens []pricing.TokenData) []APIToken {
+	if tokens == nil {
+		return nil
+	}
+
+	apiTokens := make([]APIToken, len(tokens))
+
+	for i, token := range tokens {
+		apiTokens[i] = NewAPIToken(token)
+	}
+
+	return apiTokens
+}
+
+func NewAPIOrder(order engine.MarketOrder) APIOrder {
+	return APIOrder{
+		Account:          order.Account,
+		Expiration:       order.Expiration,
+		Price:            order.Price,
+		Quantity:         order.Quantity,
+		Symbol:           order.Symbol,
+		Timestamp:        order.Timestamp,
+		TransactionID:    order.TransactionID,
+		ID:               order.ID,
+		ProfitPercentage: order.ProfitPercentage,
+	}
+}
+
+// NewAPIOrderWithSide is for orders listed outside of a token's buy_orders/sell_orders, where the side isn't obvious
+func NewAPIOrderWithSide(order engine.MarketOrder, side string) APIOrder {
+	apiOrder := NewAPIOrder(order)
+	apiOrder.Side = side
+
+	return apiOrder
+}
+
+func NewAPIOrders(orders []engine.MarketOrder) []APIOrder {
+	if orders == nil {
+		return nil
+	}
+
+	apiOrders := make([]APIOrder, len(orders))
+
+	for i, order := range orders {
+		apiOrders[i] = NewAPIOrder(order)
+	}
+
+	return apiOrders
+}
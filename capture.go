@@ -0,0 +1,437 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/goccy/go-json"
+)
+
+const (
+	CaptureRecord = "record"
+	CaptureReplay = "replay"
+)
+
+// upstreamRecorder is set in record mode, the pipeline tells it when each cycle starts
+var upstreamRecorder *CaptureRecorder
+
+// Capture is one upstream request and what came back
+type Capture struct {
+	Cycle       uint64    `json:"cycle"`
+	Seq         int       `json:"seq"`
+	At          time.Time `json:"at"`
+	Method      string    `json:"method"`
+	URL         string    `json:"url"`
+	RequestBody string    `json:"request_body,omitempty"`
+	// Error is set instead of the response when the request didn't get one (it's replayed as the same error)
+	Error          string      `json:"error,omitempty"`
+	Status         int         `json:"status,omitempty"`
+	ResponseHeader http.Header `json:"response_header,omitempty"`
+	ResponseBody   string      `json:"response_body,omitempty"`
+}
+
+// CaptureCycle is what a cycle polled, so a replay can run the same sources
+type CaptureCycle struct {
+	Cycle     uint64    `json:"cycle"`
+	StartedAt time.Time `json:"started_at"`
+	Sources   []string  `json:"sources"`
+}
+
+func captureCycleDir(dir string, cycle uint64) string {
+	return filepath.Join(dir, fmt.Sprintf("cycle-%06d", cycle))
+}
+
+// StartCapture sets up record or replay mode (it does nothing if neither is configured)
+func StartCapture(config CaptureConfig) (*CaptureReplayer, error) {
+	switch config.Mode {
+	case CaptureRecord:
+		// this goes on top of whatever's there, so a simulation can be recorded too
+		recorder, err := NewCaptureRecorder(config.Dir, upstreamClient.Transport)
+
+		if err != nil {
+			return nil, err
+		}
+
+		upstreamRecorder = recorder
+		upstreamClient.Transport = upstreamRecorder
+
+		if recorder.offset > 0 {
+			fmt.Println("Recording upstream traffic to", config.Dir, "after the", recorder.offset, "cycles already there")
+		} else {
+			fmt.Println("Recording upstream traffic to", config.Dir)
+		}
+	case CaptureReplay:
+		replayer := &CaptureReplayer{dir: config.Dir}
+		upstreamClient.Transport = replayer
+
+		fmt.Println("Replaying upstream traffic from", config.Dir)
+
+		return replayer, nil
+	}
+
+	return nil, nil
+}
+
+// captureCycleStarted tags everything after it with the cycle (in record mode)
+func captureCycleStarted(cycle uint64, sources []string) {
+	if upstreamRecorder == nil {
+		return
+	}
+
+	err := upstreamRecorder.BeginCycle(cycle, sources)
+
+	if err != nil {
+		fmt.Println("error recording cycle", cycle, err)
+	}
+}
+
+// CaptureRecorder is a transport that writes every request and response to a directory per cycle
+type CaptureRecorder struct {
+	dir  string
+	next http.RoundTripper
+	// offset is the last cycle already in dir (from an earlier run), the pipeline's cycles are recorded after it
+	offset uint64
+
+	lock  sync.Mutex
+	cycle uint64
+	seq   int
+}
+
+// NewCaptureRecorder records into dir, carrying on from whatever earlier runs recorded there. The pipeline counts its
+// cycles from 1 every run, so without that a second run would overwrite the first one's cycles file by file and a
+// replay would mix the two.
+func NewCaptureRecorder(dir string, next http.RoundTripper) (*CaptureRecorder, error) {
+	err := os.MkdirAll(dir, 0o755)
+
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(dir)
+
+	if err != nil {
+		return nil, err
+	}
+
+	recorder := &CaptureRecorder{dir: dir, next: next}
+
+	for _, entry := range entries {
+		var cycle uint64
+
+		if _, err := fmt.Sscanf(entry.Name(), "cycle-%d", &cycle); entry.IsDir() && err == nil && cycle > recorder.offset {
+			recorder.offset = cycle
+		}
+	}
+
+	// anything before our first cycle goes after the last one's captures (a replay of it won't ask for them)
+	recorder.cycle = recorder.offset
+
+	entries, err = os.ReadDir(captureCycleDir(dir, recorder.offset))
+
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	for _, entry := range entries {
+		var seq int
+
+		if _, err := fmt.Sscanf(entry.Name(), "%04d-", &seq); err == nil && seq > recorder.seq {
+			recorder.seq = seq
+		}
+	}
+
+	return recorder, nil
+}
+
+// BeginCycle starts recording the pipeline's cycle (numbered after the earlier runs' ones)
+func (r *CaptureRecorder) BeginCycle(cycle uint64, sources []string) error {
+	cycle += r.offset
+
+	r.lock.Lock()
+	r.cycle = cycle
+	r.seq = 0
+	r.lock.Unlock()
+
+	err := os.MkdirAll(captureCycleDir(r.dir, cycle), 0o755)
+
+	if err != nil {
+		return err
+	}
+
+	return writeCaptureFile(filepath.Join(captureCycleDir(r.dir, cycle), "cycle.json"), CaptureCycle{Cycle: cycle, StartedAt: time.Now(), Sources: sources})
+}
+
+func (r *CaptureRecorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	r.lock.Lock()
+	r.seq++
+	capture := Capture{Cycle: r.cycle, Seq: r.seq, At: time.Now(), Method: req.Method, URL: req.URL.String()}
+	r.lock.Unlock()
+
+	if req.Body != nil {
+		body, err := io.ReadAll(req.Body)
+
+		if err != nil {
+			return nil, err
+		}
+
+		req.Body.Close()
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		capture.RequestBody = string(body)
+	}
+
+	resp, err := r.next.RoundTrip(req)
+
+	if err != nil {
+		capture.Error = err.Error()
+	} else {
+		body, readErr := io.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		if readErr != nil {
+			return nil, readErr
+		}
+
+		resp.Body = io.NopCloser(bytes.NewReader(body))
+
+		capture.Status = resp.StatusCode
+		capture.ResponseHeader = resp.Header
+		capture.ResponseBody = string(body)
+	}
+
+	name := fmt.Sprintf("%04d-%s-%s.json", capture.Seq, req.Method, req.URL.Host)
+
+	// a capture we can't write shouldn't break the refresh
+	if writeErr := writeCaptureFile(filepath.Join(captureCycleDir(r.dir, capture.Cycle), name), capture); writeErr != nil {
+		fmt.Println("error recording", capture.URL+":", writeErr)
+	}
+
+	return resp, err
+}
+
+func writeCaptureFile(path string, value interface{}) error {
+	err := os.MkdirAll(filepath.Dir(path), 0o755)
+
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(value, "", "  ")
+
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, data, 0o644)
+}
+
+// CaptureReplayer is a transport that answers from a recorded cycle instead of the network
+type CaptureReplayer struct {
+	dir string
+
+	lock     sync.Mutex
+	cycle    uint64
+	captures []Capture
+	used     []bool
+}
+
+// Cycles are the recorded cycles, in order
+func (r *CaptureReplayer) Cycles() ([]CaptureCycle, error) {
+	entries, err := os.ReadDir(r.dir)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var cycles []CaptureCycle
+
+	for _, entry := range entries {
+		if !entry.IsDir() || !strings.HasPrefix(entry.Name(), "cycle-") {
+			continue
+		}
+
+		data, err := os.ReadFile(filepath.Join(r.dir, entry.Name(), "cycle.json"))
+
+		// requests made outside a cycle (like before the first one) don't have one
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		var cycle CaptureCycle
+
+		err = json.Unmarshal(data, &cycle)
+
+		if err != nil {
+			return nil, errors.New("error reading " + entry.Name() + ": " + err.Error())
+		}
+
+		cycles = append(cycles, cycle)
+	}
+
+	sort.Slice(cycles, func(i, j int) bool {
+		return cycles[i].Cycle < cycles[j].Cycle
+	})
+
+	return cycles, nil
+}
+
+// LoadCycle makes the replayer answer from the cycle's captures
+func (r *CaptureReplayer) LoadCycle(cycle uint64) error {
+	dir := captureCycleDir(r.dir, cycle)
+
+	entries, err := os.ReadDir(dir)
+
+	if err != nil {
+		return err
+	}
+
+	var captures []Capture
+
+	for _, entry := range entries {
+		if entry.IsDir() || entry.Name() == "cycle.json" || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+
+		data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+
+		if err != nil {
+			return err
+		}
+
+		var capture Capture
+
+		err = json.Unmarshal(data, &capture)
+
+		if err != nil {
+			return errors.New("error reading " + entry.Name() + ": " + err.Error())
+		}
+
+		captures = append(captures, capture)
+	}
+
+	sort.Slice(captures, func(i, j int) bool {
+		return captures[i].Seq < captures[j].Seq
+	})
+
+	r.lock.Lock()
+	r.cycle = cycle
+	r.captures = captures
+	r.used = make([]bool, len(captures))
+	r.lock.Unlock()
+
+	return nil
+}
+
+// find picks the first unused capture with the same method, url and body. Failing that it takes one with just the
+// same method and url, since some bodies change every time (the Hive trade history asks for the last hour).
+func (r *CaptureReplayer) find(method string, url string, body string) (Capture, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	for _, exact := range []bool{true, false} {
+		for i, capture := range r.captures {
+			if r.used[i] || capture.Method != method || capture.URL != url || (exact && capture.RequestBody != body) {
+				continue
+			}
+
+			r.used[i] = true
+
+			return capture, nil
+		}
+	}
+
+	return Capture{}, errors.New("no capture of " + method + " " + url + " in cycle " + strconv.FormatUint(r.cycle, 10))
+}
+
+func (r *CaptureReplayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+
+	if req.Body != nil {
+		var err error
+
+		body, err = io.ReadAll(req.Body)
+
+		if err != nil {
+			return nil, err
+		}
+
+		req.Body.Close()
+	}
+
+	capture, err := r.find(req.Method, req.URL.String(), string(body))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if capture.Error != "" {
+		return nil, errors.New(capture.Error)
+	}
+
+	return &http.Response{
+		Status:        strconv.Itoa(capture.Status) + " " + http.StatusText(capture.Status),
+		StatusCode:    capture.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        capture.ResponseHeader,
+		Body:          io.NopCloser(strings.NewReader(capture.ResponseBody)),
+		ContentLength: int64(len(capture.ResponseBody)),
+		Request:       req,
+	}, nil
+}
+
+// RunReplay runs every recorded cycle (up to config.ReplayUntilCycle) with the sources it polled, publishing each
+// snapshot, then keeps serving the last one. beforeCycle runs before each one (it's where config reloads are swapped
+// in).
+func RunReplay(replayer *CaptureReplayer, config CaptureConfig, beforeCycle func()) {
+	cycles, err := replayer.Cycles()
+
+	if err != nil {
+		fmt.Println("error reading captures:", err)
+		return
+	}
+
+	for _, cycle := range cycles {
+		if config.ReplayUntilCycle > 0 && cycle.Cycle > config.ReplayUntilCycle {
+			break
+		}
+
+		err := replayer.LoadCycle(cycle.Cycle)
+
+		if err != nil {
+			fmt.Println("error loading cycle", cycle.Cycle, err)
+			return
+		}
+
+		beforeCycle()
+
+		fmt.Println("Replaying cycle", cycle.Cycle, "from", cycle.StartedAt.Format(time.RFC3339))
+
+		// filtered as of when it was recorded, not whenever it's replayed
+		snapshot, _, err := RefreshPipeline.RunSourcesAt(CurrentSnapshot(), cycle.Sources, cycle.StartedAt)
+
+		if err != nil {
+			fmt.Println("error:", err)
+		} else {
+			PublishSnapshot(snapshot)
+		}
+
+		time.Sleep(time.Duration(config.ReplayIntervalSeconds * float64(time.Second)))
+	}
+
+	fmt.Println("Replay finished, serving the last snapshot")
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// echoTransport answers every request with its own path, or fails for /fail
+type echoTransport struct{}
+
+func (echoTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Path == "/fail" {
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	return &http.Response{StatusCode: http.StatusOK, Header: http.Header{"X-Path": {req.URL.Path}}, Body: io.NopCloser(strings.NewReader("body of " + req.URL.Path)), Request: req}, nil
+}
+
+func captureGet(t *testing.T, transport http.RoundTripper, url string, body string) (string, error) {
+	t.Helper()
+
+	var reader io.Reader
+
+	if body != "" {
+		reader = strings.NewReader(body)
+	}
+
+	req, err := http.NewRequest("POST", url, reader)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := transport.RoundTrip(req)
+
+	if err != nil {
+		return "", err
+	}
+
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(data), nil
+}
+
+func TestCaptureRecordAndReplay(t *testing.T) {
+	dir := t.TempDir()
+
+	// the first run records two cycles
+	recorder, err := NewCaptureRecorder(dir, echoTransport{})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = recorder.BeginCycle(1, []string{SourcePrices}); err != nil {
+		t.Fatal(err)
+	}
+
+	if body, err := captureGet(t, recorder, "http://upstream/a", `{"n":1}`); err != nil || body != "body of /a" {
+		t.Fatalf("recording changed the response: %q, %v", body, err)
+	}
+
+	if _, err = captureGet(t, recorder, "http://upstream/fail", ""); err == nil {
+		t.Fatal("recording hid an error")
+	}
+
+	if err = recorder.BeginCycle(2, []string{SourceBooks}); err != nil {
+		t.Fatal(err)
+	}
+
+	_, _ = captureGet(t, recorder, "http://upstream/b", "")
+
+	// a second run into the same directory starts counting from 1 again, but mustn't overwrite the first
+	recorder, err = NewCaptureRecorder(dir, echoTransport{})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// before its first cycle
+	_, _ = captureGet(t, recorder, "http://upstream/early", "")
+
+	if err = recorder.BeginCycle(1, []string{SourceFees}); err != nil {
+		t.Fatal(err)
+	}
+
+	_, _ = captureGet(t, recorder, "http://upstream/c", "")
+
+	if entries, _ := os.ReadDir(captureCycleDir(dir, 2)); len(entries) != 3 {
+		t.Fatalf("the first run's last cycle has %d files, want cycle.json, /b and the second run's early request", len(entries))
+	}
+
+	replayer := &CaptureReplayer{dir: dir}
+
+	cycles, err := replayer.Cycles()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(cycles) != 3 || cycles[0].Sources[0] != SourcePrices || cycles[1].Sources[0] != SourceBooks || cycles[2].Cycle != 3 || cycles[2].Sources[0] != SourceFees {
+		t.Fatalf("cycles = %+v", cycles)
+	}
+
+	if err = replayer.LoadCycle(1); err != nil {
+		t.Fatal(err)
+	}
+
+	// a different body still finds the capture if it's the only one for the url
+	if body, err := captureGet(t, replayer, "http://upstream/a", `{"n":2}`); err != nil || body != "body of /a" {
+		t.Fatalf("replayed %q, %v", body, err)
+	}
+
+	// each capture is only used once
+	if _, err = captureGet(t, replayer, "http://upstream/a", `{"n":1}`); err == nil {
+		t.Fatal("a capture was replayed twice")
+	}
+
+	if _, err = captureGet(t, replayer, "http://upstream/fail", ""); err == nil || !strings.Contains(err.Error(), io.ErrUnexpectedEOF.Error()) {
+		t.Fatalf("the recorded error came back as %v", err)
+	}
+
+	if _, err = captureGet(t, replayer, "http://upstream/c", ""); err == nil {
+		t.Fatal("replayed a request from another cycle")
+	}
+
+	if err = replayer.LoadCycle(3); err != nil {
+		t.Fatal(err)
+	}
+
+	if body, err := captureGet(t, replayer, "http://upstream/c", ""); err != nil || body != "body of /c" {
+		t.Fatalf("replayed %q, %v", body, err)
+	}
+}
+
+func TestRunReplayAppliesConfig(t *testing.T) {
+	dir := t.TempDir()
+
+	for _, cycle := range []uint64{1, 2} {
+		if err := writeCaptureFile(filepath.Join(captureCycleDir(dir, cycle), "cycle.json"), CaptureCycle{Cycle: cycle}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	calls := 0
+
+	// no sources, so nothing's fetched
+	RunReplay(&CaptureReplayer{dir: dir}, CaptureConfig{Mode: CaptureReplay, Dir: dir}, func() { calls++ })
+
+	if calls != 2 {
+		t.Fatalf("beforeCycle ran %d times, want once per cycle", calls)
+	}
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/CADawg/hive-swap-calculator/market"
+)
+
+// RunKeysCommand is the admin cli for api keys: keys issue|revoke|list
+func RunKeysCommand(args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: keys issue|revoke|list [flags]")
+	}
+
+	flags := flag.NewFlagSet("keys "+args[0], flag.ExitOnError)
+	configFile := flags.String("config", "config.json", "path to the json config file")
+
+	switch args[0] {
+	case "issue":
+		name := flags.String("name", "", "who the key is for")
+		scopes := flags.String("scopes", ScopeReadPrices, "comma separated scopes ("+strings.Join(AllScopes, ", ")+")")
+		rps := flags.Float64("rps", 0, "requests per second for this key (0 uses the server's limits)")
+		burst := flags.Int("burst", 0, "burst size for this key")
+		heavyRPS := flags.Float64("heavy-rps", 0, "requests per second on heavy endpoints for this key (0 uses the server's limits)")
+		heavyBurst := flags.Int("heavy-burst", 0, "burst size on heavy endpoints for this key")
+
+		_ = flags.Parse(args[1:])
+
+		if *name == "" {
+			return errors.New("-name is required")
+		}
+
+		config, err := LoadConfig(*configFile)
+
+		if err != nil {
+			return err
+		}
+
+		rateLimits := map[string]RateLimit{}
+
+		if *rps > 0 {
+			rateLimits[RateLimitDefault] = RateLimit{RequestsPerSecond: *rps, Burst: *burst}
+		}
+
+		if *heavyRPS > 0 {
+			rateLimits[RateLimitHeavy] = RateLimit{RequestsPerSecond: *heavyRPS, Burst: *heavyBurst}
+		}
+
+		if len(rateLimits) == 0 {
+			rateLimits = nil
+		}
+
+		rawKey, key, err := IssueAPIKey(config.APIKeysFile, *name, market.SplitList(*scopes), rateLimits)
+
+		if err != nil {
+			return err
+		}
+
+		fmt.Println("issued key", key.ID, "for", key.Name, "with scopes", strings.Join(key.Scopes, ", "))
+		fmt.Println("this is the only time the key will be shown:")
+		fmt.Println(rawKey)
+	case "revoke":
+		id := flags.String("id", "", "id of the key to revoke")
+
+		_ = flags.Parse(args[1:])
+
+		if *id == "" {
+			return errors.New("-id is required")
+		}
+
+		config, err := LoadConfig(*configFile)
+
+		if err != nil {
+			return err
+		}
+
+		err = RevokeAPIKey(config.APIKeysFile, *id)
+
+		if err != nil {
+			return err
+		}
+
+		fmt.Println("revoked key", *id)
+	case "list":
+		_ = flags.Parse(args[1:])
+
+		config, err := LoadConfig(*configFile)
+
+		if err != nil {
+			return err
+		}
+
+		keys, err := readAPIKeysFile(config.APIKeysFile)
+
+		if err != nil {
+			return err
+		}
+
+		writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+
+		_, _ = fmt.Fprintln(writer, "ID\tNAME\tSCOPES\tREQUESTS\tLAST USED\tSTATUS")
+
+		for _, key := range keys {
+			status := "active"
+
+			if key.RevokedAt != nil {
+				status = "revoked " + key.RevokedAt.Format(time.RFC3339)
+			}
+
+			lastUsed := "never"
+
+			if key.LastUsed != nil {
+				lastUsed = key.LastUsed.Format(time.RFC3339)
+			}
+
+			_, _ = fmt.Fprintf(writer, "%s\t%s\t%s\t%d\t%s\t%s\n", key.ID, key.Name, strings.Join(key.Scopes, ","), key.Requests, lastUsed, status)
+		}
+
+		return writer.Flush()
+	default:
+		return errors.New("unknown keys command " + args[0] + ", must be issue, revoke or list")
+	}
+
+	return nil
+}
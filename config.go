@@ -0,0 +1,501 @@
+package main
+
+import (
+	"errors"
+	"net"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/CADawg/hive-swap-calculator/gateway"
+	"github.com/CADawg/hive-swap-calculator/market"
+	"github.com/CADawg/hive-swap-calculator/pricing"
+	"github.com/goccy/go-json"
+	"github.com/shopspring/decimal"
+)
+
+// Config is everything the server can be told through the config file, anything left out keeps its default
+type Config struct {
+	Server    ServerConfig       `json:"server"`
+	Filters   market.OrderFilter `json:"filters"`
+	CORS      CORSConfig         `json:"cors"`
+	RateLimit RateLimitConfig    `json:"rate_limit"`
+
+	// APIKeysFile is where the (hashed) partner api keys are kept, manage it with the keys command
+	APIKeysFile string `json:"api_keys_file"`
+
+	// APIBaseURL is where the frontend should send its api requests, empty means this server. The frontend reads it
+	// from our /config.json, a build hosted elsewhere (e.g. a CDN) finds us through VITE_API_BASE_URL.
+	APIBaseURL string `json:"api_base_url"`
+
+	// Tokens are the coins we price (by their CoinGecko id) and the symbol they have on Hive Engine (without SWAP.)
+	Tokens []pricing.RegistryToken `json:"tokens"`
+	Fees   FeeConfig               `json:"fees"`
+
+	// SnapshotFile is where the last good snapshot is saved, so there's something to serve straight after a restart (empty to turn off)
+	SnapshotFile string `json:"snapshot_file"`
+
+	// Replica makes this instance follow another one instead of hitting the upstreams itself
+	Replica ReplicaConfig `json:"replica"`
+
+	// Detectors picks which opportunity detectors run each refresh, and their settings
+	Detectors DetectorConfig `json:"detectors"`
+
+	// Schedule is how often each source (prices, hbd_rate, books, fees) is polled
+	Schedule map[string]SourceSchedule `json:"schedule"`
+
+	// Capture records every upstream request and response, or replays a recording instead of going to the network
+	Capture CaptureConfig `json:"capture"`
+
+	// Simulate answers every upstream request from a made up market instead of the network, for development and demos
+	Simulate SimulateConfig `json:"simulate"`
+}
+
+// SimulateConfig is what the simulated market looks like
+type SimulateConfig struct {
+	Enabled bool `json:"enabled"`
+	// Addr also serves the fake upstreams over http (e.g. "127.0.0.1:6250"), empty means they're only used in process
+	Addr string `json:"addr"`
+	// Seed makes the market play out the same every run (0 picks a new one each time)
+	Seed int64 `json:"seed"`
+	// TickSeconds is how often prices move and orders come and go
+	TickSeconds float64 `json:"tick_seconds"`
+	// Volatility is how far prices (and gateway fees) move each tick, as a fraction (the standard deviation)
+	Volatility float64 `json:"volatility"`
+	// TokenVolatility overrides it by CoinGecko id, e.g. to keep stablecoins steady
+	TokenVolatility map[string]float64 `json:"token_volatility"`
+	// MeanReversion is how much of the way back to the start price each tick goes, so nothing wanders off forever
+	MeanReversion float64 `json:"mean_reversion"`
+	// StartPricesUSD are where prices start by CoinGecko id, anything else starts somewhere from a cent to $100
+	StartPricesUSD map[string]decimal.Decimal `json:"start_prices_usd"`
+	// OrdersPerBook is how many orders each token has on each side
+	OrdersPerBook int `json:"orders_per_book"`
+	// OrderChurn is the chance each order is taken off every tick (it's replaced by a new one at the current price)
+	OrderChurn float64 `json:"order_churn"`
+	// MispricedChance is the chance a new order is priced past the reference price, which is what we're looking for
+	MispricedChance float64 `json:"mispriced_chance"`
+	// Accounts are who the orders belong to
+	Accounts []string `json:"accounts"`
+	// Gateways are the tokens each gateway lists (without SWAP.) and its withdrawal fee, by network name
+	Gateways map[string]SimulatedGateway `json:"gateways"`
+	// LatencyMs is about how long each request takes, ErrorRate and RateLimitRate are the chances one fails (with a
+	// 500 or a 429)
+	LatencyMs     int     `json:"latency_ms"`
+	ErrorRate     float64 `json:"error_rate"`
+	RateLimitRate float64 `json:"rate_limit_rate"`
+}
+
+// SimulatedGateway is one simulated gateway
+type SimulatedGateway struct {
+	Tokens []string `json:"tokens"`
+	// WithdrawalFee is in the network's currency (ETH, BNB or MATIC)
+	WithdrawalFee decimal.Decimal `json:"withdrawal_fee"`
+}
+
+// CaptureConfig is for reproducing refresh cycles offline
+type CaptureConfig struct {
+	// Mode is "record", "replay" or empty for neither. Both start from scratch (the snapshot file isn't loaded) so a
+	// replay can rebuild everything from the captures.
+	Mode string `json:"mode"`
+	// Dir has a directory per cycle, with a file per request
+	Dir string `json:"dir"`
+	// ReplayUntilCycle stops the replay after that cycle, so the api shows it as it was then (0 replays them all)
+	ReplayUntilCycle uint64 `json:"replay_until_cycle"`
+	// ReplayIntervalSeconds is how long to wait between replayed cycles
+	ReplayIntervalSeconds float64 `json:"replay_interval_seconds"`
+}
+
+// DetectorConfig switches the opportunity detectors on, and holds the settings of the built in ones
+type DetectorConfig struct {
+	// Enabled are the detectors to run, by name (results are listed in this order)
+	Enabled []string `json:"enabled"`
+	// MinDepthHive is the smallest order (in HIVE) the min_depth detector counts
+	MinDepthHive decimal.Decimal `json:"min_depth_hive"`
+	// PreferredNetworks are the withdrawal networks the preferred_network detector looks at, e.g. "Ethereum"
+	PreferredNetworks []string `json:"preferred_networks"`
+	// Accounts are whose orders the accounts detector lists
+	Accounts []string `json:"accounts"`
+}
+
+// ReplicaConfig is how to follow a primary instance's snapshot stream (over grpc)
+type ReplicaConfig struct {
+	// PrimaryAddr is the primary's grpc_addr (host:port), empty means we're not a replica
+	PrimaryAddr string `json:"primary_addr"`
+	// TLS is whether the primary's grpc api uses TLS
+	TLS bool `json:"tls"`
+	// APIKey is sent to the primary, it needs read_prices (replicas ask for unfiltered snapshots) and gets our own rate
+	// limits there
+	APIKey string `json:"api_key"`
+	// FailoverAfterSeconds is how long without a snapshot from the primary before we fetch from the upstreams ourselves
+	FailoverAfterSeconds int `json:"failover_after_seconds"`
+}
+
+// FeeConfig is what we assume the gateways charge, as percentages
+type FeeConfig struct {
+	// PercentageFee is what every token is charged to deposit or withdraw
+	PercentageFee decimal.Decimal `json:"percentage_fee"`
+	// GatewayPercentageFee replaces it for tokens withdrawn through a gateway with a flat network fee (ETH, BSC, Polygon)
+	GatewayPercentageFee decimal.Decimal `json:"gateway_percentage_fee"`
+}
+
+// ServerConfig is where and how we listen
+type ServerConfig struct {
+	Addr string `json:"addr"`
+	// TLSCertFile and TLSKeyFile turn on https (with http/2), they're reloaded when they change on disk
+	TLSCertFile string `json:"tls_cert_file"`
+	TLSKeyFile  string `json:"tls_key_file"`
+	// RedirectHTTPAddr if set (e.g. ":80") runs a plain http listener that redirects everything to https
+	RedirectHTTPAddr string `json:"redirect_http_addr"`
+	// GRPCAddr is where the grpc api listens (it uses the same TLS cert, it's plaintext without one), empty (the
+	// default) turns it off
+	GRPCAddr string `json:"grpc_addr"`
+}
+
+// CORSConfig lets a frontend hosted on another origin (e.g. a CDN) use this server's api
+type CORSConfig struct {
+	// AllowedOrigins are the origins allowed to call the api, "*" allows any (no origins means no CORS headers at all)
+	AllowedOrigins []string `json:"allowed_origins"`
+	AllowedMethods []string `json:"allowed_methods"`
+	// AllowedHeaders are the request headers browsers may send cross origin, anything else fails the preflight
+	AllowedHeaders []string `json:"allowed_headers"`
+	// MaxAge is how long (in seconds) browsers may cache a preflight response
+	MaxAge int `json:"max_age"`
+}
+
+// FrontendConfig is what the frontend reads from /config.json at runtime
+type FrontendConfig struct {
+	APIBaseURL string `json:"api_base_url"`
+}
+
+// DefaultConfig is what we run with if there's no config file (a func so nobody can modify the defaults through a shared slice)
+func DefaultConfig() Config {
+	return Config{
+		Server: ServerConfig{
+			Addr: ":6241",
+		},
+		APIKeysFile:  "api_keys.json",
+		SnapshotFile: "snapshot.json",
+		Filters: market.OrderFilter{
+			MinHiveValue:       decimal.Zero,
+			MinSecondsToExpiry: 0,
+			ExcludeDust:        false,
+		},
+		CORS: CORSConfig{
+			AllowedOrigins: nil,
+			AllowedMethods: []string{"GET", "HEAD", "OPTIONS"},
+			AllowedHeaders: []string{"Accept", "Content-Type", "If-None-Match", APIKeyHeader},
+			MaxAge:         600,
+		},
+		Tokens: []pricing.RegistryToken{
+			{CoinGeckoID: "hive", Symbol: "HIVE"},
+			{CoinGeckoID: "bitcoin", Symbol: "BTC"},
+			{CoinGeckoID: "litecoin", Symbol: "LTC"},
+			{CoinGeckoID: "hive_dollar", Symbol: "HBD"},
+			{CoinGeckoID: "steem", Symbol: "STEEM"},
+			{CoinGeckoID: "dogecoin", Symbol: "DOGE"},
+			{CoinGeckoID: "ethereum", Symbol: "ETH"},
+			{CoinGeckoID: "tether", Symbol: "USDT"},
+			{CoinGeckoID: "binancecoin", Symbol: "BNB"},
+			{CoinGeckoID: "binance-usd", Symbol: "BUSD"},
+			{CoinGeckoID: "wax", Symbol: "WAX"},
+			{CoinGeckoID: "matic-network", Symbol: "MATIC"},
+			{CoinGeckoID: "bitcoin-cash", Symbol: "BCH"},
+			{CoinGeckoID: "basic-attention-token", Symbol: "BAT"},
+			{CoinGeckoID: "eos", Symbol: "EOS"},
+		},
+		Replica: ReplicaConfig{
+			FailoverAfterSeconds: 30,
+		},
+		Schedule: map[string]SourceSchedule{
+			SourcePrices:  {IntervalSeconds: 10, Jitter: 0.1, MaxBackoffSeconds: 300},
+			SourceHBDRate: {IntervalSeconds: 10, Jitter: 0.1, MaxBackoffSeconds: 300},
+			SourceBooks:   {IntervalSeconds: 10, FastIntervalSeconds: 3, Jitter: 0.1, MaxBackoffSeconds: 120},
+			SourceFees:    {IntervalSeconds: 60, Jitter: 0.1, MaxBackoffSeconds: 600},
+		},
+		Simulate: SimulateConfig{
+			TickSeconds: 5,
+			Volatility:  0.005,
+			TokenVolatility: map[string]float64{
+				"hive_dollar": 0.001,
+				"tether":      0.0002,
+				"binance-usd": 0.0002,
+			},
+			MeanReversion: 0.01,
+			StartPricesUSD: map[string]decimal.Decimal{
+				"hive":                  decimal.RequireFromString("0.3"),
+				"bitcoin":               decimal.NewFromInt(60000),
+				"litecoin":              decimal.NewFromInt(70),
+				"hive_dollar":           decimal.NewFromInt(1),
+				"steem":                 decimal.RequireFromString("0.2"),
+				"dogecoin":              decimal.RequireFromString("0.12"),
+				"ethereum":              decimal.NewFromInt(3000),
+				"tether":                decimal.NewFromInt(1),
+				"binancecoin":           decimal.NewFromInt(550),
+				"binance-usd":           decimal.NewFromInt(1),
+				"wax":                   decimal.RequireFromString("0.05"),
+				"matic-network":         decimal.RequireFromString("0.5"),
+				"bitcoin-cash":          decimal.NewFromInt(350),
+				"basic-attention-token": decimal.RequireFromString("0.2"),
+				"eos":                   decimal.RequireFromString("0.6"),
+			},
+			OrdersPerBook:   20,
+			OrderChurn:      0.1,
+			MispricedChance: 0.05,
+			Accounts:        []string{"alice", "bob", "carol", "dave", "erin"},
+			Gateways: map[string]SimulatedGateway{
+				"Ethereum":            {Tokens: []string{"USDT", "BAT"}, WithdrawalFee: decimal.RequireFromString("0.002")},
+				"Binance Smart Chain": {Tokens: []string{"BUSD", "USDT"}, WithdrawalFee: decimal.RequireFromString("0.0005")},
+				"Polygon (Matic)":     {Tokens: []string{"USDT"}, WithdrawalFee: decimal.RequireFromString("0.05")},
+			},
+			LatencyMs: 50,
+		},
+		Detectors: DetectorConfig{
+			Enabled:      []string{DetectorPastReference},
+			MinDepthHive: decimal.NewFromInt(100),
+		},
+		Fees: FeeConfig{
+			PercentageFee:        decimal.RequireFromString("0.75"),
+			GatewayPercentageFee: decimal.NewFromInt(1),
+		},
+		RateLimit: RateLimitConfig{
+			TrustedProxies: nil,
+			Limits: map[string]RateLimit{
+				RateLimitDefault: {RequestsPerSecond: 5, Burst: 20},
+				RateLimitHeavy:   {RequestsPerSecond: 0.5, Burst: 5},
+			},
+		},
+	}
+}
+
+var AppConfig = DefaultConfig()
+var AppConfigLock = &sync.RWMutex{}
+
+// LoadConfig reads the config file at path on top of the defaults (a missing file just means defaults), then validates it
+func LoadConfig(path string) (Config, error) {
+	config := DefaultConfig()
+
+	data, err := os.ReadFile(path)
+
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return config, nil
+		}
+
+		return config, err
+	}
+
+	err = json.Unmarshal(data, &config)
+
+	if err != nil {
+		return config, err
+	}
+
+	return config, config.Validate()
+}
+
+// Validate catches mistakes that would otherwise only show up as a broken refresh or a server that won't start
+func (c Config) Validate() error {
+	if c.Server.Addr == "" {
+		return errors.New("server.addr can't be empty")
+	}
+
+	if (c.Server.TLSCertFile == "") != (c.Server.TLSKeyFile == "") {
+		return errors.New("both server.tls_cert_file and server.tls_key_file need to be set for https")
+	}
+
+	if c.Filters.MinHiveValue.IsNegative() || c.Filters.MinSecondsToExpiry < 0 {
+		return errors.New("filters can't be negative")
+	}
+
+	for _, proxy := range c.RateLimit.TrustedProxies {
+		if net.ParseIP(proxy) == nil {
+			if _, _, err := net.ParseCIDR(proxy); err != nil {
+				return errors.New("rate_limit.trusted_proxies: " + proxy + " isn't an ip or cidr")
+			}
+		}
+	}
+
+	for class, limit := range c.RateLimit.Limits {
+		if limit.RequestsPerSecond <= 0 || limit.Burst < 1 {
+			return errors.New("rate_limit.limits." + class + " needs requests_per_second above 0 and a burst of at least 1")
+		}
+	}
+
+	if c.CORS.MaxAge < 0 {
+		return errors.New("cors.max_age can't be negative")
+	}
+
+	for _, header := range c.CORS.AllowedHeaders {
+		if header == "" || header == "*" || strings.ContainsAny(header, " ,") {
+			return errors.New("cors.allowed_headers must be header names, got \"" + header + "\"")
+		}
+	}
+
+	for _, fee := range []decimal.Decimal{c.Fees.PercentageFee, c.Fees.GatewayPercentageFee} {
+		if fee.IsNegative() || fee.GreaterThanOrEqual(decimal.NewFromInt(100)) {
+			return errors.New("fees must be percentages from 0 up to 100")
+		}
+	}
+
+	if c.Replica.PrimaryAddr != "" && c.Replica.FailoverAfterSeconds < 1 {
+		return errors.New("replica.failover_after_seconds must be at least 1")
+	}
+
+	if c.Replica.PrimaryAddr != "" && c.Replica.APIKey == "" {
+		return errors.New("replica.api_key is required, the primary only sends unfiltered snapshots to keys with read_prices")
+	}
+
+	ids := map[string]bool{}
+	symbols := map[string]bool{}
+
+	for _, token := range c.Tokens {
+		if token.CoinGeckoID == "" || token.Symbol == "" {
+			return errors.New("every token needs a coingecko_id and a symbol")
+		}
+
+		if token.Symbol != strings.ToUpper(token.Symbol) || strings.HasPrefix(token.Symbol, "SWAP.") {
+			return errors.New("token symbol " + token.Symbol + " should be upper case and without SWAP.")
+		}
+
+		if ids[token.CoinGeckoID] || symbols[token.Symbol] {
+			return errors.New("token " + token.CoinGeckoID + " (" + token.Symbol + ") is listed twice")
+		}
+
+		ids[token.CoinGeckoID] = true
+		symbols[token.Symbol] = true
+	}
+
+	// every other price is worked out from HIVE's
+	if !symbols["HIVE"] {
+		return errors.New("tokens must include HIVE")
+	}
+
+	registered := DetectorNames()
+	enabled := map[string]bool{}
+
+	for _, name := range c.Detectors.Enabled {
+		if !market.ContainsString(registered, name) {
+			return errors.New("unknown detector " + name + ", must be one of " + strings.Join(registered, ", "))
+		}
+
+		if enabled[name] {
+			return errors.New("detector " + name + " is enabled twice")
+		}
+
+		enabled[name] = true
+	}
+
+	if c.Detectors.MinDepthHive.IsNegative() {
+		return errors.New("detectors.min_depth_hive can't be negative")
+	}
+
+	switch c.Capture.Mode {
+	case "":
+	case CaptureRecord, CaptureReplay:
+		if c.Capture.Dir == "" {
+			return errors.New("capture.dir must be set to " + c.Capture.Mode)
+		}
+	default:
+		return errors.New("capture.mode must be record, replay or empty")
+	}
+
+	if c.Capture.Mode == CaptureReplay && c.Replica.PrimaryAddr != "" {
+		return errors.New("a replica can't replay captures, its data comes from the primary")
+	}
+
+	if c.Capture.ReplayIntervalSeconds < 0 {
+		return errors.New("capture.replay_interval_seconds can't be negative")
+	}
+
+	if c.Simulate.Enabled {
+		err := c.Simulate.Validate()
+
+		if err != nil {
+			return err
+		}
+
+		if c.Capture.Mode == CaptureReplay || c.Replica.PrimaryAddr != "" {
+			return errors.New("simulate can't be used with a replay or as a replica, they have their own data")
+		}
+	}
+
+	for source, schedule := range c.Schedule {
+		if !market.ContainsString(AllSources, source) {
+			return errors.New("unknown schedule source " + source + ", must be one of " + strings.Join(AllSources, ", "))
+		}
+
+		if schedule.IntervalSeconds <= 0 || schedule.FastIntervalSeconds < 0 || schedule.MaxBackoffSeconds < 0 {
+			return errors.New("schedule." + source + " needs an interval over 0, and no negative fast interval or max backoff")
+		}
+
+		if schedule.Jitter < 0 || schedule.Jitter >= 1 {
+			return errors.New("schedule." + source + ".jitter must be from 0 up to (not including) 1")
+		}
+	}
+
+	return nil
+}
+
+func (s SimulateConfig) Validate() error {
+	if s.TickSeconds <= 0 {
+		return errors.New("simulate.tick_seconds must be over 0")
+	}
+
+	if s.Volatility < 0 || s.OrdersPerBook < 0 || s.LatencyMs < 0 {
+		return errors.New("simulate.volatility, orders_per_book and latency_ms can't be negative")
+	}
+
+	for id, volatility := range s.TokenVolatility {
+		if volatility < 0 {
+			return errors.New("simulate.token_volatility." + id + " can't be negative")
+		}
+	}
+
+	for id, price := range s.StartPricesUSD {
+		if !price.IsPositive() {
+			return errors.New("simulate.start_prices_usd." + id + " must be over 0")
+		}
+	}
+
+	for _, chance := range []float64{s.MeanReversion, s.OrderChurn, s.MispricedChance, s.ErrorRate, s.RateLimitRate} {
+		if chance < 0 || chance > 1 {
+			return errors.New("simulate.mean_reversion, order_churn, mispriced_chance, error_rate and rate_limit_rate must be from 0 to 1")
+		}
+	}
+
+	for network, settings := range s.Gateways {
+		if !containsNetwork(gateway.DefaultNetworks, network) {
+			return errors.New("unknown simulate.gateways network " + network)
+		}
+
+		if settings.WithdrawalFee.IsNegative() {
+			return errors.New("simulate.gateways." + network + ".withdrawal_fee can't be negative")
+		}
+	}
+
+	return nil
+}
+
+func containsNetwork(networks []gateway.Network, name string) bool {
+	for _, network := range networks {
+		if network.Name == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// GetConfig returns a copy of the config currently in use
+func GetConfig() Config {
+	AppConfigLock.RLock()
+	defer AppConfigLock.RUnlock()
+
+	return AppConfig
+}
+
+func SetConfig(config Config) {
+	AppConfigLock.Lock()
+	AppConfig = config
+	AppConfigLock.Unlock()
+}
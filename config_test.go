@@ -0,0 +1,115 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/CADawg/hive-swap-calculator/pricing"
+	"github.com/shopspring/decimal"
+)
+
+func TestDefaultConfigIsValid(t *testing.T) {
+	if err := DefaultConfig().Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	// no new listeners nobody asked for
+	if addr := DefaultConfig().Server.GRPCAddr; addr != "" {
+		t.Fatalf("grpc is on by default at %s", addr)
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(config *Config)
+	}{
+		{"no addr", func(config *Config) { config.Server.Addr = "" }},
+		{"tls cert without a key", func(config *Config) { config.Server.TLSCertFile = "cert.pem" }},
+		{"negative filter", func(config *Config) { config.Filters.MinHiveValue = decimal.NewFromInt(-1) }},
+		{"bad trusted proxy", func(config *Config) { config.RateLimit.TrustedProxies = []string{"localhost"} }},
+		{"zero rate limit", func(config *Config) {
+			config.RateLimit.Limits = map[string]RateLimit{"default": {RequestsPerSecond: 0, Burst: 1}}
+		}},
+		{"negative cors max age", func(config *Config) { config.CORS.MaxAge = -1 }},
+		{"wildcard cors header", func(config *Config) { config.CORS.AllowedHeaders = []string{"*"} }},
+		{"cors headers in one string", func(config *Config) { config.CORS.AllowedHeaders = []string{"Accept, X-API-Key"} }},
+		{"fee of 100%", func(config *Config) { config.Fees.PercentageFee = decimal.NewFromInt(100) }},
+		{"replica without failover", func(config *Config) {
+			config.Replica.PrimaryAddr = "primary:9090"
+			config.Replica.APIKey = "hsc_key"
+			config.Replica.FailoverAfterSeconds = 0
+		}},
+		{"replica without a key", func(config *Config) { config.Replica.PrimaryAddr = "primary:9090" }},
+		{"token without a symbol", func(config *Config) {
+			config.Tokens = append(config.Tokens, pricing.RegistryToken{CoinGeckoID: "nothing"})
+		}},
+		{"lower case symbol", func(config *Config) {
+			config.Tokens = append(config.Tokens, pricing.RegistryToken{CoinGeckoID: "nothing", Symbol: "nope"})
+		}},
+		{"token listed twice", func(config *Config) { config.Tokens = append(config.Tokens, config.Tokens[0]) }},
+		{"no hive", func(config *Config) {
+			config.Tokens = []pricing.RegistryToken{{CoinGeckoID: "bitcoin", Symbol: "BTC"}}
+		}},
+		{"unknown detector", func(config *Config) { config.Detectors.Enabled = []string{"nope"} }},
+		{"unknown capture mode", func(config *Config) { config.Capture.Mode = "rewind" }},
+		{"capture without a dir", func(config *Config) { config.Capture.Mode = CaptureRecord }},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config := DefaultConfig()
+			test.change(&config)
+
+			if err := config.Validate(); err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	dir := t.TempDir()
+
+	// no file at all is fine at startup, we run with the defaults
+	config, err := LoadConfig(filepath.Join(dir, "missing.json"))
+
+	if err != nil || !reflect.DeepEqual(config, DefaultConfig()) {
+		t.Fatalf("LoadConfig(missing) = %+v, %v", config, err)
+	}
+
+	path := filepath.Join(dir, "config.json")
+
+	if err = os.WriteFile(path, []byte(`{"server": {"addr": ":9999"}, "cors": {"max_age": 60}}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	config, err = LoadConfig(path)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// what's in the file replaces the defaults, everything else is left alone
+	if config.Server.Addr != ":9999" || config.CORS.MaxAge != 60 || !reflect.DeepEqual(config.Tokens, DefaultConfig().Tokens) {
+		t.Fatalf("unexpected config %+v", config)
+	}
+
+	if err = os.WriteFile(path, []byte(`{"server": {"addr": ""}}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = LoadConfig(path); err == nil {
+		t.Fatal("loaded an invalid config")
+	}
+
+	if err = os.WriteFile(path, []byte(`{"server": `), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = LoadConfig(path); err == nil {
+		t.Fatal("loaded broken json")
+	}
+}
@@ -0,0 +1,261 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/goccy/go-json"
+)
+
+// ConfigWatcher reloads the config file on SIGHUP or when it changes on disk. A new config isn't used straight away,
+// it waits until the refresh loop picks it up between cycles so a cycle never sees half of each.
+type ConfigWatcher struct {
+	path string
+
+	lock    sync.Mutex
+	modTime time.Time
+	pending *Config
+}
+
+func NewConfigWatcher(path string) *ConfigWatcher {
+	watcher := &ConfigWatcher{path: path}
+
+	// the config we started with came from this version of the file
+	if info, err := os.Stat(path); err == nil {
+		watcher.modTime = info.ModTime()
+	}
+
+	return watcher
+}
+
+// Start watches for SIGHUP, and checks the file's modification time every interval
+func (c *ConfigWatcher) Start(interval time.Duration) {
+	hangups := make(chan os.Signal, 1)
+	signal.Notify(hangups, syscall.SIGHUP)
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-hangups:
+				c.reload("SIGHUP")
+			case <-ticker.C:
+				if c.changed() {
+					c.reload("config file changed")
+				}
+			}
+		}
+	}()
+}
+
+func (c *ConfigWatcher) changed() bool {
+	var modTime time.Time
+
+	info, err := os.Stat(c.path)
+
+	if err == nil {
+		modTime = info.ModTime()
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return false
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return !modTime.Equal(c.modTime)
+}
+
+// reload reads and validates the file, a bad (or missing) config is logged and ignored (we keep running with the old one)
+func (c *ConfigWatcher) reload(reason string) {
+	var modTime time.Time
+	var config Config
+
+	info, err := os.Stat(c.path)
+
+	if err == nil {
+		modTime = info.ModTime()
+	}
+
+	// LoadConfig falls back to the defaults without a file, which is right at startup but not here: a file that's been
+	// deleted (or is half way through an editor's save) would quietly reset the filters, cors, rate limits and tokens
+	if errors.Is(err, os.ErrNotExist) {
+		err = errors.New(c.path + " doesn't exist")
+	} else {
+		config, err = LoadConfig(c.path)
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	// don't keep retrying the same broken file, wait for it to change again
+	c.modTime = modTime
+
+	if err != nil {
+		fmt.Println("config reload ("+reason+") rejected, keeping the old config:", err)
+		return
+	}
+
+	fmt.Println("config reload (" + reason + ") accepted, it'll be used from the next refresh")
+
+	c.pending = &config
+}
+
+// ApplyPending swaps in the newly loaded config (if there is one) and logs what changed, true if it did.
+// Call it between refresh cycles.
+func (c *ConfigWatcher) ApplyPending() bool {
+	c.lock.Lock()
+	pending := c.pending
+	c.pending = nil
+	c.lock.Unlock()
+
+	if pending == nil {
+		return false
+	}
+
+	old := GetConfig()
+
+	SetConfig(*pending)
+
+	changes := ConfigDiff(old, *pending)
+
+	if len(changes) == 0 {
+		fmt.Println("config reloaded, nothing changed")
+		return true
+	}
+
+	fmt.Println("config reloaded:")
+
+	for _, change := range changes {
+		fmt.Println("  " + change)
+	}
+
+	if !reflect.DeepEqual(old.Server, pending.Server) || old.APIKeysFile != pending.APIKeysFile || (old.Replica.PrimaryAddr == "") != (pending.Replica.PrimaryAddr == "") || old.Capture != pending.Capture || !reflect.DeepEqual(old.Simulate, pending.Simulate) {
+		fmt.Println("  (server, api_keys_file, capture, simulate and turning replica mode on or off need a restart to take effect)")
+	}
+
+	if old.Simulate.Enabled && !reflect.DeepEqual(old.Tokens, pending.Tokens) {
+		fmt.Println("  (the simulated market keeps the tokens it started with until a restart)")
+	}
+
+	return true
+}
+
+// ConfigDiff lists what's different between two configs, one line per setting
+func ConfigDiff(old Config, new Config) []string {
+	var changes []string
+
+	oldTokens := map[string]string{}
+	newTokens := map[string]string{}
+
+	for _, token := range old.Tokens {
+		oldTokens[token.CoinGeckoID] = token.Symbol
+	}
+
+	for _, token := range new.Tokens {
+		newTokens[token.CoinGeckoID] = token.Symbol
+
+		if symbol, ok := oldTokens[token.CoinGeckoID]; !ok {
+			changes = append(changes, "tokens: added "+token.CoinGeckoID+" ("+token.Symbol+")")
+		} else if symbol != token.Symbol {
+			changes = append(changes, "tokens: "+token.CoinGeckoID+" symbol "+symbol+" -> "+token.Symbol)
+		}
+	}
+
+	for _, token := range old.Tokens {
+		if _, ok := newTokens[token.CoinGeckoID]; !ok {
+			changes = append(changes, "tokens: removed "+token.CoinGeckoID+" ("+token.Symbol+")")
+		}
+	}
+
+	// everything else is compared setting by setting, through its json
+	old.Tokens, new.Tokens = nil, nil
+
+	oldSettings := flattenConfig(old)
+	newSettings := flattenConfig(new)
+
+	var keys []string
+
+	for key := range oldSettings {
+		keys = append(keys, key)
+	}
+
+	for key := range newSettings {
+		if _, ok := oldSettings[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		oldValue, hadOld := oldSettings[key]
+		newValue, hasNew := newSettings[key]
+
+		if !hadOld {
+			oldValue = "(unset)"
+		}
+
+		if !hasNew {
+			newValue = "(unset)"
+		}
+
+		if oldValue != newValue {
+			// don't put secrets in the logs
+			if key == "replica.api_key" {
+				oldValue, newValue = "(hidden)", "(hidden)"
+			}
+
+			changes = append(changes, key+": "+oldValue+" -> "+newValue)
+		}
+	}
+
+	return changes
+}
+
+// flattenConfig turns the config into dotted keys (rate_limit.limits.default.burst) and their json values
+func flattenConfig(config Config) map[string]string {
+	settings := map[string]string{}
+
+	data, err := json.Marshal(config)
+
+	if err != nil {
+		return settings
+	}
+
+	var generic map[string]interface{}
+
+	err = json.Unmarshal(data, &generic)
+
+	if err != nil {
+		return settings
+	}
+
+	flattenInto(settings, "", generic)
+
+	return settings
+}
+
+func flattenInto(settings map[string]string, prefix string, value interface{}) {
+	if object, ok := value.(map[string]interface{}); ok {
+		for key, child := range object {
+			flattenInto(settings, strings.TrimPrefix(prefix+"."+key, "."), child)
+		}
+
+		return
+	}
+
+	// lists (like allowed origins) are short, so they're compared whole
+	data, _ := json.Marshal(value)
+
+	settings[prefix] = string(data)
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestConfigWatcherReload(t *testing.T) {
+	previous := GetConfig()
+	defer SetConfig(previous)
+
+	SetConfig(DefaultConfig())
+
+	path := filepath.Join(t.TempDir(), "config.json")
+
+	if err := os.WriteFile(path, []byte(`{"cors": {"max_age": 60}}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	watcher := NewConfigWatcher(path)
+
+	if watcher.changed() {
+		t.Fatal("the file we started with counts as changed")
+	}
+
+	watcher.reload("test")
+
+	// nothing changes until it's applied
+	if GetConfig().CORS.MaxAge == 60 {
+		t.Fatal("the config was swapped in before ApplyPending")
+	}
+
+	if !watcher.ApplyPending() || GetConfig().CORS.MaxAge != 60 {
+		t.Fatal("the reloaded config wasn't applied")
+	}
+
+	if watcher.ApplyPending() {
+		t.Fatal("applied the same config twice")
+	}
+
+	// a broken file is ignored
+	if err := os.WriteFile(path, []byte(`{"cors": {"max_age": -1}}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	watcher.reload("test")
+
+	if watcher.ApplyPending() || GetConfig().CORS.MaxAge != 60 {
+		t.Fatal("an invalid config was applied")
+	}
+
+	// and so is a missing one, rather than swapping in the defaults
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+
+	if !watcher.changed() {
+		t.Fatal("deleting the file doesn't count as a change")
+	}
+
+	watcher.reload("test")
+
+	if watcher.ApplyPending() || GetConfig().CORS.MaxAge != 60 {
+		t.Fatal("a missing config file reset the config")
+	}
+
+	if watcher.changed() {
+		t.Fatal("a missing file should only be tried once")
+	}
+
+	// once it's back it's picked up again
+	if err := os.WriteFile(path, []byte(`{"cors": {"max_age": 120}}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if !watcher.changed() {
+		t.Fatal("the file coming back doesn't count as a change")
+	}
+
+	watcher.reload("test")
+
+	if !watcher.ApplyPending() || GetConfig().CORS.MaxAge != 120 {
+		t.Fatal("the restored config wasn't applied")
+	}
+}
+
+func TestConfigWatcherChanged(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+
+	if err := os.WriteFile(path, []byte(`{}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	watcher := NewConfigWatcher(path)
+
+	later := time.Now().Add(time.Minute)
+
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatal(err)
+	}
+
+	if !watcher.changed() {
+		t.Fatal("a new modification time doesn't count as a change")
+	}
+}
+
+func TestConfigDiff(t *testing.T) {
+	old := DefaultConfig()
+	new := DefaultConfig()
+
+	new.CORS.MaxAge = old.CORS.MaxAge + 1
+	new.Replica.APIKey = "secret"
+	new.Tokens[1].Symbol = "RENAMED"
+
+	removed := new.Tokens[len(new.Tokens)-1]
+	new.Tokens = new.Tokens[:len(new.Tokens)-1]
+
+	changes := ConfigDiff(old, new)
+
+	want := []string{
+		"tokens: " + old.Tokens[1].CoinGeckoID + " symbol " + old.Tokens[1].Symbol + " -> RENAMED",
+		"tokens: removed " + removed.CoinGeckoID + " (" + removed.Symbol + ")",
+		"cors.max_age: " + strconv.Itoa(old.CORS.MaxAge) + " -> " + strconv.Itoa(new.CORS.MaxAge),
+		"replica.api_key: (hidden) -> (hidden)",
+	}
+
+	if !reflect.DeepEqual(changes, want) {
+		t.Fatalf("ConfigDiff() = %q, want %q", changes, want)
+	}
+
+	if changes = ConfigDiff(old, DefaultConfig()); len(changes) != 0 {
+		t.Fatalf("identical configs differ: %q", changes)
+	}
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// the longest a client can ask us to hold a delta request open for
+const maxDeltaWait = 60 * time.Second
+
+// APIDelta is what changed between two snapshot versions
+type APIDelta struct {
+	FromVersion uint64 `json:"from_version" doc:"Version the delta starts from"`
+	ToVersion   uint64 `json:"to_version" doc:"Version the delta brings you to, send it as since next time"`
+	Reset       bool   `json:"reset" doc:"The from version is too old to remember (or from before the server restarted), so the delta is from nothing (drop everything you have)"`
+
+	AddedOrders       []APIOrder          `json:"added_orders" doc:"Orders that are new (or newly past the reference price)"`
+	RemovedOrders     []APIOrderRef       `json:"removed_orders" doc:"Orders that are gone (filled, cancelled or no longer past the reference price)"`
+	ChangedQuantities []APIQuantityChange `json:"changed_quantities" doc:"Orders that have been partly filled"`
+	ChangedPrices     []APIPriceChange    `json:"changed_prices" doc:"Tokens whose reference price (in HIVE) has changed"`
+	RemovedSymbols    []string            `json:"removed_symbols" doc:"Tokens that are gone (taken out of the registry), drop them and their orders"`
+}
+
+// APIOrderRef points at an order that isn't there anymore
+type APIOrderRef struct {
+	TransactionID string `json:"txId" doc:"Hive Engine transaction id"`
+	Symbol        string `json:"symbol" doc:"Token symbol, e.g. BTC"`
+	Side          string `json:"side" doc:"buy or sell"`
+}
+
+type APIQuantityChange struct {
+	TransactionID string          `json:"txId" doc:"Hive Engine transaction id"`
+	Symbol        string          `json:"symbol" doc:"Token symbol, e.g. BTC"`
+	Side          string          `json:"side" doc:"buy or sell"`
+	OldQuantity   decimal.Decimal `json:"old_quantity" doc:"Quantity at the from version"`
+	NewQuantity   decimal.Decimal `json:"new_quantity" doc:"Quantity now"`
+}
+
+type APIPriceChange struct {
+	Symbol   string          `json:"symbol" doc:"Token symbol, e.g. BTC"`
+	OldPrice decimal.Decimal `json:"old_price" doc:"Reference price in HIVE at the from version"`
+	NewPrice decimal.Decimal `json:"new_price" doc:"Reference price in HIVE now"`
+}
+
+// ComputeDelta works out what changed from one index to another, a nil from means everything is new
+func ComputeDelta(from *TokenIndex, to *TokenIndex) APIDelta {
+	if from == nil {
+		from = NewTokenIndex(nil)
+	}
+
+	if to == nil {
+		to = NewTokenIndex(nil)
+	}
+
+	delta := APIDelta{
+		AddedOrders:       []APIOrder{},
+		RemovedOrders:     []APIOrderRef{},
+		ChangedQuantities: []APIQuantityChange{},
+		ChangedPrices:     []APIPriceChange{},
+		RemovedSymbols:    []string{},
+	}
+
+	for txID, order := range to.orders {
+		oldOrder, existed := from.orders[txID]
+
+		if !existed {
+			delta.AddedOrders = append(delta.AddedOrders, NewAPIOrderWithSide(order.Order, order.Side))
+		} else if !oldOrder.Order.Quantity.Equal(order.Order.Quantity) {
+			delta.ChangedQuantities = append(delta.ChangedQuantities, APIQuantityChange{
+				TransactionID: txID,
+				Symbol:        order.Symbol,
+				Side:          order.Side,
+				OldQuantity:   oldOrder.Order.Quantity,
+				NewQuantity:   order.Order.Quantity,
+			})
+		}
+	}
+
+	for txID, order := range from.orders {
+		if _, stillThere := to.orders[txID]; !stillThere {
+			delta.RemovedOrders = append(delta.RemovedOrders, APIOrderRef{TransactionID: txID, Symbol: order.Symbol, Side: order.Side})
+		}
+	}
+
+	for symbol, token := range to.tokens {
+		oldPrice := decimal.Zero
+
+		if oldToken, existed := from.tokens[symbol]; existed {
+			oldPrice = oldToken.HIVEPrice
+		}
+
+		if !oldPrice.Equal(token.HIVEPrice) {
+			delta.ChangedPrices = append(delta.ChangedPrices, APIPriceChange{Symbol: symbol, OldPrice: oldPrice, NewPrice: token.HIVEPrice})
+		}
+	}
+
+	for symbol := range from.tokens {
+		if _, stillThere := to.tokens[symbol]; !stillThere {
+			delta.RemovedSymbols = append(delta.RemovedSymbols, symbol)
+		}
+	}
+
+	// maps come out in any order, make the response stable
+	sort.Slice(delta.AddedOrders, func(i, j int) bool { return delta.AddedOrders[i].TransactionID < delta.AddedOrders[j].TransactionID })
+	sort.Slice(delta.RemovedOrders, func(i, j int) bool {
+		return delta.RemovedOrders[i].TransactionID < delta.RemovedOrders[j].TransactionID
+	})
+	sort.Slice(delta.ChangedQuantities, func(i, j int) bool {
+		return delta.ChangedQuantities[i].TransactionID < delta.ChangedQuantities[j].TransactionID
+	})
+	sort.Slice(delta.ChangedPrices, func(i, j int) bool { return delta.ChangedPrices[i].Symbol < delta.ChangedPrices[j].Symbol })
+	sort.Strings(delta.RemovedSymbols)
+
+	return delta
+}
+
+func handleDeltas(w http.ResponseWriter, r *http.Request) {
+	since, err := strconv.ParseUint(r.URL.Query().Get("since"), 10, 64)
+
+	if err != nil {
+		WriteAPIError(w, http.StatusBadRequest, "since must be a snapshot version (0 for everything)")
+		return
+	}
+
+	var wait time.Duration
+
+	if value := r.URL.Query().Get("wait"); value != "" {
+		seconds, err := strconv.Atoi(value)
+
+		if err != nil || seconds < 0 {
+			WriteAPIError(w, http.StatusBadRequest, "wait must be a whole number of seconds")
+			return
+		}
+
+		wait = time.Duration(seconds) * time.Second
+
+		if wait > maxDeltaWait {
+			wait = maxDeltaWait
+		}
+	}
+
+	fromIndex, snapshot := SnapshotsSince(r.Context(), since, wait)
+
+	// the same orders /prices would serve now, the old index was filtered when it was published
+	delta := ComputeDelta(fromIndex, snapshot.Index.Filtered(GetConfig().Filters, snapshot.Now()))
+	delta.FromVersion = since
+	delta.ToVersion = snapshot.Version
+	delta.Reset = fromIndex == nil
+
+	setSnapshotHeaders(w, snapshot)
+	w.Header().Set("Cache-Control", "no-store")
+
+	writeJSON(w, delta)
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"context"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/CADawg/hive-swap-calculator/engine"
+	"github.com/CADawg/hive-swap-calculator/market"
+	"github.com/CADawg/hive-swap-calculator/pricing"
+	"github.com/goccy/go-json"
+	"github.com/shopspring/decimal"
+)
+
+func deltaOrder(txID string, quantity int64) engine.MarketOrder {
+	return engine.MarketOrder{TransactionID: txID, Symbol: "SWAP.BTC", Quantity: decimal.NewFromInt(quantity), Price: decimal.NewFromInt(1)}
+}
+
+func TestComputeDelta(t *testing.T) {
+	from := NewTokenIndex([]pricing.TokenData{{
+		Symbol:     "BTC",
+		HIVEPrice:  decimal.NewFromInt(100),
+		SellOrders: []engine.MarketOrder{deltaOrder("kept", 5), deltaOrder("filled", 1), deltaOrder("same", 2)},
+	}})
+
+	to := NewTokenIndex([]pricing.TokenData{
+		{
+			Symbol:     "BTC",
+			HIVEPrice:  decimal.NewFromInt(101),
+			SellOrders: []engine.MarketOrder{deltaOrder("kept", 3), deltaOrder("same", 2)},
+			BuyOrders:  []engine.MarketOrder{deltaOrder("new", 1)},
+		},
+		{Symbol: "ETH", HIVEPrice: decimal.NewFromInt(10)},
+	})
+
+	delta := ComputeDelta(from, to)
+
+	if len(delta.AddedOrders) != 1 || delta.AddedOrders[0].TransactionID != "new" || delta.AddedOrders[0].Side != market.SideBuy {
+		t.Errorf("added = %+v", delta.AddedOrders)
+	}
+
+	if len(delta.RemovedOrders) != 1 || delta.RemovedOrders[0] != (APIOrderRef{TransactionID: "filled", Symbol: "BTC", Side: market.SideSell}) {
+		t.Errorf("removed = %+v", delta.RemovedOrders)
+	}
+
+	if len(delta.ChangedQuantities) != 1 || delta.ChangedQuantities[0].TransactionID != "kept" ||
+		!delta.ChangedQuantities[0].OldQuantity.Equal(decimal.NewFromInt(5)) || !delta.ChangedQuantities[0].NewQuantity.Equal(decimal.NewFromInt(3)) {
+		t.Errorf("changed quantities = %+v", delta.ChangedQuantities)
+	}
+
+	if len(delta.ChangedPrices) != 2 || delta.ChangedPrices[0].Symbol != "BTC" || delta.ChangedPrices[1].Symbol != "ETH" || !delta.ChangedPrices[1].OldPrice.IsZero() {
+		t.Errorf("changed prices = %+v", delta.ChangedPrices)
+	}
+
+	// from nothing everything is new, and nothing is nil so clients always get lists
+	everything := ComputeDelta(nil, to)
+
+	if len(everything.AddedOrders) != 3 || everything.RemovedOrders == nil || everything.ChangedQuantities == nil {
+		t.Errorf("delta from nothing = %+v", everything)
+	}
+}
+
+func getDelta(t *testing.T, query string) APIDelta {
+	t.Helper()
+
+	w := httptest.NewRecorder()
+	handleDeltas(w, httptest.NewRequest("GET", "/api/v1/deltas?"+query, nil))
+
+	var delta APIDelta
+
+	err := json.Unmarshal(w.Body.Bytes(), &delta)
+
+	if err != nil {
+		t.Fatalf("%d %s", w.Code, w.Body.String())
+	}
+
+	return delta
+}
+
+func TestDeltasAcrossVersions(t *testing.T) {
+	PublishSnapshot(&Snapshot{Tokens: []pricing.TokenData{{Symbol: "BTC", SellOrders: []engine.MarketOrder{deltaOrder("a", 1), deltaOrder("b", 1)}}}})
+	first := CurrentSnapshot().Version
+
+	if first < versionEpoch {
+		t.Fatalf("version %d is below this process's epoch %d", first, versionEpoch)
+	}
+
+	PublishSnapshot(&Snapshot{Tokens: []pricing.TokenData{{Symbol: "BTC", SellOrders: []engine.MarketOrder{deltaOrder("a", 1)}}}})
+	second := CurrentSnapshot().Version
+
+	if second != first+1 {
+		t.Fatalf("versions went %d then %d", first, second)
+	}
+
+	delta := getDelta(t, "since="+strconv.FormatUint(first, 10))
+
+	if delta.Reset || delta.ToVersion != second || len(delta.RemovedOrders) != 1 || delta.RemovedOrders[0].TransactionID != "b" {
+		t.Fatalf("delta since %d = %+v", first, delta)
+	}
+
+	// a version from before a restart (the last process's versions are below our epoch) can't be diffed against
+	if delta = getDelta(t, "since="+strconv.FormatUint(versionEpoch-1, 10)); !delta.Reset || len(delta.AddedOrders) != 1 {
+		t.Fatalf("delta from an old process = %+v, want a reset", delta)
+	}
+
+	if delta = getDelta(t, "since=0"); !delta.Reset {
+		t.Fatalf("delta from 0 = %+v, want a reset", delta)
+	}
+
+	// a version we've never had is reset straight away instead of being held open
+	started := time.Now()
+
+	if delta = getDelta(t, "since="+strconv.FormatUint(second+100, 10)+"&wait=5"); !delta.Reset || delta.ToVersion != second {
+		t.Fatalf("delta from the future = %+v, want a reset", delta)
+	}
+
+	if time.Since(started) > 2*time.Second {
+		t.Fatal("a version from the future was long polled")
+	}
+}
+
+func TestSnapshotsSinceWaits(t *testing.T) {
+	PublishSnapshot(&Snapshot{})
+	current := CurrentSnapshot().Version
+
+	// nothing newer, so it waits it out
+	started := time.Now()
+
+	if _, snapshot := SnapshotsSince(context.Background(), current, 50*time.Millisecond); snapshot.Version != current || time.Since(started) < 50*time.Millisecond {
+		t.Fatal("didn't wait for a newer version")
+	}
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		PublishSnapshot(&Snapshot{})
+	}()
+
+	fromIndex, snapshot := SnapshotsSince(context.Background(), current, 5*time.Second)
+
+	if snapshot.Version != current+1 || fromIndex == nil {
+		t.Fatalf("woke up with version %d (want %d) and index %v", snapshot.Version, current+1, fromIndex)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, snapshot = SnapshotsSince(ctx, current+1, 5*time.Second); snapshot.Version != current+1 {
+		t.Fatal("a cancelled wait didn't return what we have")
+	}
+}
+
+func TestDeltasAreFilteredAndReportRemovedTokens(t *testing.T) {
+	previous := GetConfig()
+	defer SetConfig(previous)
+
+	config := DefaultConfig()
+	config.Filters.DenyAccounts = []string{"mallory"}
+	SetConfig(config)
+
+	hidden := deltaOrder("hidden", 1)
+	hidden.Account = "mallory"
+
+	PublishSnapshot(&Snapshot{Tokens: []pricing.TokenData{
+		{Symbol: "BTC", HIVEPrice: decimal.NewFromInt(100), SellOrders: []engine.MarketOrder{deltaOrder("a", 1)}},
+		{Symbol: "DOGE", HIVEPrice: decimal.NewFromInt(1)},
+	}})
+	first := CurrentSnapshot().Version
+
+	// DOGE is taken out of the registry and a denied account's order turns up
+	PublishSnapshot(&Snapshot{Tokens: []pricing.TokenData{
+		{Symbol: "BTC", HIVEPrice: decimal.NewFromInt(100), SellOrders: []engine.MarketOrder{deltaOrder("a", 1), hidden}},
+	}})
+
+	delta := getDelta(t, "since="+strconv.FormatUint(first, 10))
+
+	if len(delta.AddedOrders) != 0 {
+		t.Fatalf("added = %+v, the denied account's order leaked", delta.AddedOrders)
+	}
+
+	if !equalStrings(delta.RemovedSymbols, []string{"DOGE"}) {
+		t.Fatalf("removed symbols = %v, want [DOGE]", delta.RemovedSymbols)
+	}
+
+	// a reset is only what's visible too
+	if delta = getDelta(t, "since=0"); len(delta.AddedOrders) != 1 || delta.AddedOrders[0].TransactionID != "a" {
+		t.Fatalf("reset added = %+v", delta.AddedOrders)
+	}
+
+	// once the account's allowed again its order shows up as added
+	config.Filters.DenyAccounts = nil
+	SetConfig(config)
+
+	if delta = getDelta(t, "since="+strconv.FormatUint(first+1, 10)); len(delta.AddedOrders) != 1 || delta.AddedOrders[0].TransactionID != "hidden" {
+		t.Fatalf("added = %+v, want the no longer hidden order", delta.AddedOrders)
+	}
+}
@@ -0,0 +1,468 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/CADawg/hive-swap-calculator/market"
+	"github.com/goccy/go-json"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+const (
+	ContentTypeJSON    = "application/json"
+	ContentTypeNDJSON  = "application/x-ndjson"
+	ContentTypeCSV     = "text/csv"
+	ContentTypeMsgPack = "application/msgpack"
+)
+
+// formatAliases lets ?format= pick an encoding for clients that can't set Accept (e.g. a browser link into a spreadsheet)
+var formatAliases = map[string]string{
+	"json":    ContentTypeJSON,
+	"ndjson":  ContentTypeNDJSON,
+	"csv":     ContentTypeCSV,
+	"msgpack": ContentTypeMsgPack,
+}
+
+// contentTypeAliases are other names clients commonly send for the formats we support
+var contentTypeAliases = map[string]string{
+	"application/x-msgpack": ContentTypeMsgPack,
+	"application/jsonl":     ContentTypeNDJSON,
+	"application/jsonlines": ContentTypeNDJSON,
+	"application/csv":       ContentTypeCSV,
+}
+
+// supportedContentTypes in order of preference when the client doesn't mind
+var supportedContentTypes = []string{ContentTypeJSON, ContentTypeNDJSON, ContentTypeCSV, ContentTypeMsgPack}
+
+var orderFields = jsonFieldNames(reflect.TypeOf(APIOrder{}))
+
+// NegotiateContentType picks the response encoding from ?format= or the Accept header
+func NegotiateContentType(r *http.Request) (string, error) {
+	if format := r.URL.Query().Get("format"); format != "" {
+		contentType, ok := formatAliases[strings.ToLower(format)]
+
+		if !ok {
+			return "", errors.New("format must be one of json, ndjson, csv or msgpack")
+		}
+
+		return contentType, nil
+	}
+
+	accept := r.Header.Get("Accept")
+
+	if accept == "" {
+		return ContentTypeJSON, nil
+	}
+
+	best, bestQuality := "", 0.0
+
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+
+		if err != nil {
+			continue
+		}
+
+		quality := 1.0
+
+		if q, ok := params["q"]; ok {
+			quality, err = strconv.ParseFloat(q, 64)
+
+			if err != nil {
+				continue
+			}
+		}
+
+		if alias, ok := contentTypeAliases[mediaType]; ok {
+			mediaType = alias
+		}
+
+		for _, contentType := range supportedContentTypes {
+			if mediaTypeMatches(mediaType, contentType) && quality > bestQuality {
+				best, bestQuality = contentType, quality
+				break
+			}
+		}
+	}
+
+	if best == "" {
+		return "", errors.New("no acceptable content type, we can send " + strings.Join(supportedContentTypes, ", "))
+	}
+
+	return best, nil
+}
+
+func mediaTypeMatches(pattern string, contentType string) bool {
+	if pattern == "*/*" || pattern == contentType {
+		return true
+	}
+
+	prefix, ok := strings.CutSuffix(pattern, "/*")
+
+	return ok && strings.HasPrefix(contentType, prefix+"/")
+}
+
+// WriteTokens encodes a list of tokens (with their orders) in whatever format the client asked for
+func WriteTokens(w http.ResponseWriter, r *http.Request, tokens interface{}) {
+	writeNegotiated(w, r, tokens, false, encodeCSV)
+}
+
+// WriteToken encodes a single token, as an object for json/msgpack and as a single item list for the rest
+func WriteToken(w http.ResponseWriter, r *http.Request, token interface{}) {
+	writeNegotiated(w, r, token, true, encodeCSV)
+}
+
+// WriteOrders encodes a list of orders in whatever format the client asked for
+func WriteOrders(w http.ResponseWriter, r *http.Request, orders interface{}) {
+	writeNegotiated(w, r, orders, false, encodeOrdersCSV)
+}
+
+// WriteOrder encodes a single order, like WriteToken
+func WriteOrder(w http.ResponseWriter, r *http.Request, order interface{}) {
+	writeNegotiated(w, r, order, true, encodeOrdersCSV)
+}
+
+func writeNegotiated(w http.ResponseWriter, r *http.Request, items interface{}, single bool, csvEncoder func(io.Writer, interface{}) error) {
+	contentType, err := NegotiateContentType(r)
+
+	if err != nil {
+		WriteAPIError(w, http.StatusNotAcceptable, err.Error())
+		return
+	}
+
+	// everything other than plain json goes through the generic json form, so decimals stay as their exact strings
+	var buf bytes.Buffer
+
+	switch contentType {
+	case ContentTypeJSON:
+		err = json.NewEncoder(&buf).Encode(items)
+	case ContentTypeNDJSON:
+		if single {
+			items = []interface{}{items}
+		}
+
+		err = encodeNDJSON(&buf, items)
+	case ContentTypeCSV:
+		if single {
+			items = []interface{}{items}
+		}
+
+		err = csvEncoder(&buf, items)
+	case ContentTypeMsgPack:
+		err = encodeMsgPack(&buf, items)
+	}
+
+	if err != nil {
+		WriteAPIError(w, http.StatusInternalServerError, "error encoding response")
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Add("Vary", "Accept")
+
+	_, _ = w.Write(buf.Bytes())
+}
+
+// toGeneric round trips v through json, giving maps, slices, strings, bools and json.Numbers
+func toGeneric(v interface{}) (interface{}, error) {
+	data, err := json.Marshal(v)
+
+	if err != nil {
+		return nil, err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var generic interface{}
+
+	err = decoder.Decode(&generic)
+
+	return generic, err
+}
+
+func toGenericList(v interface{}) ([]interface{}, error) {
+	generic, err := toGeneric(v)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if generic == nil {
+		return nil, nil
+	}
+
+	list, ok := generic.([]interface{})
+
+	if !ok {
+		return nil, errors.New("expected a list to encode")
+	}
+
+	return list, nil
+}
+
+// encodeNDJSON writes one item per line
+func encodeNDJSON(w io.Writer, items interface{}) error {
+	list, err := toGenericList(items)
+
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(w)
+
+	for _, item := range list {
+		err = encoder.Encode(item)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// encodeCSV writes one row per order, with the token's own fields repeated on every row.
+// A token without any orders still gets a single row so it doesn't vanish from the sheet.
+func encodeCSV(w io.Writer, items interface{}) error {
+	list, err := toGenericList(items)
+
+	if err != nil {
+		return err
+	}
+
+	var tokenColumns, orderColumns []string
+	var sides = []string{"sell_orders", "buy_orders"}
+
+	// work out which columns are actually present (sparse fieldsets drop some)
+	for _, item := range list {
+		token, _ := item.(map[string]interface{})
+
+		tokenColumns = appendPresentColumns(tokenColumns, tokenFields, token)
+
+		for _, side := range sides {
+			orders, _ := token[side].([]interface{})
+
+			for _, order := range orders {
+				orderData, _ := order.(map[string]interface{})
+
+				orderColumns = appendPresentColumns(orderColumns, orderFields, orderData)
+			}
+		}
+	}
+
+	// the order lists become rows, not columns
+	var scalarTokenColumns []string
+
+	for _, name := range tokenColumns {
+		if !market.ContainsString(sides, name) {
+			scalarTokenColumns = append(scalarTokenColumns, name)
+		}
+	}
+
+	writer := csv.NewWriter(w)
+
+	header := append(append([]string{}, scalarTokenColumns...), "side")
+
+	for _, name := range orderColumns {
+		// tokens and orders both have a symbol, keep the header names unique
+		if market.ContainsString(scalarTokenColumns, name) {
+			name = "order_" + name
+		}
+
+		header = append(header, name)
+	}
+
+	err = writer.Write(header)
+
+	if err != nil {
+		return err
+	}
+
+	for _, item := range list {
+		token, _ := item.(map[string]interface{})
+
+		var tokenRow []string
+
+		for _, name := range scalarTokenColumns {
+			tokenRow = append(tokenRow, csvValue(token[name]))
+		}
+
+		wroteOrder := false
+
+		for _, side := range sides {
+			orders, _ := token[side].([]interface{})
+
+			for _, order := range orders {
+				orderData, _ := order.(map[string]interface{})
+
+				err = writer.Write(csvRow(tokenRow, strings.TrimSuffix(side, "_orders"), orderColumns, orderData))
+
+				if err != nil {
+					return err
+				}
+
+				wroteOrder = true
+			}
+		}
+
+		if !wroteOrder {
+			err = writer.Write(csvRow(tokenRow, "", orderColumns, nil))
+
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	writer.Flush()
+
+	return writer.Error()
+}
+
+// encodeOrdersCSV writes a plain list of orders, one per row
+func encodeOrdersCSV(w io.Writer, items interface{}) error {
+	list, err := toGenericList(items)
+
+	if err != nil {
+		return err
+	}
+
+	var columns []string
+
+	for _, item := range list {
+		order, _ := item.(map[string]interface{})
+
+		columns = appendPresentColumns(columns, orderFields, order)
+	}
+
+	writer := csv.NewWriter(w)
+
+	err = writer.Write(columns)
+
+	if err != nil {
+		return err
+	}
+
+	for _, item := range list {
+		order, _ := item.(map[string]interface{})
+
+		var row []string
+
+		for _, name := range columns {
+			row = append(row, csvValue(order[name]))
+		}
+
+		err = writer.Write(row)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+
+	return writer.Error()
+}
+
+// appendPresentColumns adds any of names that are in fields to columns, in the order of names (so columns are stable)
+func appendPresentColumns(columns []string, names []string, fields map[string]interface{}) []string {
+	for _, name := range names {
+		if _, ok := fields[name]; ok && !market.ContainsString(columns, name) {
+			columns = append(columns, name)
+		}
+	}
+
+	return columns
+}
+
+func csvRow(tokenRow []string, side string, columns []string, fields map[string]interface{}) []string {
+	row := append(append([]string{}, tokenRow...), side)
+
+	for _, name := range columns {
+		row = append(row, csvValue(fields[name]))
+	}
+
+	return row
+}
+
+func csvValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case bool:
+		return strconv.FormatBool(v)
+	default:
+		data, _ := json.Marshal(v)
+		return string(data)
+	}
+}
+
+// encodeMsgPack writes items as MessagePack, decimals are sent as strings just like in the json
+func encodeMsgPack(w io.Writer, items interface{}) error {
+	generic, err := toGeneric(items)
+
+	if err != nil {
+		return err
+	}
+
+	value, err := msgPackValue(generic)
+
+	if err != nil {
+		return err
+	}
+
+	encoder := msgpack.NewEncoder(w)
+	// sorted so the same data always gives the same bytes
+	encoder.SetSortMapKeys(true)
+	encoder.UseCompactInts(true)
+
+	return encoder.Encode(value)
+}
+
+// msgPackValue turns the integer json.Numbers from toGeneric into real ints. Anything with a fraction is kept
+// as its exact string, like the decimals in the json output, since a float64 would lose digits.
+func msgPackValue(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i, nil
+		}
+
+		return v.String(), nil
+	case []interface{}:
+		for i, item := range v {
+			converted, err := msgPackValue(item)
+
+			if err != nil {
+				return nil, err
+			}
+
+			v[i] = converted
+		}
+	case map[string]interface{}:
+		for key, item := range v {
+			converted, err := msgPackValue(item)
+
+			if err != nil {
+				return nil, err
+			}
+
+			v[key] = converted
+		}
+	}
+
+	return value, nil
+}
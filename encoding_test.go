@@ -0,0 +1,217 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/goccy/go-json"
+	"github.com/shopspring/decimal"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+func TestNegotiateContentType(t *testing.T) {
+	tests := []struct {
+		url    string
+		accept string
+		want   string
+	}{
+		{"/prices", "", ContentTypeJSON},
+		{"/prices", "*/*", ContentTypeJSON},
+		{"/prices", "text/csv", ContentTypeCSV},
+		{"/prices", "application/x-msgpack", ContentTypeMsgPack},
+		{"/prices", "application/json;q=0.5, application/x-ndjson", ContentTypeNDJSON},
+		{"/prices", "text/*", ContentTypeCSV},
+		{"/prices?format=MSGPACK", "text/csv", ContentTypeMsgPack},
+		{"/prices", "image/png", ""},
+		{"/prices?format=xml", "", ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.url+" "+test.accept, func(t *testing.T) {
+			r := httptest.NewRequest("GET", test.url, nil)
+			r.Header.Set("Accept", test.accept)
+
+			contentType, err := NegotiateContentType(r)
+
+			if test.want == "" {
+				if err == nil {
+					t.Fatalf("expected an error, got %s", contentType)
+				}
+
+				return
+			}
+
+			if err != nil || contentType != test.want {
+				t.Fatalf("got %q (%v), want %q", contentType, err, test.want)
+			}
+		})
+	}
+}
+
+// decodeMsgPack decodes with the library's own decoder, as a client would
+func decodeMsgPack(t *testing.T, data []byte) interface{} {
+	t.Helper()
+
+	var value interface{}
+
+	decoder := msgpack.NewDecoder(bytes.NewReader(data))
+	decoder.SetMapDecoder(func(d *msgpack.Decoder) (interface{}, error) {
+		return d.DecodeUntypedMap()
+	})
+
+	err := decoder.Decode(&value)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return value
+}
+
+func TestEncodeMsgPackRoundTrip(t *testing.T) {
+	wideMap := map[string]interface{}{}
+
+	for i := 0; i < 20; i++ {
+		wideMap["key"+strconv.Itoa(i)] = i
+	}
+
+	hugeMap := map[string]interface{}{}
+
+	for i := 0; i < 70000; i++ {
+		hugeMap["k"+strconv.Itoa(i)] = nil
+	}
+
+	tests := []struct {
+		name  string
+		value interface{}
+		want  interface{}
+	}{
+		{"nil", nil, nil},
+		{"bool", true, true},
+		{"small int", 7, int8(7)},
+		{"negative int", -5, int8(-5)},
+		{"large int", int64(1) << 40, uint64(1) << 40},
+		{"large negative int", -300, int16(-300)},
+		{"float is its exact string", 1.5, "1.5"},
+		{"long fraction keeps its digits", json.Number("0.12345678901234567890123"), "0.12345678901234567890123"},
+		{"decimal is a string", decimal.RequireFromString("0.00012345"), "0.00012345"},
+		{"long string", strings.Repeat("a", 300), strings.Repeat("a", 300)},
+		{"list", []interface{}{1, "a", nil}, []interface{}{int8(1), "a", nil}},
+		{"fixmap", map[string]interface{}{"a": 1}, map[interface{}]interface{}{"a": int8(1)}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var buf bytes.Buffer
+
+			err := encodeMsgPack(&buf, test.value)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := decodeMsgPack(t, buf.Bytes()); !reflect.DeepEqual(got, test.want) {
+				t.Fatalf("got %#v, want %#v", got, test.want)
+			}
+		})
+	}
+
+	// maps past the fixmap (15) and map16 (65535) sizes
+	for _, m := range []map[string]interface{}{wideMap, hugeMap} {
+		var buf bytes.Buffer
+
+		err := encodeMsgPack(&buf, m)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		decoded, ok := decodeMsgPack(t, buf.Bytes()).(map[interface{}]interface{})
+
+		if !ok || len(decoded) != len(m) {
+			t.Fatalf("decoded %d keys, want %d", len(decoded), len(m))
+		}
+	}
+}
+
+func TestWriteNegotiatedEncodeError(t *testing.T) {
+	// a channel can't be encoded in any format
+	for _, accept := range []string{ContentTypeJSON, ContentTypeMsgPack, ContentTypeCSV} {
+		r := httptest.NewRequest("GET", "/tokens", nil)
+		r.Header.Set("Accept", accept)
+		w := httptest.NewRecorder()
+
+		WriteTokens(w, r, make(chan int))
+
+		var apiError APIError
+
+		if w.Code != http.StatusInternalServerError || json.Unmarshal(w.Body.Bytes(), &apiError) != nil || apiError.Error == "" {
+			t.Fatalf("%s: got %d %q", accept, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestEncodeMsgPackIsStable(t *testing.T) {
+	token := APIToken{Symbol: "BTC", HIVEPrice: decimal.NewFromInt(3), SellOrders: []APIOrder{{Account: "a", Expiration: 10}}}
+
+	var first, second bytes.Buffer
+
+	if err := encodeMsgPack(&first, token); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := encodeMsgPack(&second, token); err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(first.Bytes(), second.Bytes()) {
+		t.Fatal("the same token encoded to different bytes")
+	}
+
+	decoded := decodeMsgPack(t, first.Bytes()).(map[interface{}]interface{})
+
+	if decoded["symbol"] != "BTC" || decoded["hive"] != "3" {
+		t.Fatalf("unexpected token %#v", decoded)
+	}
+
+	orders := decoded["sell_orders"].([]interface{})
+
+	if order := orders[0].(map[interface{}]interface{}); order["account"] != "a" || order["expiration"] != int8(10) {
+		t.Fatalf("unexpected order %#v", order)
+	}
+}
+
+func TestEncodeCSV(t *testing.T) {
+	tokens := []APIToken{
+		{Symbol: "BTC", SellOrders: []APIOrder{{Account: "a", Symbol: "SWAP.BTC"}}, BuyOrders: []APIOrder{{Account: "b", Symbol: "SWAP.BTC"}}},
+		{Symbol: "ETH"},
+	}
+
+	var buf bytes.Buffer
+
+	err := encodeCSV(&buf, tokens)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+
+	// a row per order, plus one for the token without any
+	if len(lines) != 4 {
+		t.Fatalf("got %d lines, want 4:\n%s", len(lines), buf.String())
+	}
+
+	if !strings.Contains(lines[0], ",side,") || !strings.Contains(lines[0], "order_symbol") {
+		t.Fatalf("unexpected header %s", lines[0])
+	}
+
+	if !strings.Contains(lines[1], ",sell,a,") || !strings.Contains(lines[2], ",buy,b,") {
+		t.Fatalf("unexpected rows:\n%s", buf.String())
+	}
+}
@@ -0,0 +1,181 @@
+// Package engine is a client for a Hive Engine node's contracts rpc, with the market books on top
+package engine
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+
+	"github.com/CADawg/hive-swap-calculator/upstream"
+	"github.com/goccy/go-json"
+	"github.com/shopspring/decimal"
+)
+
+// DefaultNode is the engine node we use unless told otherwise
+const DefaultNode = "https://engine.rishipanthee.com"
+
+// pageSize is the most a node returns per find
+const pageSize = 1000
+
+type JSONRPCRequest struct {
+	Jsonrpc string `json:"jsonrpc"`
+	ID      int    `json:"id"`
+	Method  string `json:"method"`
+	Params  Params `json:"params"`
+}
+
+type Params struct {
+	Contract string          `json:"contract"`
+	Table    string          `json:"table"`
+	Query    json.RawMessage `json:"query"`
+	Offset   int             `json:"offset"`
+	Limit    int             `json:"limit"`
+}
+
+type JSONRPCResponse struct {
+	Jsonrpc string          `json:"jsonrpc"`
+	ID      int             `json:"id"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   string          `json:"error,omitempty"`
+}
+
+type MarketOrder struct {
+	Account       string          `json:"account"`
+	Expiration    int64           `json:"expiration"`
+	Price         decimal.Decimal `json:"price"`
+	Quantity      decimal.Decimal `json:"quantity"`
+	Symbol        string          `json:"symbol"`
+	Timestamp     int64           `json:"timestamp"`
+	TransactionID string          `json:"txId"`
+	ID            int             `json:"_id"`
+
+	// Calculated fields
+	ProfitPercentage decimal.Decimal `json:"profit_percentage,omitempty"`
+}
+
+// Client talks to one engine node
+type Client struct {
+	node string
+	http *http.Client
+}
+
+// NewClient makes a client for node (e.g. DefaultNode) that sends its requests through httpClient
+func NewClient(node string, httpClient *http.Client) *Client {
+	return &Client{node: node, http: httpClient}
+}
+
+// Node is the node the client talks to
+func (c *Client) Node() string {
+	return c.node
+}
+
+// CallContract runs a find on a contract's table, returning up to a page of results from offset
+func CallContract[T any](c *Client, contract string, table string, query json.RawMessage, offset int) ([]T, error) {
+	// call hive engine contract rpc with contract, method, params, and offset
+	request := JSONRPCRequest{
+		Jsonrpc: "2.0",
+		ID:      1,
+		Method:  "find",
+		Params: Params{
+			Contract: contract,
+			Table:    table,
+			Query:    query,
+			Offset:   offset,
+			Limit:    pageSize,
+		},
+	}
+
+	// encode to stream so the request can take it
+
+	var buf = new(bytes.Buffer)
+
+	err := json.NewEncoder(buf).Encode(request)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// send req
+	req, err := http.NewRequest("POST", c.node+"/contracts", buf)
+
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	// get resp
+	resp, err := c.http.Do(req)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+
+	err = upstream.CheckRateLimited(resp)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var output JSONRPCResponse
+
+	// parse resp
+	var outputResponse []T
+
+	err = json.NewDecoder(resp.Body).Decode(&output)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if output.Error != "" {
+		return nil, errors.New(output.Error)
+	}
+
+	err = json.Unmarshal(output.Result, &outputResponse)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return outputResponse, nil
+}
+
+// CallContractUntilEmpty pages through a find until there's nothing left
+func CallContractUntilEmpty[T any](c *Client, contract string, table string, query json.RawMessage) ([]T, error) {
+	var allResults []T
+
+	results, err := CallContract[T](c, contract, table, query, 0)
+
+	if err != nil {
+		return nil, err
+	}
+
+	allResults = append(allResults, results...)
+
+	for len(results) == pageSize {
+		results, err = CallContract[T](c, contract, table, query, len(allResults))
+
+		if err != nil {
+			return nil, err
+		}
+
+		allResults = append(allResults, results...)
+	}
+
+	return allResults, nil
+}
+
+// We will preload all the market orders for each token and then filter them out later (engine node/network requests are slower than filtering in memory)
+func (c *Client) GetAllSwapSellOrders() ([]MarketOrder, error) {
+	// get all sell orders (for all starting with SWAP.) using mongodb query $regex
+	return CallContractUntilEmpty[MarketOrder](c, "market", "sellBook", []byte(`{"symbol":{"$regex":"^SWAP\\."}}`))
+}
+
+func (c *Client) GetAllSwapBuyOrders() ([]MarketOrder, error) {
+	// get all buy orders (for all starting with SWAP.) using mongodb query $regex
+	return CallContractUntilEmpty[MarketOrder](c, "market", "buyBook", []byte(`{"symbol":{"$regex":"^SWAP\\."}}`))
+}
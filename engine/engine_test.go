@@ -0,0 +1,103 @@
+package engine
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/CADawg/hive-swap-calculator/upstream"
+	"github.com/goccy/go-json"
+)
+
+// fakeNode serves a sell book of size orders through the contracts find rpc, a page at a time
+func fakeNode(t *testing.T, size int) (*Client, *[]JSONRPCRequest) {
+	t.Helper()
+
+	var requests []JSONRPCRequest
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request JSONRPCRequest
+
+		if r.URL.Path != "/contracts" || json.NewDecoder(r.Body).Decode(&request) != nil {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+
+		requests = append(requests, request)
+
+		if request.Params.Table != "sellBook" {
+			_ = json.NewEncoder(w).Encode(JSONRPCResponse{Jsonrpc: "2.0", ID: request.ID, Error: "no table " + request.Params.Table})
+			return
+		}
+
+		orders := []MarketOrder{}
+
+		for i := request.Params.Offset; i < size && i < request.Params.Offset+request.Params.Limit; i++ {
+			orders = append(orders, MarketOrder{ID: i, TransactionID: "tx-" + strconv.Itoa(i), Symbol: "SWAP.BTC"})
+		}
+
+		result, _ := json.Marshal(orders)
+
+		_ = json.NewEncoder(w).Encode(JSONRPCResponse{Jsonrpc: "2.0", ID: request.ID, Result: result})
+	}))
+
+	t.Cleanup(server.Close)
+
+	return NewClient(server.URL, server.Client()), &requests
+}
+
+func TestGetAllSwapSellOrdersPages(t *testing.T) {
+	client, requests := fakeNode(t, pageSize+5)
+
+	orders, err := client.GetAllSwapSellOrders()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(orders) != pageSize+5 || orders[pageSize].TransactionID != "tx-"+strconv.Itoa(pageSize) {
+		t.Fatalf("got %d orders", len(orders))
+	}
+
+	if len(*requests) != 2 || (*requests)[1].Params.Offset != pageSize {
+		t.Fatalf("requests = %+v", *requests)
+	}
+
+	if request := (*requests)[0]; request.Method != "find" || request.Params.Contract != "market" || string(request.Params.Query) != `{"symbol":{"$regex":"^SWAP\\."}}` {
+		t.Fatalf("first request = %+v", request)
+	}
+}
+
+func TestGetAllSwapSellOrdersExactPage(t *testing.T) {
+	client, requests := fakeNode(t, pageSize)
+
+	orders, err := client.GetAllSwapSellOrders()
+
+	if err != nil || len(orders) != pageSize {
+		t.Fatalf("got %d orders, %v", len(orders), err)
+	}
+
+	// a full page might not be the last, so it asks again and gets nothing
+	if len(*requests) != 2 {
+		t.Fatalf("made %d requests", len(*requests))
+	}
+}
+
+func TestCallContractErrors(t *testing.T) {
+	client, _ := fakeNode(t, 0)
+
+	if _, err := client.GetAllSwapBuyOrders(); err == nil || err.Error() != "no table buyBook" {
+		t.Fatalf("got %v, want the node's error", err)
+	}
+
+	limited := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer limited.Close()
+
+	if _, err := NewClient(limited.URL, limited.Client()).GetAllSwapSellOrders(); !errors.Is(err, upstream.ErrRateLimited) {
+		t.Fatalf("got %v, want a rate limit", err)
+	}
+}
@@ -0,0 +1,291 @@
+package main
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/CADawg/hive-swap-calculator/gateway"
+	"github.com/CADawg/hive-swap-calculator/market"
+	"github.com/CADawg/hive-swap-calculator/pricing"
+)
+
+// the events on the bus
+const (
+	// EventSnapshot is sent for every snapshot that's published
+	EventSnapshot = "snapshot"
+	// EventOrderSeen is an order that's new (or newly past the reference price)
+	EventOrderSeen = "order_seen"
+	// EventOrderRemoved is an order that's gone (filled, cancelled or no longer past the reference price)
+	EventOrderRemoved = "order_removed"
+	// EventFeeChanged is a token whose fee (percentage, flat or network) has changed
+	EventFeeChanged = "fee_changed"
+	// EventUpstreamFailed is a source that's started failing (it's not sent again until it's recovered)
+	EventUpstreamFailed = "upstream_failed"
+	// EventUpstreamRecovered is a source that's working again after failing
+	EventUpstreamRecovered = "upstream_recovered"
+)
+
+var AllEvents = []string{EventSnapshot, EventOrderSeen, EventOrderRemoved, EventFeeChanged, EventUpstreamFailed, EventUpstreamRecovered}
+
+// what happens to a new event when a subscriber's queue is full
+const (
+	// DropOldest makes room by throwing away the oldest queued event, for subscribers that only care about what's latest
+	DropOldest = "drop_oldest"
+	// DropNewest throws away the new event, for subscribers that want everything up to the point they fell behind
+	DropNewest = "drop_newest"
+)
+
+// Event is something that happened, only the fields for its type are set
+type Event struct {
+	Type string
+	At   time.Time
+	// Version is the snapshot the event came from (snapshot, order and fee events)
+	Version uint64
+	// Snapshot is the snapshot that was published, don't modify it
+	Snapshot *Snapshot
+	// Order is the order that was seen or removed
+	Order IndexedOrder
+	// Symbol, OldFee and NewFee are the token whose fee changed
+	Symbol string
+	OldFee gateway.TokenFee
+	NewFee gateway.TokenFee
+	// Source is the upstream that failed or recovered, Error is why it failed
+	Source string
+	Error  string
+}
+
+// SubscribeOptions is what a subscriber wants and how much it can fall behind by
+type SubscribeOptions struct {
+	// Types are the events to get, every type if empty
+	Types []string
+	// QueueSize is how many events can wait for the subscriber before the drop policy kicks in
+	QueueSize int
+	// DropPolicy is DropOldest or DropNewest
+	DropPolicy string
+}
+
+// Subscription is one subscriber's queue, read it from Events
+type Subscription struct {
+	name    string
+	options SubscribeOptions
+	types   map[string]bool
+	bus     *EventBus
+
+	// lock is held while queueing, so dropping the oldest event and adding the new one happen together
+	lock    sync.Mutex
+	queue   chan Event
+	dropped atomic.Uint64
+}
+
+// Events is the subscriber's queue, it's closed when the subscriber unsubscribes
+func (s *Subscription) Events() <-chan Event {
+	return s.queue
+}
+
+// Dropped is how many events the subscriber has missed because it was too slow
+func (s *Subscription) Dropped() uint64 {
+	return s.dropped.Load()
+}
+
+// Unsubscribe stops the events and closes the queue (whatever's still in it can be read first)
+func (s *Subscription) Unsubscribe() {
+	s.bus.lock.Lock()
+	defer s.bus.lock.Unlock()
+
+	if _, ok := s.bus.subscribers[s]; !ok {
+		return
+	}
+
+	delete(s.bus.subscribers, s)
+	close(s.queue)
+}
+
+// deliver queues the event without ever waiting for the subscriber
+func (s *Subscription) deliver(event Event) {
+	if len(s.types) > 0 && !s.types[event.Type] {
+		return
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	select {
+	case s.queue <- event:
+		return
+	default:
+	}
+
+	if s.options.DropPolicy == DropNewest {
+		s.dropped.Add(1)
+		return
+	}
+
+	select {
+	case <-s.queue:
+		s.dropped.Add(1)
+	default:
+		// the subscriber's just made room itself
+	}
+
+	select {
+	case s.queue <- event:
+	default:
+		s.dropped.Add(1)
+	}
+}
+
+// EventBus fans events out to every subscriber. Publishing never blocks, a subscriber that can't keep up loses
+// events (by its drop policy) rather than holding up the refresh.
+type EventBus struct {
+	lock        sync.RWMutex
+	subscribers map[*Subscription]bool
+}
+
+func NewEventBus() *EventBus {
+	return &EventBus{subscribers: map[*Subscription]bool{}}
+}
+
+// Events is the bus everything in the server publishes to
+var Events = NewEventBus()
+
+// Subscribe starts queueing events for a new subscriber, the name is just for the admin state
+func (b *EventBus) Subscribe(name string, options SubscribeOptions) (*Subscription, error) {
+	if options.QueueSize < 1 {
+		return nil, errors.New("queue size must be at least 1")
+	}
+
+	if options.DropPolicy != DropOldest && options.DropPolicy != DropNewest {
+		return nil, errors.New("drop policy must be " + DropOldest + " or " + DropNewest)
+	}
+
+	types := map[string]bool{}
+
+	for _, eventType := range options.Types {
+		if !market.ContainsString(AllEvents, eventType) {
+			return nil, errors.New("unknown event " + eventType + ", must be one of " + strings.Join(AllEvents, ", "))
+		}
+
+		types[eventType] = true
+	}
+
+	subscription := &Subscription{name: name, options: options, types: types, bus: b, queue: make(chan Event, options.QueueSize)}
+
+	b.lock.Lock()
+	b.subscribers[subscription] = true
+	b.lock.Unlock()
+
+	return subscription, nil
+}
+
+// Publish sends the events to every subscriber that wants them, in order
+func (b *EventBus) Publish(events ...Event) {
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+
+	for _, event := range events {
+		if event.At.IsZero() {
+			event.At = time.Now()
+		}
+
+		for subscription := range b.subscribers {
+			subscription.deliver(event)
+		}
+	}
+}
+
+// SubscriberStats is how a subscriber is keeping up
+type SubscriberStats struct {
+	Name       string
+	Types      []string
+	DropPolicy string
+	QueueSize  int
+	Queued     int
+	Dropped    uint64
+}
+
+// Stats is how every subscriber is keeping up, by name
+func (b *EventBus) Stats() []SubscriberStats {
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+
+	var stats []SubscriberStats
+
+	for subscription := range b.subscribers {
+		stats = append(stats, SubscriberStats{
+			Name:       subscription.name,
+			Types:      subscription.options.Types,
+			DropPolicy: subscription.options.DropPolicy,
+			QueueSize:  subscription.options.QueueSize,
+			Queued:     len(subscription.queue),
+			Dropped:    subscription.Dropped(),
+		})
+	}
+
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].Name < stats[j].Name
+	})
+
+	return stats
+}
+
+// snapshotEvents is what changed from previous to next, starting with next itself. Orders and fees are only compared
+// once there's a previous snapshot, otherwise everything in the first one would be "new".
+func snapshotEvents(previous *Snapshot, next *Snapshot) []Event {
+	now := time.Now()
+
+	events := []Event{{Type: EventSnapshot, At: now, Version: next.Version, Snapshot: next}}
+
+	if previous.Version == 0 || previous.Index == nil || next.Index == nil {
+		return events
+	}
+
+	var seen, removed []Event
+
+	for txID, order := range next.Index.orders {
+		if _, existed := previous.Index.orders[txID]; !existed {
+			seen = append(seen, Event{Type: EventOrderSeen, At: now, Version: next.Version, Order: order})
+		}
+	}
+
+	for txID, order := range previous.Index.orders {
+		if _, stillThere := next.Index.orders[txID]; !stillThere {
+			removed = append(removed, Event{Type: EventOrderRemoved, At: now, Version: next.Version, Order: order})
+		}
+	}
+
+	var fees []Event
+
+	for symbol, token := range next.Index.tokens {
+		oldToken, existed := previous.Index.tokens[symbol]
+
+		if !existed {
+			continue
+		}
+
+		oldFee, newFee := tokenFee(*oldToken), tokenFee(*token)
+
+		if !oldFee.PercentageFee.Equal(newFee.PercentageFee) || !oldFee.FlatFee.Equal(newFee.FlatFee) || oldFee.Network != newFee.Network {
+			fees = append(fees, Event{Type: EventFeeChanged, At: now, Version: next.Version, Symbol: symbol, OldFee: oldFee, NewFee: newFee})
+		}
+	}
+
+	// maps come out in any order, keep the events stable
+	sort.Slice(seen, func(i, j int) bool { return seen[i].Order.Order.TransactionID < seen[j].Order.Order.TransactionID })
+	sort.Slice(removed, func(i, j int) bool {
+		return removed[i].Order.Order.TransactionID < removed[j].Order.Order.TransactionID
+	})
+	sort.Slice(fees, func(i, j int) bool { return fees[i].Symbol < fees[j].Symbol })
+
+	events = append(events, removed...)
+	events = append(events, seen...)
+
+	return append(events, fees...)
+}
+
+func tokenFee(token pricing.TokenData) gateway.TokenFee {
+	return gateway.TokenFee{PercentageFee: token.NetworkPercentageFee, FlatFee: token.NetworkFlatFee, Network: token.Network}
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/CADawg/hive-swap-calculator/engine"
+	"github.com/CADawg/hive-swap-calculator/pricing"
+	"github.com/shopspring/decimal"
+)
+
+// queued is everything waiting in the subscription, without blocking
+func queued(subscription *Subscription) []Event {
+	var events []Event
+
+	for {
+		select {
+		case event := <-subscription.Events():
+			events = append(events, event)
+		default:
+			return events
+		}
+	}
+}
+
+func TestSubscribeOptions(t *testing.T) {
+	bus := NewEventBus()
+
+	for _, options := range []SubscribeOptions{
+		{QueueSize: 0, DropPolicy: DropOldest},
+		{QueueSize: 1, DropPolicy: "drop_everything"},
+		{QueueSize: 1, DropPolicy: DropOldest, Types: []string{"nope"}},
+	} {
+		if _, err := bus.Subscribe("bad", options); err == nil {
+			t.Errorf("subscribed with %+v", options)
+		}
+	}
+}
+
+func TestEventDropPolicies(t *testing.T) {
+	bus := NewEventBus()
+
+	oldest, err := bus.Subscribe("oldest", SubscribeOptions{QueueSize: 2, DropPolicy: DropOldest})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	newest, err := bus.Subscribe("newest", SubscribeOptions{QueueSize: 2, DropPolicy: DropNewest})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// only wants fee changes, so the snapshots never fill it up
+	fees, err := bus.Subscribe("fees", SubscribeOptions{QueueSize: 1, DropPolicy: DropNewest, Types: []string{EventFeeChanged}})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bus.Publish(Event{Type: EventSnapshot, Version: 1}, Event{Type: EventSnapshot, Version: 2}, Event{Type: EventSnapshot, Version: 3})
+	bus.Publish(Event{Type: EventFeeChanged, Symbol: "BTC"})
+
+	if events := queued(oldest); len(events) != 2 || events[0].Version != 3 || events[1].Type != EventFeeChanged || oldest.Dropped() != 2 {
+		t.Fatalf("drop_oldest kept %+v, dropped %d", events, oldest.Dropped())
+	}
+
+	if events := queued(newest); len(events) != 2 || events[0].Version != 1 || events[1].Version != 2 || newest.Dropped() != 2 {
+		t.Fatalf("drop_newest kept %+v, dropped %d", events, newest.Dropped())
+	}
+
+	if events := queued(fees); len(events) != 1 || events[0].Symbol != "BTC" || events[0].At.IsZero() || fees.Dropped() != 0 {
+		t.Fatalf("fee subscriber got %+v, dropped %d", events, fees.Dropped())
+	}
+
+	if stats := bus.Stats(); len(stats) != 3 || stats[0].Name != "fees" || stats[2].Dropped != 2 {
+		t.Fatalf("stats = %+v", stats)
+	}
+
+	newest.Unsubscribe()
+	newest.Unsubscribe()
+
+	if _, open := <-newest.Events(); open {
+		t.Fatal("the queue is still open")
+	}
+
+	bus.Publish(Event{Type: EventSnapshot})
+
+	if len(bus.Stats()) != 2 {
+		t.Fatal("still subscribed")
+	}
+}
+
+func TestSnapshotEvents(t *testing.T) {
+	btc := func(percentageFee int64, orders ...engine.MarketOrder) pricing.TokenData {
+		return pricing.TokenData{Symbol: "BTC", NetworkPercentageFee: decimal.NewFromInt(percentageFee), NetworkFlatFee: decimal.NewFromInt(1), Network: "btc", SellOrders: orders}
+	}
+
+	previous := &Snapshot{Version: 1, Index: NewTokenIndex([]pricing.TokenData{btc(1, deltaOrder("filled", 1), deltaOrder("kept", 2))})}
+	next := &Snapshot{Version: 2, Index: NewTokenIndex([]pricing.TokenData{
+		btc(2, deltaOrder("kept", 1), deltaOrder("new-b", 1), deltaOrder("new-a", 1)),
+		// new tokens don't count as a fee change
+		{Symbol: "ETH", NetworkPercentageFee: decimal.NewFromInt(5)},
+	})}
+
+	events := snapshotEvents(previous, next)
+
+	want := []string{EventSnapshot, EventOrderRemoved, EventOrderSeen, EventOrderSeen, EventFeeChanged}
+
+	if len(events) != len(want) {
+		t.Fatalf("got %+v", events)
+	}
+
+	for i, event := range events {
+		if event.Type != want[i] || event.Version != 2 {
+			t.Fatalf("event %d = %+v, want a %s", i, event, want[i])
+		}
+	}
+
+	if events[0].Snapshot != next || events[1].Order.Order.TransactionID != "filled" || events[2].Order.Order.TransactionID != "new-a" || events[3].Order.Order.TransactionID != "new-b" {
+		t.Fatalf("got %+v", events)
+	}
+
+	if fee := events[4]; fee.Symbol != "BTC" || !fee.OldFee.PercentageFee.Equal(decimal.NewFromInt(1)) || !fee.NewFee.PercentageFee.Equal(decimal.NewFromInt(2)) {
+		t.Fatalf("fee event = %+v", fee)
+	}
+
+	// the first snapshot is only a snapshot, not every order being new
+	if events = snapshotEvents(&Snapshot{}, next); len(events) != 1 || events[0].Type != EventSnapshot {
+		t.Fatalf("first snapshot got %+v", events)
+	}
+}
+
+func TestUpstreamEvents(t *testing.T) {
+	pipeline := NewPipeline()
+
+	report := func(stages ...StageReport) *CycleReport {
+		return &CycleReport{Stages: stages}
+	}
+
+	failedPrices := StageReport{Stage: StagePrices, Source: SourcePrices, Error: "down"}
+	// fees failed because prices did, which says nothing about the gateways
+	failedFees := StageReport{Stage: StageFees, Source: SourceFees, DependsOn: []string{StagePrices}, Error: "no prices", DependencyFailed: true}
+
+	events := pipeline.upstreamEvents(report(failedPrices, failedFees), []string{SourcePrices, SourceFees})
+
+	if len(events) != 1 || events[0].Type != EventUpstreamFailed || events[0].Source != SourcePrices || events[0].Error != "prices: down" {
+		t.Fatalf("got %+v", events)
+	}
+
+	if result := report(failedFees).SourceResult(SourceFees); result.Failed {
+		t.Fatal("fees backed off for the prices failing")
+	}
+
+	// still failing, nothing new to say
+	if events = pipeline.upstreamEvents(report(failedPrices), []string{SourcePrices}); len(events) != 0 {
+		t.Fatalf("got %+v", events)
+	}
+
+	// fees failing on its own does count, and a dependency failing later doesn't make it look recovered
+	ownFees := failedFees
+	ownFees.DependencyFailed = false
+
+	if events = pipeline.upstreamEvents(report(ownFees), []string{SourceFees}); len(events) != 1 || events[0].Source != SourceFees {
+		t.Fatalf("got %+v", events)
+	}
+
+	if events = pipeline.upstreamEvents(report(failedPrices, failedFees), []string{SourcePrices, SourceFees}); len(events) != 0 {
+		t.Fatalf("got %+v", events)
+	}
+
+	events = pipeline.upstreamEvents(report(StageReport{Stage: StagePrices, Source: SourcePrices}, StageReport{Stage: StageFees, Source: SourceFees}), []string{SourcePrices, SourceFees})
+
+	if len(events) != 2 || events[0].Type != EventUpstreamRecovered || events[1].Type != EventUpstreamRecovered {
+		t.Fatalf("got %+v", events)
+	}
+}
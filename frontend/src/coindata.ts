@@ -95,11 +95,40 @@ export type ParsedCoinData = {
 
 export type ParsedCoinDataArrayOrNull = ParsedCoinData[] | null;
 
+/**
+ * FrontendConfig is the runtime config the backend serves at /config.json
+ */
+export type FrontendConfig = {
+    api_base_url: string
+};
+
+/**
+ * backendUrl is the backend this build was made for (VITE_API_BASE_URL), needed when the frontend is hosted somewhere
+ * else (e.g. a CDN). Left empty the frontend is served by the backend itself, so it's the same origin.
+ */
+const backendUrl = (import.meta.env.VITE_API_BASE_URL ?? '').replace(/\/+$/, '');
+
+let apiBaseUrl: Promise<string> | undefined;
+
+/**
+ * GetAPIBaseURL asks the backend (once) where the api lives, falling back to the backend itself
+ */
+export function GetAPIBaseURL(): Promise<string> {
+    if (apiBaseUrl === undefined) {
+        apiBaseUrl = fetch(backendUrl + '/config.json')
+            .then(response => response.ok ? response.json() as Promise<FrontendConfig> : null)
+            .then(config => (config?.api_base_url || backendUrl).replace(/\/+$/, ''))
+            .catch(() => backendUrl);
+    }
+
+    return apiBaseUrl;
+}
+
 /**
  * GetCoinsData fetches the data from the backend and returns it
  */
 export default async function GetCoinsData(): Promise<ParsedCoinDataArrayOrNull> {
-    const response = await fetch('http://localhost:6241/prices');
+    const response = await fetch(await GetAPIBaseURL() + '/api/v1/prices');
     let data = await response.json() as CoinDataArrayOrNull;
 
     if (data === null) return null;
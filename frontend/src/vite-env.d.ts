@@ -0,0 +1,10 @@
+/// <reference types="vite/client" />
+
+interface ImportMetaEnv {
+    // where the backend is when the frontend isn't served by it, e.g. https://api.swap.example.com
+    readonly VITE_API_BASE_URL?: string
+}
+
+interface ImportMeta {
+    readonly env: ImportMetaEnv
+}
@@ -4,4 +4,11 @@ import preact from '@preact/preset-vite'
 // https://vitejs.dev/config/
 export default defineConfig({
   plugins: [preact()],
+  server: {
+    // in dev the api is the go server, in production it's the same origin or VITE_API_BASE_URL for a build hosted elsewhere
+    proxy: {
+      '/api': 'http://localhost:6241',
+      '/config.json': 'http://localhost:6241',
+    },
+  },
 })
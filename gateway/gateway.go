@@ -0,0 +1,371 @@
+// Package gateway is a client for the Hive Engine gateways (ETH, BSC and Polygon), which list the tokens withdrawn
+// through them and charge a flat fee on top of the percentage one
+package gateway
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/CADawg/hive-swap-calculator/upstream"
+	"github.com/shopspring/decimal"
+)
+
+type TokenNetworkData struct {
+	Name                string `json:"name"`
+	HiveEngineSymbol    string `json:"heSymbol"`
+	HiveEnginePrecision int    `json:"hePrecision"`
+	ContractAddress     string `json:"contractAddress"`
+	DepositEnabled      bool   `json:"depositEnabled"`
+	WithdrawalEnabled   bool   `json:"withdrawalEnabled"`
+
+	// Calculated by the program
+	Network  string          `json:"network,omitempty"`
+	FixedFee decimal.Decimal `json:"fixed_fee,omitempty"`
+	// FeeLookedUp is whether FixedFee is real, until then the token's fee isn't known (0 doesn't mean free)
+	FeeLookedUp bool `json:"fee_looked_up,omitempty"`
+}
+
+type TokenFeeData struct {
+	Status string          `json:"status"`
+	Data   decimal.Decimal `json:"data"`
+}
+
+type TokenNetworkDataResponse struct {
+	Status string             `json:"status"`
+	Data   []TokenNetworkData `json:"data"`
+}
+
+// DefaultTokens These don't appear in the API but can be requested from the fee endpoint and have the 1% fee
+var DefaultTokens = []TokenNetworkData{
+	{
+		Name:                "Ethereum",
+		HiveEngineSymbol:    "SWAP.ETH",
+		HiveEnginePrecision: 8,
+		ContractAddress:     "",
+		DepositEnabled:      true,
+		WithdrawalEnabled:   true,
+		Network:             "Ethereum",
+	},
+	{
+		Name:                "BNB",
+		HiveEngineSymbol:    "SWAP.BNB",
+		HiveEnginePrecision: 8,
+		ContractAddress:     "",
+		DepositEnabled:      true,
+		WithdrawalEnabled:   true,
+		Network:             "Binance Smart Chain",
+	},
+	{
+		Name:                "Polygon (MATIC)",
+		HiveEngineSymbol:    "SWAP.MATIC",
+		HiveEnginePrecision: 8,
+		ContractAddress:     "",
+		DepositEnabled:      true,
+		WithdrawalEnabled:   true,
+		Network:             "Polygon (Matic)",
+	},
+}
+
+// Network is a gateway with a flat withdrawal fee
+type Network struct {
+	Name string
+	// TokensURL lists the tokens withdrawn through it
+	TokensURL string
+	// FeeURL with a SWAP. symbol on the end is that token's withdrawal fee, in Currency
+	FeeURL   string
+	Currency string
+}
+
+var DefaultNetworks = []Network{
+	{
+		Name:      "Binance Smart Chain",
+		TokensURL: "https://bscgw.hive-engine.com/api/utils/tokens/bep20",
+		FeeURL:    "https://bscgw.hive-engine.com/api/utils/withdrawalfee/",
+		Currency:  "BNB",
+	},
+	{
+		Name:      "Ethereum",
+		TokensURL: "https://ethgw.hive-engine.com/api/utils/tokens/erc20",
+		FeeURL:    "https://ethgw.hive-engine.com/api/utils/withdrawalfee/",
+		Currency:  "ETH",
+	},
+	{
+		Name:      "Polygon (Matic)",
+		TokensURL: "https://polygw.hive-engine.com/api/utils/tokens/erc20",
+		FeeURL:    "https://polygw.hive-engine.com/api/utils/withdrawalfee/",
+		Currency:  "MATIC",
+	},
+}
+
+// Client talks to the gateways of networks
+type Client struct {
+	http     *http.Client
+	networks []Network
+}
+
+// NewClient makes a client for networks (e.g. DefaultNetworks) that sends its requests through httpClient
+func NewClient(httpClient *http.Client, networks []Network) *Client {
+	return &Client{http: httpClient, networks: networks}
+}
+
+func (c *Client) Networks() []Network {
+	return c.networks
+}
+
+func (c *Client) network(name string) (Network, bool) {
+	for _, network := range c.networks {
+		if network.Name == name {
+			return network, true
+		}
+	}
+
+	return Network{}, false
+}
+
+// LoadTokenNetworkData fetches the token lists from every gateway, plus the defaults that aren't in them
+func (c *Client) LoadTokenNetworkData() ([]TokenNetworkData, error) {
+	// add defaults (these don't come from the api, but still need to get their fixed fee price)
+	store := append([]TokenNetworkData{}, DefaultTokens...)
+
+	for _, network := range c.networks {
+		tokens, err := upstream.GetJSON[TokenNetworkDataResponse](c.http, network.TokensURL)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if tokens.Status != "success" {
+			return nil, errors.New("failed to get token network data for " + network.Name)
+		}
+
+		for i := range tokens.Data {
+			tokens.Data[i].Network = network.Name
+		}
+
+		store = append(store, tokens.Data...)
+	}
+
+	return store, nil
+}
+
+// WithdrawalFee is the flat fee to withdraw a token (by its SWAP. symbol) through a network, in the network's currency
+func (c *Client) WithdrawalFee(networkName string, hiveEngineSymbol string) (decimal.Decimal, error) {
+	network, ok := c.network(networkName)
+
+	if !ok {
+		return decimal.Decimal{}, errors.New("unknown network " + networkName)
+	}
+
+	feeData, err := upstream.GetJSON[TokenFeeData](c.http, network.FeeURL+hiveEngineSymbol)
+
+	if err != nil {
+		return decimal.Decimal{}, err
+	}
+
+	if feeData.Status != "success" {
+		return decimal.Decimal{}, errors.New("failed to get the withdrawal fee of " + hiveEngineSymbol)
+	}
+
+	return feeData.Data, nil
+}
+
+// TokenFee is what it costs to deposit or withdraw a token
+type TokenFee struct {
+	PercentageFee decimal.Decimal `json:"percentage_fee"`
+	FlatFee       decimal.Decimal `json:"flat_fee"`
+	Network       string          `json:"network,omitempty"`
+}
+
+// Store is the gateway tokens with their flat withdrawal fees in HIVE. The list is replaced (never changed in place)
+// under the lock, so a copy of it can be read after unlocking.
+type Store struct {
+	client *Client
+
+	lock   sync.RWMutex
+	tokens []TokenNetworkData
+	// ready is set once any withdrawal fee has been looked up (or restored), each token says whether its own has
+	ready bool
+}
+
+func NewStore(client *Client) *Store {
+	return &Store{client: client}
+}
+
+// Tokens is the gateway tokens with their fees, and whether the fees have been looked up yet
+func (s *Store) Tokens() ([]TokenNetworkData, bool) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return s.tokens, s.ready
+}
+
+// Restore puts back tokens saved from Tokens (e.g. after a restart), the fees that had been looked up still count
+func (s *Store) Restore(tokens []TokenNetworkData) {
+	ready := false
+
+	for _, token := range tokens {
+		ready = ready || token.FeeLookedUp
+	}
+
+	s.lock.Lock()
+	s.tokens = tokens
+	s.ready = ready
+	s.lock.Unlock()
+}
+
+// UpdateWithdrawalFees looks up the flat withdrawal fee of every gateway token we have a price for, priced in HIVE
+// using hivePrices (HIVE per token, by symbol without SWAP.) since the fees are in ETH, BNB or MATIC. The gateway lists
+// are loaded first if they haven't been. It only fails if every lookup did, a token we couldn't get keeps the fee it
+// had (or stays without one, it's never given a made up 0).
+func (s *Store) UpdateWithdrawalFees(hivePrices map[string]decimal.Decimal) error {
+	s.lock.RLock()
+	store := s.tokens
+	s.lock.RUnlock()
+
+	if store == nil {
+		// load token network data (data that links token -> withdrawal network, so we know which endpoint to ask for the fee)
+		_, err := s.Reload()
+
+		if err != nil {
+			return err
+		}
+
+		s.lock.RLock()
+		store = s.tokens
+		s.lock.RUnlock()
+	}
+
+	// load cost of each token in the store
+	// and then add the cheapest fixed fee to the token
+	newFees := map[string]decimal.Decimal{}
+
+	var lastErr error
+
+	for _, token := range store {
+		// no point making extra network requests if we don't have the token
+		if _, ok := hivePrices[strings.TrimPrefix(strings.ToUpper(token.HiveEngineSymbol), "SWAP.")]; !ok {
+			continue
+		}
+
+		fee, err := s.client.WithdrawalFee(token.Network, token.HiveEngineSymbol)
+
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		network, _ := s.client.network(token.Network)
+
+		currencyPrice, ok := hivePrices[network.Currency]
+
+		// without a price for the currency we can't tell what the fee is in HIVE, that's as good as a failed lookup
+		if !ok {
+			lastErr = errors.New("no price for " + network.Currency + " to work out the withdrawal fee of " + token.HiveEngineSymbol)
+			continue
+		}
+
+		newFees[networkDataKey(token)] = currencyPrice.Mul(fee)
+	}
+
+	if len(newFees) == 0 && lastErr != nil {
+		return lastErr
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	// the lists might have been reloaded while we were fetching, so match the fees up by network and symbol
+	updated := append([]TokenNetworkData{}, s.tokens...)
+
+	for i, token := range updated {
+		if fee, ok := newFees[networkDataKey(token)]; ok {
+			updated[i].FixedFee = fee
+			updated[i].FeeLookedUp = true
+		}
+	}
+
+	s.tokens = updated
+	s.ready = true
+
+	return nil
+}
+
+func networkDataKey(token TokenNetworkData) string {
+	return token.Network + "|" + strings.ToUpper(token.HiveEngineSymbol)
+}
+
+// Fees works out the fee of every symbol (without SWAP.), the withdrawal fees have to have been looked up with
+// UpdateWithdrawalFees (or restored) first. Every token pays percentageFee unless it's withdrawn through a gateway,
+// then it's gatewayPercentageFee plus the cheapest flat fee we've looked up. A gateway token none of whose fees have
+// been looked up yet is left out, its fee isn't known.
+func (s *Store) Fees(symbols []string, percentageFee decimal.Decimal, gatewayPercentageFee decimal.Decimal) (map[string]TokenFee, error) {
+	store, ready := s.Tokens()
+
+	// Prevent data with wrong fee info from reaching the site
+	if !ready {
+		return nil, errors.New("not ready yet")
+	}
+
+	fees := map[string]TokenFee{}
+
+	for _, symbol := range symbols {
+		fee := TokenFee{PercentageFee: percentageFee}
+
+		viaGateway, found := false, false
+
+		for _, networkData := range store {
+			if strings.ToUpper(networkData.HiveEngineSymbol) != "SWAP."+symbol {
+				continue
+			}
+
+			viaGateway = true
+
+			// update price if the fee is known and less than the current fee, or there isn't a current fee yet
+			if networkData.FeeLookedUp && (!found || networkData.FixedFee.LessThanOrEqual(fee.FlatFee)) {
+				fee.PercentageFee = gatewayPercentageFee
+				fee.FlatFee = networkData.FixedFee
+				fee.Network = networkData.Network
+				found = true
+			}
+		}
+
+		// Prevent data with wrong fee info from reaching the site
+		if viaGateway && !found {
+			continue
+		}
+
+		fees[symbol] = fee
+	}
+
+	return fees, nil
+}
+
+// Reload replaces the token lists with freshly fetched ones, keeping the fixed fees we already know so there's no gap
+// until the fees are next updated. The old lists are kept if fetching fails.
+func (s *Store) Reload() (int, error) {
+	store, err := s.client.LoadTokenNetworkData()
+
+	if err != nil {
+		return 0, err
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	knownFees := map[string]TokenNetworkData{}
+
+	for _, token := range s.tokens {
+		knownFees[networkDataKey(token)] = token
+	}
+
+	for i, token := range store {
+		store[i].FixedFee = knownFees[networkDataKey(token)].FixedFee
+		store[i].FeeLookedUp = knownFees[networkDataKey(token)].FeeLookedUp
+	}
+
+	s.tokens = store
+
+	return len(store), nil
+}
@@ -0,0 +1,232 @@
+package gateway
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+// fakeGateway serves an Ethereum gateway listing SWAP.BTC and SWAP.DOGE, fees are looked up in fees (a missing one is
+// a failed lookup)
+type fakeGateway struct {
+	lock sync.Mutex
+	fees map[string]string
+}
+
+func (g *fakeGateway) setFee(symbol string, fee string) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	if fee == "" {
+		delete(g.fees, symbol)
+		return
+	}
+
+	g.fees[symbol] = fee
+}
+
+func (g *fakeGateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/tokens" {
+		_, _ = w.Write([]byte(`{"status":"success","data":[{"name":"Bitcoin","heSymbol":"SWAP.BTC"},{"name":"Doge","heSymbol":"SWAP.DOGE"}]}`))
+		return
+	}
+
+	g.lock.Lock()
+	fee, ok := g.fees[strings.TrimPrefix(r.URL.Path, "/fee/")]
+	g.lock.Unlock()
+
+	if !ok {
+		_, _ = w.Write([]byte(`{"status":"error"}`))
+		return
+	}
+
+	_, _ = w.Write([]byte(`{"status":"success","data":"` + fee + `"}`))
+}
+
+func newTestStore(t *testing.T) (*Store, *fakeGateway) {
+	t.Helper()
+
+	gateway := &fakeGateway{fees: map[string]string{}}
+	server := httptest.NewServer(gateway)
+	t.Cleanup(server.Close)
+
+	client := NewClient(server.Client(), []Network{{Name: "Ethereum", TokensURL: server.URL + "/tokens", FeeURL: server.URL + "/fee/", Currency: "ETH"}})
+
+	return NewStore(client), gateway
+}
+
+var testPrices = map[string]decimal.Decimal{
+	"ETH":  decimal.NewFromInt(1000),
+	"BTC":  decimal.NewFromInt(20000),
+	"DOGE": decimal.RequireFromString("0.5"),
+}
+
+func TestLoadTokenNetworkData(t *testing.T) {
+	store, _ := newTestStore(t)
+
+	tokens, err := store.client.LoadTokenNetworkData()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the defaults come first, then every network's list with the network filled in
+	if len(tokens) != len(DefaultTokens)+2 || tokens[len(DefaultTokens)].HiveEngineSymbol != "SWAP.BTC" || tokens[len(DefaultTokens)].Network != "Ethereum" {
+		t.Fatalf("unexpected tokens %+v", tokens)
+	}
+}
+
+func TestUpdateWithdrawalFees(t *testing.T) {
+	store, gateway := newTestStore(t)
+
+	if _, ready := store.Tokens(); ready {
+		t.Fatal("ready before anything was looked up")
+	}
+
+	if _, err := store.Fees([]string{"BTC"}, decimal.NewFromInt(1), decimal.NewFromInt(2)); err == nil {
+		t.Fatal("got fees before anything was looked up")
+	}
+
+	// every lookup failing is an error
+	if err := store.UpdateWithdrawalFees(testPrices); err == nil {
+		t.Fatal("expected an error when every lookup fails")
+	}
+
+	gateway.setFee("SWAP.BTC", "0.001")
+	gateway.setFee("SWAP.ETH", "0")
+
+	if err := store.UpdateWithdrawalFees(testPrices); err != nil {
+		t.Fatal(err)
+	}
+
+	fees, err := store.Fees([]string{"BTC", "ETH", "DOGE", "HIVE"}, decimal.NewFromInt(1), decimal.NewFromInt(2))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the fee is in ETH, priced in HIVE
+	if fee := fees["BTC"]; !fee.FlatFee.Equal(decimal.NewFromInt(1)) || !fee.PercentageFee.Equal(decimal.NewFromInt(2)) || fee.Network != "Ethereum" {
+		t.Fatalf("BTC fee = %+v", fee)
+	}
+
+	// a real zero fee is a fee
+	if fee, ok := fees["ETH"]; !ok || !fee.FlatFee.IsZero() || fee.Network != "Ethereum" {
+		t.Fatalf("ETH fee = %+v (%v)", fee, ok)
+	}
+
+	// DOGE's lookup failed, so its fee isn't known and it mustn't get a flat fee of 0
+	if fee, ok := fees["DOGE"]; ok {
+		t.Fatalf("DOGE got a fee %+v without one being looked up", fee)
+	}
+
+	// not a gateway token, so it's just the percentage fee
+	if fee := fees["HIVE"]; !fee.PercentageFee.Equal(decimal.NewFromInt(1)) || !fee.FlatFee.IsZero() || fee.Network != "" {
+		t.Fatalf("HIVE fee = %+v", fee)
+	}
+
+	// a lookup that fails later keeps the fee we had
+	gateway.setFee("SWAP.BTC", "")
+	gateway.setFee("SWAP.DOGE", "0.002")
+
+	if err = store.UpdateWithdrawalFees(testPrices); err != nil {
+		t.Fatal(err)
+	}
+
+	fees, err = store.Fees([]string{"BTC", "DOGE"}, decimal.NewFromInt(1), decimal.NewFromInt(2))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !fees["BTC"].FlatFee.Equal(decimal.NewFromInt(1)) || !fees["DOGE"].FlatFee.Equal(decimal.NewFromInt(2)) {
+		t.Fatalf("fees = %+v", fees)
+	}
+}
+
+func TestUpdateWithdrawalFeesWithoutACurrencyPrice(t *testing.T) {
+	store, gateway := newTestStore(t)
+
+	gateway.setFee("SWAP.BTC", "0.001")
+
+	// no ETH price, so the fee can't be priced in HIVE
+	if err := store.UpdateWithdrawalFees(map[string]decimal.Decimal{"BTC": decimal.NewFromInt(20000)}); err == nil {
+		t.Fatal("expected an error")
+	}
+
+	tokens, _ := store.Tokens()
+
+	for _, token := range tokens {
+		if token.FeeLookedUp {
+			t.Fatalf("%s counts as looked up", token.HiveEngineSymbol)
+		}
+	}
+}
+
+func TestFeesPicksTheCheapestNetwork(t *testing.T) {
+	store := NewStore(nil)
+
+	store.Restore([]TokenNetworkData{
+		{HiveEngineSymbol: "SWAP.USDT", Network: "Ethereum", FixedFee: decimal.NewFromInt(5), FeeLookedUp: true},
+		{HiveEngineSymbol: "SWAP.USDT", Network: "Binance Smart Chain", FixedFee: decimal.NewFromInt(1), FeeLookedUp: true},
+		// never looked up, its 0 isn't a fee
+		{HiveEngineSymbol: "SWAP.USDT", Network: "Polygon (Matic)"},
+	})
+
+	fees, err := store.Fees([]string{"USDT"}, decimal.NewFromInt(1), decimal.NewFromInt(2))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if fee := fees["USDT"]; fee.Network != "Binance Smart Chain" || !fee.FlatFee.Equal(decimal.NewFromInt(1)) {
+		t.Fatalf("USDT fee = %+v", fee)
+	}
+}
+
+func TestRestoreWithoutLookedUpFees(t *testing.T) {
+	store := NewStore(nil)
+
+	// e.g. saved before any lookup had worked
+	store.Restore([]TokenNetworkData{{HiveEngineSymbol: "SWAP.BTC", Network: "Ethereum"}})
+
+	if _, ready := store.Tokens(); ready {
+		t.Fatal("restored tokens without fees count as ready")
+	}
+}
+
+func TestReloadKeepsKnownFees(t *testing.T) {
+	store, gateway := newTestStore(t)
+
+	gateway.setFee("SWAP.BTC", "0.001")
+
+	if err := store.UpdateWithdrawalFees(testPrices); err != nil {
+		t.Fatal(err)
+	}
+
+	count, err := store.Reload()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tokens, _ := store.Tokens()
+
+	if count != len(tokens) {
+		t.Fatalf("Reload() = %d, have %d tokens", count, len(tokens))
+	}
+
+	for _, token := range tokens {
+		if token.HiveEngineSymbol == "SWAP.BTC" && (!token.FeeLookedUp || !token.FixedFee.Equal(decimal.NewFromInt(1))) {
+			t.Fatalf("BTC lost its fee: %+v", token)
+		}
+
+		if token.HiveEngineSymbol == "SWAP.DOGE" && token.FeeLookedUp {
+			t.Fatalf("DOGE gained a fee: %+v", token)
+		}
+	}
+}
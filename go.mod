@@ -3,6 +3,19 @@ module github.com/CADawg/hive-swap-calculator
 go 1.20
 
 require (
+	github.com/andybalholm/brotli v1.1.0
 	github.com/goccy/go-json v0.10.2
 	github.com/shopspring/decimal v1.3.1
+	github.com/vmihailenco/msgpack/v5 v5.4.1
+	golang.org/x/sys v0.10.0
+	google.golang.org/grpc v1.58.3
+	google.golang.org/protobuf v1.31.0
+)
+
+require (
+	github.com/golang/protobuf v1.5.3 // indirect
+	github.com/vmihailenco/tagparser/v2 v2.0.0 // indirect
+	golang.org/x/net v0.12.0 // indirect
+	golang.org/x/text v0.11.0 // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20230711160842-782d3b101e98 // indirect
 )
@@ -0,0 +1,545 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/CADawg/hive-swap-calculator/engine"
+	"github.com/CADawg/hive-swap-calculator/market"
+	"github.com/CADawg/hive-swap-calculator/pricing"
+	hiveswapv1 "github.com/CADawg/hive-swap-calculator/proto/hiveswap/v1"
+	"github.com/shopspring/decimal"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// grpcAPIKeyMetadata is the metadata key the api key goes in (grpc metadata keys are always lowercase)
+var grpcAPIKeyMetadata = strings.ToLower(APIKeyHeader)
+
+// the rate limit class and scope of each rpc, anything not listed is RateLimitDefault with no scope needed
+var grpcRateLimitClasses = map[string]string{
+	hiveswapv1.HiveSwapService_GetQuote_FullMethodName: RateLimitHeavy,
+}
+
+var grpcScopes = map[string]string{
+	hiveswapv1.HiveSwapService_GetToken_FullMethodName: ScopeReadPrices,
+	hiveswapv1.HiveSwapService_GetOrder_FullMethodName: ScopeReadPrices,
+	hiveswapv1.HiveSwapService_GetQuote_FullMethodName: ScopeComputeRoutes,
+}
+
+// GRPCServer serves the same snapshots as the http api, plus quotes and a stream of every new snapshot
+type GRPCServer struct {
+	hiveswapv1.UnimplementedHiveSwapServiceServer
+
+	limiter *RateLimiter
+}
+
+// ServeGRPC listens on config.GRPCAddr (with the same TLS cert as the http server, if there is one)
+func ServeGRPC(config ServerConfig, limiter *RateLimiter) error {
+	options := []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(limiter.unaryInterceptor),
+		grpc.ChainStreamInterceptor(limiter.streamInterceptor),
+	}
+
+	if config.TLSCertFile != "" && config.TLSKeyFile != "" {
+		reloader, err := NewCertReloader(config.TLSCertFile, config.TLSKeyFile)
+
+		if err != nil {
+			return err
+		}
+
+		tlsConfig := NewTLSConfig(reloader)
+		tlsConfig.NextProtos = []string{"h2"}
+
+		options = append(options, grpc.Creds(credentials.NewTLS(tlsConfig)))
+	}
+
+	server := grpc.NewServer(options...)
+	hiveswapv1.RegisterHiveSwapServiceServer(server, &GRPCServer{limiter: limiter})
+
+	listener, err := net.Listen("tcp", config.GRPCAddr)
+
+	if err != nil {
+		return err
+	}
+
+	return server.Serve(listener)
+}
+
+// authorizeRPC does for grpc what Authenticate, RateLimiter.Limit and RequireScope do for http
+func (l *RateLimiter) authorizeRPC(ctx context.Context, method string) (context.Context, error) {
+	var key *APIKey
+
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	if values := md.Get(grpcAPIKeyMetadata); len(values) > 0 && values[0] != "" {
+		var ok bool
+
+		key, ok = APIKeys.Use(values[0])
+
+		if !ok {
+			return ctx, status.Error(codes.Unauthenticated, "invalid or revoked api key")
+		}
+
+		ctx = context.WithValue(ctx, apiKeyContextKey{}, key)
+	}
+
+	class, ok := grpcRateLimitClasses[method]
+
+	if !ok {
+		class = RateLimitDefault
+	}
+
+	config := GetConfig().RateLimit
+	limit, hasLimit := config.Limits[class]
+
+	// no forwarded headers here, grpc clients connect to us directly
+	clientKey := "ip:unknown"
+
+	if p, ok := peer.FromContext(ctx); ok {
+		clientKey = "ip:" + p.Addr.String()
+
+		if host, _, err := net.SplitHostPort(p.Addr.String()); err == nil {
+			clientKey = "ip:" + host
+		}
+	}
+
+	if key != nil {
+		clientKey = "key:" + key.ID
+
+		if keyLimit, hasKeyLimit := key.RateLimits[class]; hasKeyLimit {
+			limit, hasLimit = keyLimit, true
+		}
+	}
+
+	if hasLimit {
+		allowed, retryAfter := l.Allow(class+"|"+clientKey, limit, time.Now())
+
+		if !allowed {
+			return ctx, status.Error(codes.ResourceExhausted, "rate limit exceeded, try again in "+strconv.Itoa(int(math.Ceil(retryAfter.Seconds())))+"s")
+		}
+	}
+
+	if scope, ok := grpcScopes[method]; ok {
+		if key == nil {
+			return ctx, status.Error(codes.Unauthenticated, "an api key is required, send it in the "+grpcAPIKeyMetadata+" metadata")
+		}
+
+		if !key.HasScope(scope) && !key.HasScope(ScopeAdmin) {
+			return ctx, status.Error(codes.PermissionDenied, "this api key doesn't have the "+scope+" scope")
+		}
+	}
+
+	return ctx, nil
+}
+
+func (l *RateLimiter) unaryInterceptor(ctx context.Context, request interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	ctx, err := l.authorizeRPC(ctx, info.FullMethod)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return handler(ctx, request)
+}
+
+func (l *RateLimiter) streamInterceptor(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := l.authorizeRPC(stream.Context(), info.FullMethod)
+
+	if err != nil {
+		return err
+	}
+
+	return handler(srv, &authorizedStream{ServerStream: stream, ctx: ctx})
+}
+
+// authorizedStream is a stream with the api key in its context, so the rpc can check scopes that depend on the request
+type authorizedStream struct {
+	grpc.ServerStream
+
+	ctx context.Context
+}
+
+func (s *authorizedStream) Context() context.Context {
+	return s.ctx
+}
+
+// grpcQuery is the config's order filters, limited to symbols (BTC or SWAP.BTC)
+func grpcQuery(symbols []string) PricesQuery {
+	query := PricesQuery{Side: SideBoth, Filter: GetConfig().Filters}
+
+	for _, symbol := range symbols {
+		query.Symbols = append(query.Symbols, strings.TrimPrefix(strings.ToUpper(symbol), "SWAP."))
+	}
+
+	return query
+}
+
+func (s *GRPCServer) GetSnapshot(ctx context.Context, request *hiveswapv1.GetSnapshotRequest) (*hiveswapv1.Snapshot, error) {
+	query := grpcQuery(request.Symbols)
+
+	snapshot := CurrentSnapshot()
+
+	return newProtoSnapshot(snapshot, query.Apply(snapshot.Tokens, snapshot.Now()), request.WithoutOrders), nil
+}
+
+func (s *GRPCServer) GetToken(ctx context.Context, request *hiveswapv1.GetTokenRequest) (*hiveswapv1.Token, error) {
+	snapshot := CurrentSnapshot()
+
+	token, ok := snapshot.Index.Token(request.Symbol)
+
+	if !ok {
+		return nil, status.Error(codes.NotFound, "no token with symbol "+request.Symbol)
+	}
+
+	return newProtoToken(grpcQuery(nil).Apply([]pricing.TokenData{token}, snapshot.Now())[0], false), nil
+}
+
+func (s *GRPCServer) GetOrder(ctx context.Context, request *hiveswapv1.GetOrderRequest) (*hiveswapv1.Order, error) {
+	snapshot := CurrentSnapshot()
+
+	order, ok := snapshot.Index.FilteredOrder(request.TxId, GetConfig().Filters, snapshot.Now())
+
+	if !ok {
+		return nil, status.Error(codes.NotFound, "no order with txId "+request.TxId)
+	}
+
+	return newProtoOrder(order.Order, order.Side), nil
+}
+
+func (s *GRPCServer) GetQuote(ctx context.Context, request *hiveswapv1.GetQuoteRequest) (*hiveswapv1.Quote, error) {
+	quoteRequest, err := newQuoteRequest(request)
+
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	snapshot := CurrentSnapshot()
+
+	quote := market.QuoteRoutes(grpcQuery(nil).Apply(snapshot.Tokens, snapshot.Now()), quoteRequest)
+
+	return newProtoQuote(quote, snapshot.Version, request.Direction), nil
+}
+
+// WatchSnapshots sends whatever we have now, then every new version until the client goes away
+func (s *GRPCServer) WatchSnapshots(request *hiveswapv1.WatchSnapshotsRequest, stream hiveswapv1.HiveSwapService_WatchSnapshotsServer) error {
+	query := grpcQuery(request.Symbols)
+
+	// the filters hide orders (e.g. from denied accounts) on purpose, so only keys with read_prices can see past them
+	if request.Unfiltered {
+		key, ok := APIKeyFromContext(stream.Context())
+
+		if !ok {
+			return status.Error(codes.Unauthenticated, "an api key is required for unfiltered snapshots, send it in the "+grpcAPIKeyMetadata+" metadata")
+		}
+
+		if !key.HasScope(ScopeReadPrices) && !key.HasScope(ScopeAdmin) {
+			return status.Error(codes.PermissionDenied, "this api key doesn't have the "+ScopeReadPrices+" scope")
+		}
+
+		query.Filter = market.OrderFilter{}
+	}
+
+	var since uint64
+
+	for {
+		_, snapshot := SnapshotsSince(stream.Context(), since, maxDeltaWait)
+
+		if err := stream.Context().Err(); err != nil {
+			return nil
+		}
+
+		if snapshot.Version == since {
+			continue
+		}
+
+		err := stream.Send(newProtoSnapshot(snapshot, query.Apply(snapshot.Tokens, snapshot.Now()), request.WithoutOrders))
+
+		if err != nil {
+			return err
+		}
+
+		since = snapshot.Version
+	}
+}
+
+func newQuoteRequest(request *hiveswapv1.GetQuoteRequest) (market.QuoteRequest, error) {
+	quoteRequest := market.QuoteRequest{
+		FromSwapHive:      request.FromSwapHive,
+		SwapFeePercentage: market.DefaultSwapFeePercentage,
+	}
+
+	switch request.Direction {
+	case hiveswapv1.Direction_DIRECTION_HIVE_TO_TOKEN:
+		quoteRequest.Direction = market.DirectionHiveToToken
+	case hiveswapv1.Direction_DIRECTION_TOKEN_TO_HIVE:
+		quoteRequest.Direction = market.DirectionTokenToHive
+	}
+
+	var err error
+
+	quoteRequest.HiveAmount, err = decimal.NewFromString(request.HiveAmount)
+
+	if err != nil {
+		return quoteRequest, errors.New("hive_amount must be a number")
+	}
+
+	if request.SwapFeePercentage != "" {
+		quoteRequest.SwapFeePercentage, err = decimal.NewFromString(request.SwapFeePercentage)
+
+		if err != nil {
+			return quoteRequest, errors.New("swap_fee_percentage must be a number")
+		}
+	}
+
+	quoteRequest.Symbols = grpcQuery(request.Symbols).Symbols
+
+	return quoteRequest, quoteRequest.Validate()
+}
+
+// newProtoSnapshot converts from, with tokens in place of its own (so they can be filtered first)
+func newProtoSnapshot(from *Snapshot, tokens []pricing.TokenData, withoutOrders bool) *hiveswapv1.Snapshot {
+	snapshot := &hiveswapv1.Snapshot{Version: from.Version, Stale: from.Stale}
+
+	if !from.UpdatedAt.IsZero() {
+		snapshot.UpdatedAt = from.UpdatedAt.Unix()
+	}
+
+	for _, stage := range from.Stages {
+		protoStage := &hiveswapv1.StageStatus{Stage: stage.Stage, Degraded: stage.Degraded(), Error: stage.Error}
+
+		if !stage.LastSuccess.IsZero() {
+			protoStage.LastSuccessAt = stage.LastSuccess.Unix()
+		}
+
+		snapshot.Stages = append(snapshot.Stages, protoStage)
+	}
+
+	for _, token := range tokens {
+		snapshot.Tokens = append(snapshot.Tokens, newProtoToken(token, withoutOrders))
+	}
+
+	return snapshot
+}
+
+func newProtoToken(token pricing.TokenData, withoutOrders bool) *hiveswapv1.Token {
+	protoToken := &hiveswapv1.Token{
+		Symbol:        token.Symbol,
+		SwapSymbol:    token.SwapSymbol,
+		Name:          token.CoinGeckoName,
+		UsdPrice:      token.USDPrice.String(),
+		Usd_24HChange: token.USD24HChange.String(),
+		BtcPrice:      token.BTCPrice.String(),
+		Btc_24HChange: token.BTC24HChange.String(),
+		LastUpdatedAt: token.LastUpdated,
+		HivePrice:     token.HIVEPrice.String(),
+		Fee: &hiveswapv1.Fee{
+			Network:    token.Network,
+			Percentage: token.NetworkPercentageFee.String(),
+			FlatHive:   token.NetworkFlatFee.String(),
+		},
+	}
+
+	if withoutOrders {
+		return protoToken
+	}
+
+	for _, order := range token.SellOrders {
+		protoToken.SellOrders = append(protoToken.SellOrders, newProtoOrder(order, market.SideSell))
+	}
+
+	for _, order := range token.BuyOrders {
+		protoToken.BuyOrders = append(protoToken.BuyOrders, newProtoOrder(order, market.SideBuy))
+	}
+
+	return protoToken
+}
+
+func newProtoOrder(order engine.MarketOrder, side string) *hiveswapv1.Order {
+	return &hiveswapv1.Order{
+		Account:          order.Account,
+		Expiration:       order.Expiration,
+		Price:            order.Price.String(),
+		Quantity:         order.Quantity.String(),
+		Symbol:           order.Symbol,
+		Timestamp:        order.Timestamp,
+		TxId:             order.TransactionID,
+		Id:               int64(order.ID),
+		ProfitPercentage: order.ProfitPercentage.String(),
+		Side:             newProtoSide(side),
+	}
+}
+
+func newProtoSide(side string) hiveswapv1.Side {
+	switch side {
+	case market.SideSell:
+		return hiveswapv1.Side_SIDE_SELL
+	case market.SideBuy:
+		return hiveswapv1.Side_SIDE_BUY
+	}
+
+	return hiveswapv1.Side_SIDE_UNSPECIFIED
+}
+
+func newProtoQuote(quote market.Quote, version uint64, direction hiveswapv1.Direction) *hiveswapv1.Quote {
+	protoQuote := &hiveswapv1.Quote{
+		SnapshotVersion:    version,
+		Direction:          direction,
+		HiveAmount:         quote.HiveAmount.String(),
+		UnroutedHive:       quote.UnroutedHive.String(),
+		ExpectedProfitHive: quote.ExpectedProfitHive.String(),
+		ProfitPercentage:   quote.ProfitPercentage.String(),
+	}
+
+	for _, leg := range quote.Legs {
+		protoQuote.Legs = append(protoQuote.Legs, &hiveswapv1.RouteLeg{
+			Symbol:           leg.Symbol,
+			Side:             newProtoSide(leg.Side),
+			TxIds:            leg.TransactionIDs,
+			HiveAmount:       leg.HiveAmount.String(),
+			TokenAmount:      leg.TokenAmount.String(),
+			FlatFeeHive:      leg.FlatFeeHive.String(),
+			ProfitHive:       leg.ProfitHive.String(),
+			ProfitPercentage: leg.ProfitPercentage.String(),
+		})
+	}
+
+	return protoQuote
+}
+
+// tokensFromProto turns a snapshot from another instance back into our own tokens
+func tokensFromProto(snapshot *hiveswapv1.Snapshot) ([]pricing.TokenData, error) {
+	var tokens []pricing.TokenData
+
+	for _, protoToken := range snapshot.Tokens {
+		token := pricing.TokenData{
+			LastUpdated:   protoToken.LastUpdatedAt,
+			CoinGeckoName: protoToken.Name,
+			Symbol:        protoToken.Symbol,
+			SwapSymbol:    protoToken.SwapSymbol,
+		}
+
+		var err error
+
+		decimals := []decimalField{
+			{protoToken.UsdPrice, &token.USDPrice},
+			{protoToken.Usd_24HChange, &token.USD24HChange},
+			{protoToken.BtcPrice, &token.BTCPrice},
+			{protoToken.Btc_24HChange, &token.BTC24HChange},
+			{protoToken.HivePrice, &token.HIVEPrice},
+		}
+
+		if protoToken.Fee != nil {
+			token.Network = protoToken.Fee.Network
+			decimals = append(decimals, decimalField{protoToken.Fee.Percentage, &token.NetworkPercentageFee}, decimalField{protoToken.Fee.FlatHive, &token.NetworkFlatFee})
+		}
+
+		err = parseDecimals(decimals)
+
+		if err != nil {
+			return nil, errors.New("token " + protoToken.Symbol + ": " + err.Error())
+		}
+
+		token.SellOrders, err = ordersFromProto(protoToken.SellOrders)
+
+		if err != nil {
+			return nil, err
+		}
+
+		token.BuyOrders, err = ordersFromProto(protoToken.BuyOrders)
+
+		if err != nil {
+			return nil, err
+		}
+
+		tokens = append(tokens, token)
+	}
+
+	return tokens, nil
+}
+
+// stagesFromProto is the primary's stage statuses, so a replica reports the same degraded stages
+func stagesFromProto(snapshot *hiveswapv1.Snapshot) []StageStatus {
+	var stages []StageStatus
+
+	for _, protoStage := range snapshot.Stages {
+		stage := StageStatus{Stage: protoStage.Stage, Error: protoStage.Error}
+
+		if protoStage.LastSuccessAt > 0 {
+			stage.LastSuccess = time.Unix(protoStage.LastSuccessAt, 0)
+		}
+
+		// a primary should always send why, but don't lose the flag if it doesn't
+		if protoStage.Degraded && stage.Error == "" {
+			stage.Error = "degraded on the primary"
+		}
+
+		stages = append(stages, stage)
+	}
+
+	return stages
+}
+
+func ordersFromProto(protoOrders []*hiveswapv1.Order) ([]engine.MarketOrder, error) {
+	var orders []engine.MarketOrder
+
+	for _, protoOrder := range protoOrders {
+		order := engine.MarketOrder{
+			Account:       protoOrder.Account,
+			Expiration:    protoOrder.Expiration,
+			Symbol:        protoOrder.Symbol,
+			Timestamp:     protoOrder.Timestamp,
+			TransactionID: protoOrder.TxId,
+			ID:            int(protoOrder.Id),
+		}
+
+		err := parseDecimals([]decimalField{
+			{protoOrder.Price, &order.Price},
+			{protoOrder.Quantity, &order.Quantity},
+			{protoOrder.ProfitPercentage, &order.ProfitPercentage},
+		})
+
+		if err != nil {
+			return nil, errors.New("order " + protoOrder.TxId + ": " + err.Error())
+		}
+
+		orders = append(orders, order)
+	}
+
+	return orders, nil
+}
+
+// decimalField is a decimal string from a message and where it should be parsed into
+type decimalField struct {
+	value string
+	into  *decimal.Decimal
+}
+
+func parseDecimals(fields []decimalField) error {
+	for _, field := range fields {
+		// left empty means zero
+		if field.value == "" {
+			*field.into = decimal.Zero
+			continue
+		}
+
+		value, err := decimal.NewFromString(field.value)
+
+		if err != nil {
+			return errors.New(field.value + " isn't a decimal")
+		}
+
+		*field.into = value
+	}
+
+	return nil
+}
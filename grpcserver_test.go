@@ -0,0 +1,200 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/CADawg/hive-swap-calculator/engine"
+	"github.com/CADawg/hive-swap-calculator/pricing"
+	hiveswapv1 "github.com/CADawg/hive-swap-calculator/proto/hiveswap/v1"
+	"github.com/shopspring/decimal"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func grpcTestTokens() []pricing.TokenData {
+	return []pricing.TokenData{{
+		Symbol:               "BTC",
+		SwapSymbol:           "SWAP.BTC",
+		CoinGeckoName:        "bitcoin",
+		USDPrice:             decimal.RequireFromString("30000.5"),
+		HIVEPrice:            decimal.NewFromInt(1),
+		NetworkPercentageFee: decimal.RequireFromString("0.75"),
+		NetworkFlatFee:       decimal.RequireFromString("0.1"),
+		Network:              "Ethereum",
+		BuyOrders: []engine.MarketOrder{{
+			TransactionID:    "buy-1",
+			Account:          "bob",
+			Symbol:           "SWAP.BTC",
+			Price:            decimal.NewFromInt(2),
+			Quantity:         decimal.NewFromInt(10),
+			ProfitPercentage: decimal.NewFromInt(100),
+		}},
+	}}
+}
+
+func TestProtoSnapshotRoundTrip(t *testing.T) {
+	snapshot := &Snapshot{Version: 7, Tokens: grpcTestTokens(), Stages: []StageStatus{{Stage: "prices", Error: "timeout"}}}
+
+	protoSnapshot := newProtoSnapshot(snapshot, snapshot.Tokens, false)
+
+	if protoSnapshot.Version != 7 || len(protoSnapshot.Tokens) != 1 || len(protoSnapshot.Tokens[0].BuyOrders) != 1 {
+		t.Fatalf("unexpected proto snapshot %+v", protoSnapshot)
+	}
+
+	tokens, err := tokensFromProto(protoSnapshot)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token, want := tokens[0], snapshot.Tokens[0]
+
+	if token.Symbol != want.Symbol || token.CoinGeckoName != want.CoinGeckoName || token.Network != want.Network ||
+		!token.USDPrice.Equal(want.USDPrice) || !token.NetworkFlatFee.Equal(want.NetworkFlatFee) || !token.NetworkPercentageFee.Equal(want.NetworkPercentageFee) {
+		t.Fatalf("token came back as %+v", token)
+	}
+
+	if order := token.BuyOrders[0]; order.TransactionID != "buy-1" || !order.Price.Equal(decimal.NewFromInt(2)) || !order.ProfitPercentage.Equal(decimal.NewFromInt(100)) {
+		t.Fatalf("order came back as %+v", order)
+	}
+
+	if stages := stagesFromProto(protoSnapshot); len(stages) != 1 || !stages[0].Degraded() {
+		t.Fatalf("stages came back as %+v", stages)
+	}
+
+	if withoutOrders := newProtoSnapshot(snapshot, snapshot.Tokens, true); len(withoutOrders.Tokens[0].BuyOrders) != 0 {
+		t.Fatal("orders were sent with without_orders")
+	}
+
+	protoSnapshot.Tokens[0].HivePrice = "lots"
+
+	if _, err = tokensFromProto(protoSnapshot); err == nil {
+		t.Fatal("a bad decimal from the primary was accepted")
+	}
+}
+
+func TestGRPCGetQuote(t *testing.T) {
+	previous := GetConfig()
+	defer SetConfig(previous)
+
+	SetConfig(DefaultConfig())
+	PublishSnapshot(&Snapshot{Tokens: grpcTestTokens()})
+
+	server := &GRPCServer{}
+
+	quote, err := server.GetQuote(context.Background(), &hiveswapv1.GetQuoteRequest{
+		Direction:    hiveswapv1.Direction_DIRECTION_TOKEN_TO_HIVE,
+		HiveAmount:   "20",
+		FromSwapHive: true,
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 10 HIVE buys the 10 tokens the order takes at the reference price, they sell for 20 less the 0.75% fee
+	if quote.SnapshotVersion != CurrentSnapshot().Version || quote.UnroutedHive != "10" || quote.ExpectedProfitHive != "9.85" {
+		t.Fatalf("unexpected quote %+v", quote)
+	}
+
+	_, err = server.GetQuote(context.Background(), &hiveswapv1.GetQuoteRequest{HiveAmount: "20"})
+
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("a quote without a direction got %v", err)
+	}
+
+	_, err = server.GetOrder(context.Background(), &hiveswapv1.GetOrderRequest{TxId: "nope"})
+
+	if status.Code(err) != codes.NotFound {
+		t.Fatalf("a missing order got %v", err)
+	}
+
+	token, err := server.GetToken(context.Background(), &hiveswapv1.GetTokenRequest{Symbol: "swap.btc"})
+
+	if err != nil || token.Symbol != "BTC" {
+		t.Fatalf("GetToken = %+v, %v", token, err)
+	}
+}
+
+func TestGRPCScopes(t *testing.T) {
+	// only for its keys, the rpcs check the same global store
+	_, _, key := adminTestMux(t)
+
+	limiter := NewRateLimiter()
+	keyed := metadata.NewIncomingContext(context.Background(), metadata.Pairs(grpcAPIKeyMetadata, key))
+
+	tests := []struct {
+		method string
+		ctx    context.Context
+		want   codes.Code
+	}{
+		{hiveswapv1.HiveSwapService_GetSnapshot_FullMethodName, context.Background(), codes.OK},
+		{hiveswapv1.HiveSwapService_GetToken_FullMethodName, context.Background(), codes.Unauthenticated},
+		{hiveswapv1.HiveSwapService_GetOrder_FullMethodName, context.Background(), codes.Unauthenticated},
+		{hiveswapv1.HiveSwapService_GetToken_FullMethodName, keyed, codes.OK},
+		{hiveswapv1.HiveSwapService_GetOrder_FullMethodName, keyed, codes.OK},
+		{hiveswapv1.HiveSwapService_GetQuote_FullMethodName, keyed, codes.PermissionDenied},
+	}
+
+	for _, test := range tests {
+		if _, err := limiter.authorizeRPC(test.ctx, test.method); status.Code(err) != test.want {
+			t.Errorf("%s got %v, want %s", test.method, err, test.want)
+		}
+	}
+}
+
+// watchStream is a WatchSnapshots stream that's already been cancelled, so the rpc returns after its checks
+type watchStream struct {
+	grpc.ServerStream
+
+	ctx  context.Context
+	sent []*hiveswapv1.Snapshot
+}
+
+func (s *watchStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *watchStream) Send(snapshot *hiveswapv1.Snapshot) error {
+	s.sent = append(s.sent, snapshot)
+	return nil
+}
+
+func TestGRPCUnfilteredWatchNeedsAKey(t *testing.T) {
+	_, admin, key := adminTestMux(t)
+
+	limiter := NewRateLimiter()
+	server := &GRPCServer{}
+	info := &grpc.StreamServerInfo{FullMethod: hiveswapv1.HiveSwapService_WatchSnapshots_FullMethodName}
+
+	watch := func(rawKey string, request *hiveswapv1.WatchSnapshotsRequest) error {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		if rawKey != "" {
+			ctx = metadata.NewIncomingContext(ctx, metadata.Pairs(grpcAPIKeyMetadata, rawKey))
+		}
+
+		// through the interceptor, so the key makes it to the rpc like it would for real
+		return limiter.streamInterceptor(server, &watchStream{ctx: ctx}, info, func(srv interface{}, stream grpc.ServerStream) error {
+			return server.WatchSnapshots(request, &watchStream{ServerStream: stream, ctx: stream.Context()})
+		})
+	}
+
+	if err := watch("", &hiveswapv1.WatchSnapshotsRequest{}); err != nil {
+		t.Fatalf("a filtered watch without a key got %v", err)
+	}
+
+	if err := watch("", &hiveswapv1.WatchSnapshotsRequest{Unfiltered: true}); status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("an unfiltered watch without a key got %v", err)
+	}
+
+	for _, rawKey := range []string{key, admin} {
+		if err := watch(rawKey, &hiveswapv1.WatchSnapshotsRequest{Unfiltered: true}); err != nil {
+			t.Fatalf("an unfiltered watch with a key got %v", err)
+		}
+	}
+}
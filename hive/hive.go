@@ -1,16 +1,38 @@
-package main
+// Package hive is a client for a Hive node's api, for the HIVE/HBD rate on the internal market
+package hive
 
 import (
 	"bytes"
 	"errors"
-	"github.com/goccy/go-json"
-	"github.com/shopspring/decimal"
 	"net/http"
 	"strings"
 	"time"
+
+	"github.com/CADawg/hive-swap-calculator/upstream"
+	"github.com/goccy/go-json"
+	"github.com/shopspring/decimal"
 )
 
-type HiveRequest struct {
+// DefaultNode is deathwing's hive node
+const DefaultNode = "https://api.deathwing.me/"
+
+// Client talks to one hive node
+type Client struct {
+	node string
+	http *http.Client
+}
+
+// NewClient makes a client for node (e.g. DefaultNode) that sends its requests through httpClient
+func NewClient(node string, httpClient *http.Client) *Client {
+	return &Client{node: node, http: httpClient}
+}
+
+// Node is the node the client talks to
+func (c *Client) Node() string {
+	return c.node
+}
+
+type Request struct {
 	Id      int             `json:"id"`
 	JsonRPC string          `json:"jsonrpc"`
 	Method  string          `json:"method"`
@@ -28,11 +50,11 @@ type HistoryData struct {
 }
 
 // FetchBlockchainHiveHBDRate fetch hive/hbd rate from internal market (most people don't have access to bittrex [the only major-ish exchange that trades hbd] but everyone has access to the hive blockchain internal market)
-// fetch from deathwing's hive node using condenser.api.get_order_book
-func FetchBlockchainHiveHBDRate() (decimal.Decimal, error) {
+// fetch from the client's node using condenser_api.get_trade_history
+func (c *Client) FetchBlockchainHiveHBDRate() (decimal.Decimal, error) {
 	timestamp1, timestamp2 := GetTimestampsForPastHour()
 
-	reqData := HiveRequest{
+	reqData := Request{
 		Id:      0,
 		JsonRPC: "2.0",
 		Method:  "condenser_api.get_trade_history",
@@ -47,7 +69,7 @@ func FetchBlockchainHiveHBDRate() (decimal.Decimal, error) {
 
 	var resp HistoryData
 
-	req, err := http.NewRequest("POST", "https://api.deathwing.me/", bytes.NewBuffer(reqJson))
+	req, err := http.NewRequest("POST", c.node, bytes.NewBuffer(reqJson))
 
 	if err != nil {
 		return decimal.Decimal{}, err
@@ -55,7 +77,7 @@ func FetchBlockchainHiveHBDRate() (decimal.Decimal, error) {
 
 	req.Header.Set("Content-Type", "application/json")
 
-	res, err := http.DefaultClient.Do(req)
+	res, err := c.http.Do(req)
 
 	if err != nil {
 		return decimal.Decimal{}, err
@@ -63,6 +85,12 @@ func FetchBlockchainHiveHBDRate() (decimal.Decimal, error) {
 
 	defer res.Body.Close()
 
+	err = upstream.CheckRateLimited(res)
+
+	if err != nil {
+		return decimal.Decimal{}, err
+	}
+
 	err = json.NewDecoder(res.Body).Decode(&resp)
 
 	if err != nil {
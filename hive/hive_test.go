@@ -0,0 +1,105 @@
+package hive
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/CADawg/hive-swap-calculator/upstream"
+	"github.com/goccy/go-json"
+	"github.com/shopspring/decimal"
+)
+
+func fakeNode(t *testing.T, status int, body string) *Client {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request Request
+
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil || request.Method != "condenser_api.get_trade_history" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(body))
+	}))
+
+	t.Cleanup(server.Close)
+
+	return NewClient(server.URL, server.Client())
+}
+
+func TestFetchBlockchainHiveHBDRate(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:   "latest trade, hive for hbd",
+			status: http.StatusOK,
+			body: `{"jsonrpc":"2.0","id":0,"result":[
+				{"date":"2024-01-01T00:00:00","current_pays":"10.000 HIVE","open_pays":"1.000 HBD"},
+				{"date":"2024-01-01T00:05:00","current_pays":"30.000 HIVE","open_pays":"10.000 HBD"},
+				{"date":"2024-01-01T00:01:00","current_pays":"5.000 HIVE","open_pays":"1.000 HBD"}
+			]}`,
+			want: "3",
+		},
+		{
+			name:   "hbd for hive",
+			status: http.StatusOK,
+			body:   `{"jsonrpc":"2.0","id":0,"result":[{"date":"2024-01-01T00:00:00","current_pays":"2.000 HBD","open_pays":"5.000 HIVE"}]}`,
+			want:   "2.5",
+		},
+		{name: "no trades", status: http.StatusOK, body: `{"jsonrpc":"2.0","id":0,"result":[]}`, wantErr: true},
+		{name: "bad amount", status: http.StatusOK, body: `{"result":[{"date":"2024-01-01T00:00:00","current_pays":"lots HBD","open_pays":"5.000 HIVE"}]}`, wantErr: true},
+		{name: "same currency", status: http.StatusOK, body: `{"result":[{"date":"2024-01-01T00:00:00","current_pays":"1.000 HIVE","open_pays":"5.000 HIVE"}]}`, wantErr: true},
+		{name: "broken json", status: http.StatusOK, body: `{"result":`, wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rate, err := fakeNode(t, test.status, test.body).FetchBlockchainHiveHBDRate()
+
+			if test.wantErr {
+				if err == nil {
+					t.Fatalf("got %s, want an error", rate)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !rate.Equal(decimal.RequireFromString(test.want)) {
+				t.Fatalf("rate = %s, want %s", rate, test.want)
+			}
+		})
+	}
+}
+
+func TestFetchBlockchainHiveHBDRateRateLimited(t *testing.T) {
+	if _, err := fakeNode(t, http.StatusTooManyRequests, "").FetchBlockchainHiveHBDRate(); !errors.Is(err, upstream.ErrRateLimited) {
+		t.Fatalf("got %v, want a rate limit", err)
+	}
+}
+
+func TestGetCurrencyAndDecimalFromString(t *testing.T) {
+	currency, amount, err := GetCurrencyAndDecimalFromString("1.234 HBD")
+
+	if err != nil || currency != "HBD" || !amount.Equal(decimal.RequireFromString("1.234")) {
+		t.Fatalf("got %s %s, %v", amount, currency, err)
+	}
+
+	for _, bad := range []string{"", "1.234", "1.234 HBD extra", "abc HBD"} {
+		if _, _, err = GetCurrencyAndDecimalFromString(bad); err == nil {
+			t.Errorf("parsed %q", bad)
+		}
+	}
+}
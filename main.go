@@ -2,113 +2,130 @@ package main
 
 import (
 	"errors"
+	"flag"
 	"fmt"
-	"github.com/CADawg/hive-swap-calculator/frontend"
 	"net/http"
 	"os"
 	"os/signal"
-	"sync"
 	"time"
-
-	"github.com/goccy/go-json"
 )
 
-var Tokens []TokenData
-var TokensLock sync.RWMutex
 var signals = make(chan os.Signal, 1)
 
+var configPath = flag.String("config", "config.json", "path to the json config file (defaults are used if it doesn't exist)")
+
 func main() {
-	signal.Notify(signals, os.Interrupt)
+	// admin cli
+	if len(os.Args) > 1 && os.Args[1] == "keys" {
+		err := RunKeysCommand(os.Args[2:])
 
-	go func() {
-		for {
-			v, err2 := FetchBlockchainHiveHBDRate()
+		if err != nil {
+			fmt.Println("error:", err)
+			os.Exit(1)
+		}
 
-			fmt.Println("Fetched blockchain hive/hbd rate", v)
+		return
+	}
 
-			data, err := LoadPriceAndSymbolData()
+	flag.Parse()
 
-			if err2 == nil {
-				for i := range data {
-					if data[i].Symbol == "HBD" {
-						data[i].HIVEPrice = v // update the hive/hbd rate using our blockchain data (this is more accurate than coingecko for most people and has no delay)
-					}
-				}
-			}
+	config, err := LoadConfig(*configPath)
 
-			if err == nil {
-				fmt.Println("Loaded price and symbol data")
+	if err != nil {
+		panic("error loading config: " + err.Error())
+	}
 
-				data, err = AddNetworkFee(data)
+	SetConfig(config)
 
-				if err == nil {
-					fmt.Println("Added network fee data")
+	// SIGHUP (or editing the file) reloads the config, it's swapped in at the start of the next refresh
+	configWatcher := NewConfigWatcher(*configPath)
+	configWatcher.Start(5 * time.Second)
 
-					data, err = GetUnderpricedMarketSellOrders(data)
+	APIKeys = NewAPIKeyStore(config.APIKeysFile)
 
-					if err == nil {
-						fmt.Println("Added underpriced market sell orders")
+	err = APIKeys.Reload()
 
-						data, err = GetUnderpricedMarketBuyOrders(data)
+	if err != nil {
+		panic("error loading api keys: " + err.Error())
+	}
 
-						if err == nil {
-							fmt.Println("Added underpriced market buy orders")
+	APIKeys.Start(time.Minute)
 
-							PrettyPrintTokenData(data)
-						}
-					}
-				}
-			}
+	_, err = StartSimulation(config.Simulate, config.Tokens)
 
-			// handle any error
-			if err != nil {
-				fmt.Println("error:", err)
-			}
+	if err != nil {
+		panic("error starting simulation: " + err.Error())
+	}
 
-			TokensLock.Lock()
-			Tokens = data
-			TokensLock.Unlock()
+	replayer, err := StartCapture(config.Capture)
+
+	if err != nil {
+		panic("error starting capture: " + err.Error())
+	}
 
-			time.Sleep(10 * time.Second)
+	// serve the last good snapshot until the first refresh is done (captures start from scratch, so a replay can
+	// rebuild everything the recording saw, and a simulation has nothing to do with the real market)
+	if config.Capture.Mode == "" && !config.Simulate.Enabled {
+		err = LoadSnapshot(config.SnapshotFile)
+
+		if err != nil {
+			fmt.Println("error loading snapshot, starting without one:", err)
 		}
-	}()
+	}
 
-	go func() {
-		// start the server
+	signal.Notify(signals, os.Interrupt)
 
-		mux := http.NewServeMux()
+	go func() {
+		// a replica gets its data from the primary, only going to the upstreams itself if the primary goes away
+		if GetConfig().Replica.PrimaryAddr != "" {
+			FollowPrimary(configWatcher)
+			return
+		}
 
-		mux.HandleFunc("/prices", func(w http.ResponseWriter, r *http.Request) {
-			TokensLock.RLock()
-			defer TokensLock.RUnlock()
+		if replayer != nil {
+			RunReplay(replayer, GetConfig().Capture, func() {
+				configWatcher.ApplyPending()
+			})
+			return
+		}
 
-			if r.Method != "GET" {
-				w.WriteHeader(http.StatusMethodNotAllowed)
-				return
+		RunScheduledRefreshes(func() {
+			// new tokens (or fee and schedule settings) need everything polled now, not whenever it's next due
+			if configWatcher.ApplyPending() {
+				_, _ = RefreshScheduler.Trigger(nil)
 			}
+		})
+	}()
 
-			w.Header().Set("Content-Type", "application/json")
+	// http and grpc share rate limits, so a client can't double their limit by using both
+	limiter := NewRateLimiter()
 
-			err := json.NewEncoder(w).Encode(Tokens)
+	if grpcAddr := GetConfig().Server.GRPCAddr; grpcAddr != "" {
+		go func() {
+			err := ServeGRPC(GetConfig().Server, limiter)
 
 			if err != nil {
-				w.WriteHeader(http.StatusInternalServerError)
-				return
+				panic("error starting grpc server: " + err.Error())
 			}
-		})
+		}()
+	}
+
+	go func() {
+		// start the server
+
+		mux := NewServeMux(limiter)
 
-		// static files at / apart from /prices
-		mux.Handle("/", http.FileServer(http.FS(frontend.RootDir)))
+		serverConfig := GetConfig().Server
 
 		server := http.Server{
-			Addr:              ":6241",
+			Addr:              serverConfig.Addr,
 			ReadHeaderTimeout: 5 * time.Second, // no slowloris pls
 			ReadTimeout:       5 * time.Second,
 			Handler:           mux,
 		}
 
 		// serve
-		err := server.ListenAndServe()
+		err := Serve(&server, serverConfig)
 
 		if err != nil {
 			if errors.Is(err, http.ErrServerClosed) {
@@ -121,29 +138,3 @@ func main() {
 
 	<-signals
 }
-
-func LoadPriceAndSymbolData() ([]TokenData, error) {
-	tokens, err := GetJSON[map[string]TokenData]("https://api.coingecko.com/api/v3/simple/price?ids=hive,bitcoin,litecoin,hive_dollar,steem,dogecoin,ethereum,tether,binancecoin,binance-usd,wax,matic-network,bitcoin-cash,basic-attention-token,eos&vs_currencies=usd,btc&include_24hr_change=true&include_last_updated_at=true&precision=full")
-
-	if err != nil {
-		return nil, err
-	}
-
-	tokensArray := MapToTokenWithName(*tokens)
-
-	tokensWithData := AddHivePriceInformation(AddAllSymbolInformation(tokensArray))
-
-	return tokensWithData, nil
-}
-
-func MapToTokenWithName(data map[string]TokenData) []TokenData {
-	var dataParsed []TokenData
-
-	for key, value := range data {
-		value.CoinGeckoName = key
-
-		dataParsed = append(dataParsed, value)
-	}
-
-	return dataParsed
-}
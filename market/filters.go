@@ -0,0 +1,166 @@
+package market
+
+import (
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/CADawg/hive-swap-calculator/engine"
+	"github.com/CADawg/hive-swap-calculator/pricing"
+	"github.com/shopspring/decimal"
+)
+
+// OrderFilter drops orders we don't want to show even though they're priced past the reference price
+type OrderFilter struct {
+	// MinHiveValue is the smallest order (quantity * price) worth showing, in HIVE
+	MinHiveValue decimal.Decimal `json:"min_hive_value"`
+	// MinSecondsToExpiry hides orders that are about to expire (you'd never get your tx in on time)
+	MinSecondsToExpiry int64 `json:"min_seconds_to_expiry"`
+	// ExcludeDust hides orders that wouldn't even cover the token's flat withdrawal fee
+	ExcludeDust bool `json:"exclude_dust"`
+	// AllowAccounts if set, only orders from these accounts are kept
+	AllowAccounts []string `json:"allow_accounts,omitempty"`
+	// DenyAccounts orders from these accounts are always dropped (e.g. our own)
+	DenyAccounts []string `json:"deny_accounts,omitempty"`
+}
+
+// Keep reports whether an order for token passes the filter at the given time
+func (f OrderFilter) Keep(token pricing.TokenData, order engine.MarketOrder, now time.Time) bool {
+	if len(f.AllowAccounts) > 0 && !containsAccount(f.AllowAccounts, order.Account) {
+		return false
+	}
+
+	if containsAccount(f.DenyAccounts, order.Account) {
+		return false
+	}
+
+	// orders without an expiration never expire
+	if f.MinSecondsToExpiry > 0 && order.Expiration > 0 && order.Expiration-now.Unix() < f.MinSecondsToExpiry {
+		return false
+	}
+
+	hiveValue := order.Quantity.Mul(order.Price)
+
+	if hiveValue.LessThan(f.MinHiveValue) {
+		return false
+	}
+
+	if f.ExcludeDust && hiveValue.LessThanOrEqual(token.NetworkFlatFee) {
+		return false
+	}
+
+	return true
+}
+
+// FilterOrders returns the orders that pass the filter (the input slice is left alone)
+func (f OrderFilter) FilterOrders(token pricing.TokenData, orders []engine.MarketOrder, now time.Time) []engine.MarketOrder {
+	var kept []engine.MarketOrder
+
+	for _, order := range orders {
+		if f.Keep(token, order, now) {
+			kept = append(kept, order)
+		}
+	}
+
+	return kept
+}
+
+// FilterTokens applies the filter to the buy and sell orders of every token, returning new token values
+func (f OrderFilter) FilterTokens(tokens []pricing.TokenData, now time.Time) []pricing.TokenData {
+	if tokens == nil {
+		return nil
+	}
+
+	filtered := make([]pricing.TokenData, len(tokens))
+
+	for i, token := range tokens {
+		filtered[i] = token
+		filtered[i].SellOrders = f.FilterOrders(token, token.SellOrders, now)
+		filtered[i].BuyOrders = f.FilterOrders(token, token.BuyOrders, now)
+	}
+
+	return filtered
+}
+
+// ParseOrderFilterQuery overrides the fields of base that are set in the query string
+func ParseOrderFilterQuery(base OrderFilter, query url.Values) (OrderFilter, error) {
+	f := base
+
+	if value := query.Get("min_hive_value"); value != "" {
+		minHiveValue, err := decimal.NewFromString(value)
+
+		if err != nil || minHiveValue.IsNegative() {
+			return f, errors.New("min_hive_value must be a non-negative number")
+		}
+
+		f.MinHiveValue = minHiveValue
+	}
+
+	if value := query.Get("min_seconds_to_expiry"); value != "" {
+		seconds, err := strconv.ParseInt(value, 10, 64)
+
+		if err != nil || seconds < 0 {
+			return f, errors.New("min_seconds_to_expiry must be a non-negative whole number of seconds")
+		}
+
+		f.MinSecondsToExpiry = seconds
+	}
+
+	if value := query.Get("exclude_dust"); value != "" {
+		excludeDust, err := strconv.ParseBool(value)
+
+		if err != nil {
+			return f, errors.New("exclude_dust must be true or false")
+		}
+
+		f.ExcludeDust = excludeDust
+	}
+
+	if query.Has("allow_accounts") {
+		f.AllowAccounts = SplitList(query.Get("allow_accounts"))
+	}
+
+	if query.Has("deny_accounts") {
+		f.DenyAccounts = SplitList(query.Get("deny_accounts"))
+	}
+
+	return f, nil
+}
+
+// SplitList splits a comma separated query value, dropping empty entries
+func SplitList(value string) []string {
+	var list []string
+
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+
+		if item != "" {
+			list = append(list, item)
+		}
+	}
+
+	return list
+}
+
+// ContainsString reports whether list holds value exactly
+func ContainsString(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+func containsAccount(accounts []string, account string) bool {
+	for _, a := range accounts {
+		if strings.EqualFold(a, account) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,149 @@
+package market
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/CADawg/hive-swap-calculator/engine"
+	"github.com/CADawg/hive-swap-calculator/pricing"
+	"github.com/shopspring/decimal"
+)
+
+func TestOrderFilterKeep(t *testing.T) {
+	now := time.Unix(1_000_000, 0)
+	token := pricing.TokenData{Symbol: "BTC", SwapSymbol: "SWAP.BTC", NetworkFlatFee: decimal.NewFromInt(5)}
+
+	order := func(account string, quantity, price int64, expiration int64) engine.MarketOrder {
+		return engine.MarketOrder{
+			Account:    account,
+			Quantity:   decimal.NewFromInt(quantity),
+			Price:      decimal.NewFromInt(price),
+			Expiration: expiration,
+		}
+	}
+
+	tests := []struct {
+		name   string
+		filter OrderFilter
+		order  engine.MarketOrder
+		keep   bool
+	}{
+		{"no filter", OrderFilter{}, order("alice", 1, 1, 0), true},
+		{"allowed account", OrderFilter{AllowAccounts: []string{"alice"}}, order("alice", 1, 1, 0), true},
+		{"allowed account is case insensitive", OrderFilter{AllowAccounts: []string{"ALICE"}}, order("alice", 1, 1, 0), true},
+		{"account not allowed", OrderFilter{AllowAccounts: []string{"bob"}}, order("alice", 1, 1, 0), false},
+		{"denied account", OrderFilter{DenyAccounts: []string{"alice"}}, order("alice", 1, 1, 0), false},
+		{"deny beats allow", OrderFilter{AllowAccounts: []string{"alice"}, DenyAccounts: []string{"alice"}}, order("alice", 1, 1, 0), false},
+		{"expires too soon", OrderFilter{MinSecondsToExpiry: 60}, order("alice", 1, 1, now.Unix()+59), false},
+		{"expires in time", OrderFilter{MinSecondsToExpiry: 60}, order("alice", 1, 1, now.Unix()+60), true},
+		{"never expires", OrderFilter{MinSecondsToExpiry: 60}, order("alice", 1, 1, 0), true},
+		{"under min value", OrderFilter{MinHiveValue: decimal.NewFromInt(10)}, order("alice", 3, 3, 0), false},
+		{"at min value", OrderFilter{MinHiveValue: decimal.NewFromInt(9)}, order("alice", 3, 3, 0), true},
+		{"dust", OrderFilter{ExcludeDust: true}, order("alice", 5, 1, 0), false},
+		{"not dust", OrderFilter{ExcludeDust: true}, order("alice", 6, 1, 0), true},
+		{"dust allowed", OrderFilter{}, order("alice", 5, 1, 0), true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if keep := test.filter.Keep(token, test.order, now); keep != test.keep {
+				t.Fatalf("Keep() = %v, want %v", keep, test.keep)
+			}
+		})
+	}
+}
+
+func TestOrderFilterFilterTokens(t *testing.T) {
+	tokens := []pricing.TokenData{{
+		Symbol:     "BTC",
+		SellOrders: []engine.MarketOrder{{Account: "alice"}, {Account: "bob"}},
+		BuyOrders:  []engine.MarketOrder{{Account: "bob"}, {Account: "carol"}},
+	}}
+
+	filtered := OrderFilter{DenyAccounts: []string{"bob"}}.FilterTokens(tokens, time.Now())
+
+	if len(filtered[0].SellOrders) != 1 || filtered[0].SellOrders[0].Account != "alice" {
+		t.Fatalf("sell orders = %+v, want only alice", filtered[0].SellOrders)
+	}
+
+	if len(filtered[0].BuyOrders) != 1 || filtered[0].BuyOrders[0].Account != "carol" {
+		t.Fatalf("buy orders = %+v, want only carol", filtered[0].BuyOrders)
+	}
+
+	// the input is left alone
+	if len(tokens[0].SellOrders) != 2 || len(tokens[0].BuyOrders) != 2 {
+		t.Fatal("FilterTokens changed its input")
+	}
+
+	if (OrderFilter{}).FilterTokens(nil, time.Now()) != nil {
+		t.Fatal("nil tokens should stay nil")
+	}
+}
+
+func TestParseOrderFilterQuery(t *testing.T) {
+	base := OrderFilter{MinHiveValue: decimal.NewFromInt(1), DenyAccounts: []string{"me"}}
+
+	tests := []struct {
+		query   string
+		want    OrderFilter
+		wantErr bool
+	}{
+		{query: "", want: base},
+		{query: "min_hive_value=2.5", want: OrderFilter{MinHiveValue: decimal.RequireFromString("2.5"), DenyAccounts: []string{"me"}}},
+		{query: "min_seconds_to_expiry=30&exclude_dust=true", want: OrderFilter{MinHiveValue: decimal.NewFromInt(1), MinSecondsToExpiry: 30, ExcludeDust: true, DenyAccounts: []string{"me"}}},
+		{query: "allow_accounts=a,,b%20&deny_accounts=", want: OrderFilter{MinHiveValue: decimal.NewFromInt(1), AllowAccounts: []string{"a", "b"}}},
+		{query: "min_hive_value=-1", wantErr: true},
+		{query: "min_hive_value=lots", wantErr: true},
+		{query: "min_seconds_to_expiry=1.5", wantErr: true},
+		{query: "min_seconds_to_expiry=-1", wantErr: true},
+		{query: "exclude_dust=maybe", wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.query, func(t *testing.T) {
+			query, err := url.ParseQuery(test.query)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			filter, err := ParseOrderFilterQuery(base, query)
+
+			if test.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !filter.MinHiveValue.Equal(test.want.MinHiveValue) {
+				t.Fatalf("MinHiveValue = %s, want %s", filter.MinHiveValue, test.want.MinHiveValue)
+			}
+
+			filter.MinHiveValue, test.want.MinHiveValue = decimal.Zero, decimal.Zero
+
+			if !reflect.DeepEqual(filter, test.want) {
+				t.Fatalf("got %+v, want %+v", filter, test.want)
+			}
+		})
+	}
+}
+
+func TestSplitList(t *testing.T) {
+	list := SplitList(" alice,, bob ,")
+
+	if len(list) != 2 || list[0] != "alice" || list[1] != "bob" {
+		t.Fatalf("got %q", list)
+	}
+
+	if SplitList("") != nil || !ContainsString(list, "bob") || ContainsString(list, "Bob") {
+		t.Fatal("expected an empty list and exact matches")
+	}
+}
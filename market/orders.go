@@ -0,0 +1,86 @@
+// Package market works out which orders are past the reference price, filters them and routes quotes through them
+package market
+
+import (
+	"github.com/CADawg/hive-swap-calculator/engine"
+	"github.com/CADawg/hive-swap-calculator/gateway"
+	"github.com/CADawg/hive-swap-calculator/pricing"
+	"github.com/shopspring/decimal"
+)
+
+// the sides of the book an order can be on
+const (
+	SideBuy  = "buy"
+	SideSell = "sell"
+)
+
+// GetUnderpricedMarketSellOrders adds the sell orders from book (load it with engine.Client.GetAllSwapSellOrders) that are under each token's price
+func GetUnderpricedMarketSellOrders(tokens []pricing.TokenData, book []engine.MarketOrder) []pricing.TokenData {
+	for i, token := range tokens {
+		orders := GetSellOrdersForToken(token, token.HIVEPrice, book)
+
+		for j := range orders {
+			orders[j].ProfitPercentage = token.HIVEPrice.Sub(orders[j].Price).Div(token.HIVEPrice).Mul(decimal.NewFromInt(100)).Abs()
+		}
+
+		tokens[i].SellOrders = orders
+	}
+
+	return tokens
+}
+
+func GetSellOrdersForToken(token pricing.TokenData, hivePrice decimal.Decimal, book []engine.MarketOrder) []engine.MarketOrder {
+	var allOrders []engine.MarketOrder
+
+	for _, order := range book {
+		if order.Symbol == token.SwapSymbol && order.Price.LessThanOrEqual(hivePrice) {
+			allOrders = append(allOrders, order)
+		}
+	}
+
+	return allOrders
+}
+
+// GetUnderpricedMarketBuyOrders adds the buy orders from book (load it with engine.Client.GetAllSwapBuyOrders) that are over each token's price
+func GetUnderpricedMarketBuyOrders(tokens []pricing.TokenData, book []engine.MarketOrder) []pricing.TokenData {
+	for i, token := range tokens {
+		orders := GetBuyOrdersForToken(token, token.HIVEPrice, book)
+
+		for j := range orders {
+			orders[j].ProfitPercentage = token.HIVEPrice.Sub(orders[j].Price).Div(token.HIVEPrice).Abs()
+		}
+
+		tokens[i].BuyOrders = orders
+	}
+
+	return tokens
+}
+
+func GetBuyOrdersForToken(token pricing.TokenData, hivePrice decimal.Decimal, book []engine.MarketOrder) []engine.MarketOrder {
+	var allOrders []engine.MarketOrder
+
+	for _, order := range book {
+		if order.Symbol == token.SwapSymbol && order.Price.GreaterThanOrEqual(hivePrice) {
+			allOrders = append(allOrders, order)
+		}
+	}
+
+	return allOrders
+}
+
+// ApplyNetworkFees sets the fees of each token (fees are by symbol), tokens we don't have a fee for get defaultFee
+func ApplyNetworkFees(data []pricing.TokenData, fees map[string]gateway.TokenFee, defaultFee decimal.Decimal) []pricing.TokenData {
+	for i, token := range data {
+		fee, ok := fees[token.Symbol]
+
+		if !ok {
+			fee = gateway.TokenFee{PercentageFee: defaultFee}
+		}
+
+		data[i].NetworkPercentageFee = fee.PercentageFee
+		data[i].NetworkFlatFee = fee.FlatFee
+		data[i].Network = fee.Network
+	}
+
+	return data
+}
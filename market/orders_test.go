@@ -0,0 +1,73 @@
+package market
+
+import (
+	"testing"
+
+	"github.com/CADawg/hive-swap-calculator/engine"
+	"github.com/CADawg/hive-swap-calculator/gateway"
+	"github.com/CADawg/hive-swap-calculator/pricing"
+	"github.com/shopspring/decimal"
+)
+
+func testBook() []engine.MarketOrder {
+	return []engine.MarketOrder{
+		{TransactionID: "cheap", Symbol: "SWAP.BTC", Price: decimal.NewFromInt(90), Quantity: decimal.NewFromInt(1)},
+		{TransactionID: "at", Symbol: "SWAP.BTC", Price: decimal.NewFromInt(100), Quantity: decimal.NewFromInt(1)},
+		{TransactionID: "dear", Symbol: "SWAP.BTC", Price: decimal.NewFromInt(110), Quantity: decimal.NewFromInt(1)},
+		{TransactionID: "other", Symbol: "SWAP.ETH", Price: decimal.NewFromInt(1), Quantity: decimal.NewFromInt(1)},
+	}
+}
+
+func TestGetUnderpricedMarketOrders(t *testing.T) {
+	tokens := []pricing.TokenData{{Symbol: "BTC", SwapSymbol: "SWAP.BTC", HIVEPrice: decimal.NewFromInt(100)}}
+
+	tokens = GetUnderpricedMarketSellOrders(tokens, testBook())
+	tokens = GetUnderpricedMarketBuyOrders(tokens, testBook())
+
+	tests := []struct {
+		side   string
+		orders []engine.MarketOrder
+		want   map[string]string
+	}{
+		{SideSell, tokens[0].SellOrders, map[string]string{"cheap": "10", "at": "0"}},
+		{SideBuy, tokens[0].BuyOrders, map[string]string{"at": "0", "dear": "0.1"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.side, func(t *testing.T) {
+			if len(test.orders) != len(test.want) {
+				t.Fatalf("got %d orders, want %d", len(test.orders), len(test.want))
+			}
+
+			// the sell side is in percent, the buy side is a fraction (as it always has been)
+			for _, order := range test.orders {
+				want, ok := test.want[order.TransactionID]
+
+				if !ok {
+					t.Fatalf("unexpected order %s", order.TransactionID)
+				}
+
+				if !order.ProfitPercentage.Equal(decimal.RequireFromString(want)) {
+					t.Fatalf("%s profit = %s, want %s", order.TransactionID, order.ProfitPercentage, want)
+				}
+			}
+		})
+	}
+}
+
+func TestApplyNetworkFees(t *testing.T) {
+	tokens := []pricing.TokenData{{Symbol: "BTC"}, {Symbol: "ETH"}}
+	fees := map[string]gateway.TokenFee{
+		"ETH": {PercentageFee: decimal.RequireFromString("0.5"), FlatFee: decimal.NewFromInt(3), Network: "Ethereum"},
+	}
+
+	tokens = ApplyNetworkFees(tokens, fees, decimal.NewFromInt(1))
+
+	if !tokens[0].NetworkPercentageFee.Equal(decimal.NewFromInt(1)) || !tokens[0].NetworkFlatFee.IsZero() || tokens[0].Network != "" {
+		t.Fatalf("BTC should get the default fee, got %+v", tokens[0])
+	}
+
+	if !tokens[1].NetworkPercentageFee.Equal(decimal.RequireFromString("0.5")) || !tokens[1].NetworkFlatFee.Equal(decimal.NewFromInt(3)) || tokens[1].Network != "Ethereum" {
+		t.Fatalf("ETH should get its gateway fee, got %+v", tokens[1])
+	}
+}
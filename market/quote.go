@@ -0,0 +1,236 @@
+package market
+
+import (
+	"errors"
+	"sort"
+
+	"github.com/CADawg/hive-swap-calculator/engine"
+	"github.com/CADawg/hive-swap-calculator/pricing"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	// DirectionHiveToToken spends HIVE on underpriced sell orders and withdraws the token
+	DirectionHiveToToken = "hive_to_token"
+	// DirectionTokenToHive deposits the token and sells it into overpriced buy orders for HIVE
+	DirectionTokenToHive = "token_to_hive"
+)
+
+// DefaultSwapFeePercentage is what it costs to move HIVE on/off Hive Engine (same default as the frontend)
+var DefaultSwapFeePercentage = decimal.RequireFromString("0.75")
+
+var oneHundred = decimal.NewFromInt(100)
+
+// QuoteRequest is what someone wants to do with an amount of HIVE
+type QuoteRequest struct {
+	Direction  string
+	HiveAmount decimal.Decimal
+	// FromSwapHive means the HIVE is already SWAP.HIVE on Hive Engine, so the swap fee doesn't apply
+	FromSwapHive      bool
+	SwapFeePercentage decimal.Decimal
+	// Symbols limits the tokens the quote can use (empty for all of them)
+	Symbols []string
+}
+
+// RouteLeg is the part of a quote that goes through one token
+type RouteLeg struct {
+	Symbol           string
+	Side             string
+	TransactionIDs   []string
+	HiveAmount       decimal.Decimal
+	TokenAmount      decimal.Decimal
+	FlatFeeHive      decimal.Decimal
+	ProfitHive       decimal.Decimal
+	ProfitPercentage decimal.Decimal
+}
+
+// Quote is the best way we found to route the HIVE, whatever couldn't be routed profitably is left as UnroutedHive
+type Quote struct {
+	Direction          string
+	HiveAmount         decimal.Decimal
+	Legs               []RouteLeg
+	UnroutedHive       decimal.Decimal
+	ExpectedProfitHive decimal.Decimal
+	ProfitPercentage   decimal.Decimal
+}
+
+type quoteCandidate struct {
+	token pricing.TokenData
+	order engine.MarketOrder
+	// ratio is the profit per HIVE put through the order (0.01 = 1%), only for sorting (profit multiplies first so it doesn't lose precision)
+	ratio decimal.Decimal
+	// keep is what's left of each HIVE after the gateway and swap fees
+	keep decimal.Decimal
+	// capacity is how much of the HIVE being routed the order can take
+	capacity decimal.Decimal
+}
+
+// Validate checks the request makes sense
+func (q *QuoteRequest) Validate() error {
+	if q.Direction != DirectionHiveToToken && q.Direction != DirectionTokenToHive {
+		return errors.New("direction must be " + DirectionHiveToToken + " or " + DirectionTokenToHive)
+	}
+
+	if !q.HiveAmount.IsPositive() {
+		return errors.New("hive_amount must be more than 0")
+	}
+
+	if q.SwapFeePercentage.IsNegative() || q.SwapFeePercentage.GreaterThanOrEqual(oneHundred) {
+		return errors.New("swap_fee_percentage must be between 0 and 100")
+	}
+
+	return nil
+}
+
+// QuoteRoutes works out the most profitable way to put the HIVE through the orders in tokens.
+// Orders are filled best first, then any token whose flat fee eats all its profit is dropped and we try again.
+func QuoteRoutes(tokens []pricing.TokenData, request QuoteRequest) Quote {
+	swapPenalty := decimal.Zero
+
+	if !request.FromSwapHive {
+		swapPenalty = request.SwapFeePercentage.Div(oneHundred)
+	}
+
+	var candidates []quoteCandidate
+
+	for _, token := range tokens {
+		if token.Symbol == "HIVE" || !token.HIVEPrice.IsPositive() {
+			continue
+		}
+
+		if len(request.Symbols) > 0 && !ContainsString(request.Symbols, token.Symbol) {
+			continue
+		}
+
+		orders := token.SellOrders
+
+		if request.Direction == DirectionTokenToHive {
+			orders = token.BuyOrders
+		}
+
+		keep := decimal.NewFromInt(1).Sub(token.NetworkPercentageFee.Div(oneHundred)).Mul(decimal.NewFromInt(1).Sub(swapPenalty))
+
+		for _, order := range orders {
+			if !order.Price.IsPositive() {
+				continue
+			}
+
+			var ratio, capacity decimal.Decimal
+
+			if request.Direction == DirectionHiveToToken {
+				// the HIVE buys the order's tokens at its price
+				ratio = token.HIVEPrice.Div(order.Price).Mul(keep).Sub(decimal.NewFromInt(1))
+				capacity = order.Price.Mul(order.Quantity)
+			} else {
+				// the HIVE buys tokens at the reference price, which are sold into the order
+				ratio = order.Price.Div(token.HIVEPrice).Mul(keep).Sub(decimal.NewFromInt(1))
+				capacity = token.HIVEPrice.Mul(order.Quantity)
+			}
+
+			if ratio.IsPositive() {
+				candidates = append(candidates, quoteCandidate{token: token, order: order, ratio: ratio, keep: keep, capacity: capacity})
+			}
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].ratio.GreaterThan(candidates[j].ratio) })
+
+	excluded := map[string]bool{}
+
+	for {
+		quote := fillQuote(candidates, excluded, request)
+
+		dropped := false
+
+		for _, leg := range quote.Legs {
+			if !leg.ProfitHive.IsPositive() {
+				excluded[leg.Symbol] = true
+				dropped = true
+			}
+		}
+
+		if !dropped {
+			return quote
+		}
+	}
+}
+
+func fillQuote(candidates []quoteCandidate, excluded map[string]bool, request QuoteRequest) Quote {
+	remaining := request.HiveAmount
+	legs := map[string]*RouteLeg{}
+	var symbols []string
+
+	for _, candidate := range candidates {
+		if !remaining.IsPositive() {
+			break
+		}
+
+		symbol := candidate.token.Symbol
+
+		if excluded[symbol] {
+			continue
+		}
+
+		leg, ok := legs[symbol]
+
+		if !ok {
+			leg = &RouteLeg{Symbol: symbol, Side: SideSell}
+
+			if request.Direction == DirectionTokenToHive {
+				leg.Side = SideBuy
+			} else {
+				// the flat fee is for withdrawing, so only when we end up holding the token
+				leg.FlatFeeHive = candidate.token.NetworkFlatFee
+				leg.ProfitHive = leg.FlatFeeHive.Neg()
+			}
+
+			legs[symbol] = leg
+			symbols = append(symbols, symbol)
+		}
+
+		hive := decimal.Min(candidate.capacity, remaining)
+
+		leg.TransactionIDs = append(leg.TransactionIDs, candidate.order.TransactionID)
+		leg.HiveAmount = leg.HiveAmount.Add(hive)
+		leg.TokenAmount = leg.TokenAmount.Add(candidate.tokens(hive, request.Direction))
+		leg.ProfitHive = leg.ProfitHive.Add(candidate.profit(hive, request.Direction))
+
+		remaining = remaining.Sub(hive)
+	}
+
+	quote := Quote{
+		Direction:    request.Direction,
+		HiveAmount:   request.HiveAmount,
+		UnroutedHive: remaining,
+	}
+
+	for _, symbol := range symbols {
+		leg := legs[symbol]
+
+		leg.ProfitPercentage = leg.ProfitHive.Div(leg.HiveAmount).Mul(oneHundred)
+		quote.ExpectedProfitHive = quote.ExpectedProfitHive.Add(leg.ProfitHive)
+		quote.Legs = append(quote.Legs, *leg)
+	}
+
+	quote.ProfitPercentage = quote.ExpectedProfitHive.Div(request.HiveAmount).Mul(oneHundred)
+
+	return quote
+}
+
+// tokens is how many tokens hive buys, from the order itself going to the token or at the reference price going to HIVE
+func (c quoteCandidate) tokens(hive decimal.Decimal, direction string) decimal.Decimal {
+	if direction == DirectionHiveToToken {
+		return hive.Div(c.order.Price)
+	}
+
+	return hive.Div(c.token.HIVEPrice)
+}
+
+// profit is how much HIVE we'd gain putting hive through the order
+func (c quoteCandidate) profit(hive decimal.Decimal, direction string) decimal.Decimal {
+	if direction == DirectionHiveToToken {
+		return hive.Mul(c.token.HIVEPrice).Div(c.order.Price).Mul(c.keep).Sub(hive)
+	}
+
+	return hive.Mul(c.order.Price).Div(c.token.HIVEPrice).Mul(c.keep).Sub(hive)
+}
@@ -0,0 +1,231 @@
+package market
+
+import (
+	"testing"
+
+	"github.com/CADawg/hive-swap-calculator/engine"
+	"github.com/CADawg/hive-swap-calculator/pricing"
+	"github.com/shopspring/decimal"
+)
+
+func d(value string) decimal.Decimal {
+	return decimal.RequireFromString(value)
+}
+
+func quoteOrder(txID string, price string, quantity string) engine.MarketOrder {
+	return engine.MarketOrder{TransactionID: txID, Price: d(price), Quantity: d(quantity)}
+}
+
+type wantLeg struct {
+	symbol string
+	txIDs  []string
+	hive   string
+	tokens string
+	profit string
+}
+
+func TestQuoteRoutes(t *testing.T) {
+	tests := []struct {
+		name      string
+		tokens    []pricing.TokenData
+		request   QuoteRequest
+		legs      []wantLeg
+		unrouted  string
+		profit    string
+		percent   string
+		direction string
+	}{
+		{
+			name:     "hive to token, part of one order",
+			tokens:   []pricing.TokenData{{Symbol: "BTC", HIVEPrice: d("10"), SellOrders: []engine.MarketOrder{quoteOrder("a", "8", "5")}}},
+			request:  QuoteRequest{Direction: DirectionHiveToToken, HiveAmount: d("20"), FromSwapHive: true},
+			legs:     []wantLeg{{"BTC", []string{"a"}, "20", "2.5", "5"}},
+			unrouted: "0",
+			profit:   "5",
+			percent:  "25",
+		},
+		{
+			name:     "hive to token, more than the order takes",
+			tokens:   []pricing.TokenData{{Symbol: "BTC", HIVEPrice: d("10"), SellOrders: []engine.MarketOrder{quoteOrder("a", "8", "5")}}},
+			request:  QuoteRequest{Direction: DirectionHiveToToken, HiveAmount: d("100"), FromSwapHive: true},
+			legs:     []wantLeg{{"BTC", []string{"a"}, "40", "5", "10"}},
+			unrouted: "60",
+			profit:   "10",
+			percent:  "10",
+		},
+		{
+			name: "hive to token, best order first across tokens",
+			tokens: []pricing.TokenData{
+				{Symbol: "BTC", HIVEPrice: d("10"), SellOrders: []engine.MarketOrder{quoteOrder("b1", "9", "10"), quoteOrder("b2", "8", "5")}},
+				{Symbol: "ETH", HIVEPrice: d("2"), SellOrders: []engine.MarketOrder{quoteOrder("e1", "1", "10")}},
+			},
+			request: QuoteRequest{Direction: DirectionHiveToToken, HiveAmount: d("70"), FromSwapHive: true},
+			// e1 doubles the HIVE, then b2 (25%), then what's left goes to b1
+			legs:     []wantLeg{{"ETH", []string{"e1"}, "10", "10", "10"}, {"BTC", []string{"b2", "b1"}, "60", "7.2222222222", "12.2222222222"}},
+			unrouted: "0",
+			profit:   "22.2222222222",
+			percent:  "31.746031746",
+		},
+		{
+			name: "hive to token, with percentage, swap and flat fees",
+			tokens: []pricing.TokenData{{
+				Symbol: "BTC", HIVEPrice: d("10"), NetworkPercentageFee: d("1"), NetworkFlatFee: d("2"),
+				SellOrders: []engine.MarketOrder{quoteOrder("a", "8", "5")},
+			}},
+			request: QuoteRequest{Direction: DirectionHiveToToken, HiveAmount: d("40"), SwapFeePercentage: d("0.75")},
+			// 50 tokens worth of HIVE * 0.99 * 0.9925 - 40 - 2
+			legs:     []wantLeg{{"BTC", []string{"a"}, "40", "5", "7.12875"}},
+			unrouted: "0",
+			profit:   "7.12875",
+			percent:  "17.821875",
+		},
+		{
+			name: "hive to token, a flat fee bigger than the profit drops the token",
+			tokens: []pricing.TokenData{
+				{Symbol: "BTC", HIVEPrice: d("10"), NetworkFlatFee: d("20"), SellOrders: []engine.MarketOrder{quoteOrder("a", "8", "5")}},
+				{Symbol: "ETH", HIVEPrice: d("2"), SellOrders: []engine.MarketOrder{quoteOrder("e1", "1.6", "10")}},
+			},
+			request:  QuoteRequest{Direction: DirectionHiveToToken, HiveAmount: d("40"), FromSwapHive: true},
+			legs:     []wantLeg{{"ETH", []string{"e1"}, "16", "10", "4"}},
+			unrouted: "24",
+			profit:   "4",
+			percent:  "10",
+		},
+		{
+			name:     "hive to token, nothing past the reference price",
+			tokens:   []pricing.TokenData{{Symbol: "BTC", HIVEPrice: d("10"), SellOrders: []engine.MarketOrder{quoteOrder("a", "10", "5")}}},
+			request:  QuoteRequest{Direction: DirectionHiveToToken, HiveAmount: d("40"), FromSwapHive: true},
+			unrouted: "40",
+			profit:   "0",
+			percent:  "0",
+		},
+		{
+			// the HIVE buys 10 tokens at the reference price, the order only takes 10 of them
+			name:     "token to hive, more than the order takes",
+			tokens:   []pricing.TokenData{{Symbol: "BTC", HIVEPrice: d("1"), BuyOrders: []engine.MarketOrder{quoteOrder("a", "2", "10")}}},
+			request:  QuoteRequest{Direction: DirectionTokenToHive, HiveAmount: d("20"), FromSwapHive: true},
+			legs:     []wantLeg{{"BTC", []string{"a"}, "10", "10", "10"}},
+			unrouted: "10",
+			profit:   "10",
+			percent:  "50",
+		},
+		{
+			name:     "token to hive, part of one order",
+			tokens:   []pricing.TokenData{{Symbol: "BTC", HIVEPrice: d("4"), BuyOrders: []engine.MarketOrder{quoteOrder("a", "5", "10")}}},
+			request:  QuoteRequest{Direction: DirectionTokenToHive, HiveAmount: d("8"), FromSwapHive: true},
+			legs:     []wantLeg{{"BTC", []string{"a"}, "8", "2", "2"}},
+			unrouted: "0",
+			profit:   "2",
+			percent:  "25",
+		},
+		{
+			name: "token to hive, several orders",
+			tokens: []pricing.TokenData{{Symbol: "BTC", HIVEPrice: d("1"), BuyOrders: []engine.MarketOrder{
+				quoteOrder("low", "1.5", "4"),
+				quoteOrder("high", "2", "10"),
+				quoteOrder("under", "0.9", "100"),
+			}}},
+			request:  QuoteRequest{Direction: DirectionTokenToHive, HiveAmount: d("20"), FromSwapHive: true},
+			legs:     []wantLeg{{"BTC", []string{"high", "low"}, "14", "14", "12"}},
+			unrouted: "6",
+			profit:   "12",
+			percent:  "60",
+		},
+		{
+			name: "token to hive, with fees (no flat fee, nothing is withdrawn)",
+			tokens: []pricing.TokenData{{
+				Symbol: "BTC", HIVEPrice: d("1"), NetworkPercentageFee: d("1"), NetworkFlatFee: d("5"),
+				BuyOrders: []engine.MarketOrder{quoteOrder("a", "2", "10")},
+			}},
+			request:  QuoteRequest{Direction: DirectionTokenToHive, HiveAmount: d("10"), SwapFeePercentage: d("0.75")},
+			legs:     []wantLeg{{"BTC", []string{"a"}, "10", "10", "9.6515"}},
+			unrouted: "0",
+			profit:   "9.6515",
+			percent:  "96.515",
+		},
+		{
+			name: "only the asked for symbols",
+			tokens: []pricing.TokenData{
+				{Symbol: "BTC", HIVEPrice: d("1"), BuyOrders: []engine.MarketOrder{quoteOrder("a", "2", "10")}},
+				{Symbol: "ETH", HIVEPrice: d("1"), BuyOrders: []engine.MarketOrder{quoteOrder("e", "3", "10")}},
+			},
+			request:  QuoteRequest{Direction: DirectionTokenToHive, HiveAmount: d("5"), FromSwapHive: true, Symbols: []string{"BTC"}},
+			legs:     []wantLeg{{"BTC", []string{"a"}, "5", "5", "5"}},
+			unrouted: "0",
+			profit:   "5",
+			percent:  "100",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			quote := QuoteRoutes(test.tokens, test.request)
+
+			if len(quote.Legs) != len(test.legs) {
+				t.Fatalf("got %d legs, want %d: %+v", len(quote.Legs), len(test.legs), quote.Legs)
+			}
+
+			for i, want := range test.legs {
+				leg := quote.Legs[i]
+
+				if leg.Symbol != want.symbol || len(leg.TransactionIDs) != len(want.txIDs) {
+					t.Fatalf("leg %d is %s through %v, want %s through %v", i, leg.Symbol, leg.TransactionIDs, want.symbol, want.txIDs)
+				}
+
+				for j := range want.txIDs {
+					if leg.TransactionIDs[j] != want.txIDs[j] {
+						t.Fatalf("leg %d goes through %v, want %v", i, leg.TransactionIDs, want.txIDs)
+					}
+				}
+
+				assertDecimal(t, leg.Symbol+" hive", leg.HiveAmount, want.hive)
+				assertDecimal(t, leg.Symbol+" tokens", leg.TokenAmount, want.tokens)
+				assertDecimal(t, leg.Symbol+" profit", leg.ProfitHive, want.profit)
+			}
+
+			assertDecimal(t, "unrouted", quote.UnroutedHive, test.unrouted)
+			assertDecimal(t, "profit", quote.ExpectedProfitHive, test.profit)
+			assertDecimal(t, "profit percentage", quote.ProfitPercentage, test.percent)
+
+			// what's routed plus what isn't is what was asked for
+			routed := quote.UnroutedHive
+
+			for _, leg := range quote.Legs {
+				routed = routed.Add(leg.HiveAmount)
+			}
+
+			if !routed.Equal(test.request.HiveAmount) {
+				t.Fatalf("routed %s of %s", routed, test.request.HiveAmount)
+			}
+		})
+	}
+}
+
+// assertDecimal compares to 10 decimal places, divisions don't come out exact
+func assertDecimal(t *testing.T, name string, got decimal.Decimal, want string) {
+	t.Helper()
+
+	if !got.Round(10).Equal(d(want).Round(10)) {
+		t.Fatalf("%s = %s, want %s", name, got, want)
+	}
+}
+
+func TestQuoteRequestValidate(t *testing.T) {
+	tests := []struct {
+		request QuoteRequest
+		valid   bool
+	}{
+		{QuoteRequest{Direction: DirectionHiveToToken, HiveAmount: d("1")}, true},
+		{QuoteRequest{Direction: DirectionTokenToHive, HiveAmount: d("1"), SwapFeePercentage: d("99.9")}, true},
+		{QuoteRequest{Direction: "sideways", HiveAmount: d("1")}, false},
+		{QuoteRequest{Direction: DirectionHiveToToken, HiveAmount: d("0")}, false},
+		{QuoteRequest{Direction: DirectionHiveToToken, HiveAmount: d("1"), SwapFeePercentage: d("-1")}, false},
+		{QuoteRequest{Direction: DirectionHiveToToken, HiveAmount: d("1"), SwapFeePercentage: d("100")}, false},
+	}
+
+	for _, test := range tests {
+		if err := test.request.Validate(); (err == nil) != test.valid {
+			t.Errorf("Validate(%+v) = %v, want valid %v", test.request, err, test.valid)
+		}
+	}
+}
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/CADawg/hive-swap-calculator/market"
+	"github.com/andybalholm/brotli"
+)
+
+// below this it's not worth the cpu to compress
+const minCompressSize = 1024
+
+// bufferedResponse holds a handler's response so we can hash and compress it before it goes out (or hand it back from
+// a simulated upstream)
+type bufferedResponse struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (b *bufferedResponse) Header() http.Header {
+	return b.header
+}
+
+func (b *bufferedResponse) Write(data []byte) (int, error) {
+	return b.body.Write(data)
+}
+
+func (b *bufferedResponse) WriteHeader(status int) {
+	if b.status == 0 {
+		b.status = status
+	}
+}
+
+// CacheAndCompress gives successful responses a strong ETag (answering If-None-Match with a 304),
+// a Cache-Control lasting until the next refresh and gzip/brotli compression when the client accepts it
+func CacheAndCompress(next http.Handler, maxAge func() time.Duration) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		buffered := &bufferedResponse{header: w.Header()}
+
+		next.ServeHTTP(buffered, r)
+
+		if buffered.status == 0 {
+			buffered.status = http.StatusOK
+		}
+
+		// errors go out as they are
+		if buffered.status != http.StatusOK {
+			w.WriteHeader(buffered.status)
+			_, _ = w.Write(buffered.body.Bytes())
+			return
+		}
+
+		body := buffered.body.Bytes()
+
+		// net/http would otherwise sniff the compressed bytes
+		if w.Header().Get("Content-Type") == "" {
+			w.Header().Set("Content-Type", http.DetectContentType(body))
+		}
+
+		encoding := ""
+
+		if len(body) >= minCompressSize {
+			encoding = negotiateEncoding(r.Header.Get("Accept-Encoding"))
+		}
+
+		// the etag is for these exact bytes, so each encoding needs its own
+		sum := sha256.Sum256(body)
+		etag := `"` + hex.EncodeToString(sum[:16])
+
+		if encoding != "" {
+			etag += "-" + encoding
+		}
+
+		etag += `"`
+
+		w.Header().Set("ETag", etag)
+		w.Header().Add("Vary", "Accept-Encoding")
+		cacheControl := "public"
+
+		// keyed responses aren't for everyone, keep them out of shared caches
+		if _, ok := APIKeyFromContext(r.Context()); ok {
+			cacheControl = "private"
+		}
+
+		w.Header().Set("Cache-Control", cacheControl+", max-age="+strconv.Itoa(int(maxAge().Seconds())))
+
+		if etagMatches(r.Header.Get("If-None-Match"), etag) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		if encoding == "" {
+			w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+			_, _ = w.Write(body)
+			return
+		}
+
+		var compressed bytes.Buffer
+		var compressor io.WriteCloser
+
+		switch encoding {
+		case "br":
+			compressor = brotli.NewWriterLevel(&compressed, brotli.DefaultCompression)
+		case "gzip":
+			compressor = gzip.NewWriter(&compressed)
+		}
+
+		_, err := compressor.Write(body)
+
+		if err == nil {
+			err = compressor.Close()
+		}
+
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Encoding", encoding)
+		w.Header().Set("Content-Length", strconv.Itoa(compressed.Len()))
+		_, _ = w.Write(compressed.Bytes())
+	})
+}
+
+// negotiateEncoding picks br over gzip, or nothing if the client accepts neither
+func negotiateEncoding(acceptEncoding string) string {
+	qualities := map[string]float64{}
+
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+
+		quality := 1.0
+
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(q, 64)
+
+			if err != nil {
+				continue
+			}
+
+			quality = parsed
+		}
+
+		qualities[strings.ToLower(strings.TrimSpace(name))] = quality
+	}
+
+	best, bestQuality := "", 0.0
+
+	for _, encoding := range []string{"br", "gzip"} {
+		quality, ok := qualities[encoding]
+
+		if !ok {
+			quality, ok = qualities["*"]
+		}
+
+		if ok && quality > bestQuality {
+			best, bestQuality = encoding, quality
+		}
+	}
+
+	return best
+}
+
+// etagMatches checks an If-None-Match header against our etag (weak comparison, as the spec asks for If-None-Match)
+func etagMatches(ifNoneMatch string, etag string) bool {
+	if ifNoneMatch == "" {
+		return false
+	}
+
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+
+	return false
+}
+
+// exposedHeaders are the response headers browsers let a cross origin frontend read (beyond the basic ones)
+var exposedHeaders = []string{"ETag", "Retry-After", "X-Snapshot-Version", "X-Snapshot-Stale", "X-Degraded-Stages"}
+
+// CORS adds the access control headers for origins allowed by the config, and answers preflight requests
+func CORS(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		config := GetConfig().CORS
+		origin := r.Header.Get("Origin")
+
+		w.Header().Add("Vary", "Origin")
+
+		// same origin requests (or non browsers) don't need anything
+		if origin == "" || !originAllowed(config.AllowedOrigins, origin) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if market.ContainsString(config.AllowedOrigins, "*") {
+			w.Header().Set("Access-Control-Allow-Origin", "*")
+		} else {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+		}
+
+		w.Header().Set("Access-Control-Expose-Headers", strings.Join(exposedHeaders, ", "))
+
+		// preflight
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			w.Header().Set("Access-Control-Allow-Methods", strings.Join(config.AllowedMethods, ", "))
+
+			// only ever the configured headers, the browser refuses the request if it wanted something else
+			if len(config.AllowedHeaders) > 0 {
+				w.Header().Set("Access-Control-Allow-Headers", strings.Join(config.AllowedHeaders, ", "))
+			}
+
+			if config.MaxAge > 0 {
+				w.Header().Set("Access-Control-Max-Age", strconv.Itoa(config.MaxAge))
+			}
+
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func originAllowed(allowedOrigins []string, origin string) bool {
+	for _, allowed := range allowedOrigins {
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return true
+		}
+	}
+
+	return false
+}
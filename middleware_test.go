@@ -0,0 +1,192 @@
+package main
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNegotiateEncoding(t *testing.T) {
+	tests := map[string]string{
+		"":                      "",
+		"gzip":                  "gzip",
+		"gzip, br":              "br",
+		"br;q=0.5, gzip":        "gzip",
+		"*":                     "br",
+		"br;q=0, *;q=0.1":       "gzip",
+		"identity":              "",
+		"GZIP;q=0.3, br;q=oops": "gzip",
+	}
+
+	for acceptEncoding, want := range tests {
+		if got := negotiateEncoding(acceptEncoding); got != want {
+			t.Errorf("negotiateEncoding(%q) = %q, want %q", acceptEncoding, got, want)
+		}
+	}
+}
+
+func TestEtagMatches(t *testing.T) {
+	tests := []struct {
+		ifNoneMatch string
+		match       bool
+	}{
+		{"", false},
+		{`"abc"`, true},
+		{`W/"abc"`, true},
+		{`"xyz", "abc"`, true},
+		{"*", true},
+		{`"abcd"`, false},
+	}
+
+	for _, test := range tests {
+		if got := etagMatches(test.ifNoneMatch, `"abc"`); got != test.match {
+			t.Errorf("etagMatches(%q) = %v, want %v", test.ifNoneMatch, got, test.match)
+		}
+	}
+}
+
+func TestCacheAndCompress(t *testing.T) {
+	body := strings.Repeat("hive ", minCompressSize)
+	status := http.StatusOK
+
+	handler := CacheAndCompress(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.WriteHeader(status)
+		_, _ = io.WriteString(w, body)
+	}), func() time.Duration { return 30 * time.Second })
+
+	get := func(headers map[string]string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", "/prices", nil)
+
+		for name, value := range headers {
+			r.Header.Set(name, value)
+		}
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+
+		return w
+	}
+
+	plain := get(nil)
+
+	if plain.Code != http.StatusOK || plain.Body.String() != body || plain.Header().Get("Content-Encoding") != "" {
+		t.Fatalf("plain response: %d %q", plain.Code, plain.Header().Get("Content-Encoding"))
+	}
+
+	if plain.Header().Get("Cache-Control") != "public, max-age=30" {
+		t.Fatalf("Cache-Control = %q", plain.Header().Get("Cache-Control"))
+	}
+
+	gzipped := get(map[string]string{"Accept-Encoding": "gzip"})
+
+	if gzipped.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("Content-Encoding = %q, want gzip", gzipped.Header().Get("Content-Encoding"))
+	}
+
+	reader, err := gzip.NewReader(gzipped.Body)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decompressed, err := io.ReadAll(reader)
+
+	if err != nil || string(decompressed) != body {
+		t.Fatalf("gzip body didn't decompress to the original (%v)", err)
+	}
+
+	// each encoding has its own etag
+	if plain.Header().Get("ETag") == gzipped.Header().Get("ETag") {
+		t.Fatal("plain and gzip responses share an etag")
+	}
+
+	notModified := get(map[string]string{"Accept-Encoding": "gzip", "If-None-Match": gzipped.Header().Get("ETag")})
+
+	if notModified.Code != http.StatusNotModified || notModified.Body.Len() != 0 {
+		t.Fatalf("got %d with %d bytes, want an empty 304", notModified.Code, notModified.Body.Len())
+	}
+
+	// the plain etag doesn't match the gzipped bytes
+	if get(map[string]string{"Accept-Encoding": "gzip", "If-None-Match": plain.Header().Get("ETag")}).Code != http.StatusOK {
+		t.Fatal("the plain etag matched a gzipped response")
+	}
+
+	status = http.StatusServiceUnavailable
+	failed := get(map[string]string{"Accept-Encoding": "gzip"})
+
+	if failed.Code != http.StatusServiceUnavailable || failed.Header().Get("ETag") != "" || failed.Body.String() != body {
+		t.Fatalf("errors should go out untouched, got %d etag %q", failed.Code, failed.Header().Get("ETag"))
+	}
+}
+
+func TestCORS(t *testing.T) {
+	previous := GetConfig()
+	defer SetConfig(previous)
+
+	config := DefaultConfig()
+	config.CORS.AllowedOrigins = []string{"https://swap.example.com"}
+	SetConfig(config)
+
+	handler := CORS(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "ok")
+	}))
+
+	request := func(method string, headers map[string]string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, "/api/v1/prices", nil)
+
+		for name, value := range headers {
+			r.Header.Set(name, value)
+		}
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+
+		return w
+	}
+
+	allowed := request("GET", map[string]string{"Origin": "https://swap.example.com"})
+
+	if allowed.Header().Get("Access-Control-Allow-Origin") != "https://swap.example.com" || allowed.Body.String() != "ok" {
+		t.Fatalf("allowed origin got %q", allowed.Header().Get("Access-Control-Allow-Origin"))
+	}
+
+	// the frontend needs these to know how fresh the data is and when to retry
+	if got := allowed.Header().Get("Access-Control-Expose-Headers"); got != "ETag, Retry-After, X-Snapshot-Version, X-Snapshot-Stale, X-Degraded-Stages" {
+		t.Fatalf("Access-Control-Expose-Headers = %q", got)
+	}
+
+	if denied := request("GET", map[string]string{"Origin": "https://evil.example.com"}); denied.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Fatal("an unknown origin got CORS headers")
+	}
+
+	preflight := request("OPTIONS", map[string]string{
+		"Origin":                         "https://swap.example.com",
+		"Access-Control-Request-Method":  "GET",
+		"Access-Control-Request-Headers": "X-API-Key, X-Anything-Else",
+	})
+
+	if preflight.Code != http.StatusNoContent || preflight.Body.Len() != 0 {
+		t.Fatalf("preflight got %d", preflight.Code)
+	}
+
+	// the configured headers, never whatever the client asked for
+	if got := preflight.Header().Get("Access-Control-Allow-Headers"); got != "Accept, Content-Type, If-None-Match, X-API-Key" {
+		t.Fatalf("Access-Control-Allow-Headers = %q", got)
+	}
+
+	if preflight.Header().Get("Access-Control-Allow-Methods") != "GET, HEAD, OPTIONS" || preflight.Header().Get("Access-Control-Max-Age") != "600" {
+		t.Fatalf("unexpected preflight headers %v", preflight.Header())
+	}
+
+	config.CORS.AllowedOrigins = []string{"*"}
+	SetConfig(config)
+
+	if wildcard := request("GET", map[string]string{"Origin": "https://anywhere.example.com"}); wildcard.Header().Get("Access-Control-Allow-Origin") != "*" {
+		t.Fatalf("wildcard origin got %q", wildcard.Header().Get("Access-Control-Allow-Origin"))
+	}
+}
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+// APIRoute is one endpoint of the versioned api, it's used both to register the handler and to describe it in the OpenAPI document
+type APIRoute struct {
+	Path string
+	// Method is GET (and HEAD) if left empty
+	Method      string
+	Summary     string
+	Description string
+	Parameters  []APIParameter
+	// Response is an example value of what a 200 sends, its type becomes the schema
+	Response interface{}
+	// Formats are the extra content types (beyond json) the route can send
+	Formats []string
+	// Scope is the api key scope needed, empty for public routes
+	Scope string
+	// RateLimitClass picks which limit applies
+	RateLimitClass string
+	// Cached routes get ETags, Cache-Control and compression
+	Cached  bool
+	Handler http.HandlerFunc
+}
+
+// APIParameter is a path or query parameter of an APIRoute
+type APIParameter struct {
+	Name        string
+	In          string
+	Description string
+	// Type is the OpenAPI type (string, integer, number, boolean)
+	Type     string
+	Enum     []string
+	Required bool
+}
+
+var decimalType = reflect.TypeOf(decimal.Decimal{})
+
+// OpenAPIDocument builds an OpenAPI 3 document for the routes, the schemas come from the response types
+func OpenAPIDocument(routes []APIRoute) map[string]interface{} {
+	schemas := map[string]interface{}{}
+	paths := map[string]interface{}{}
+
+	errorResponse := map[string]interface{}{
+		"description": "Error",
+		"content": map[string]interface{}{
+			ContentTypeJSON: map[string]interface{}{"schema": openAPISchema(reflect.TypeOf(APIError{}), schemas)},
+		},
+	}
+
+	for _, route := range routes {
+		var parameters []interface{}
+
+		for _, parameter := range route.Parameters {
+			schema := map[string]interface{}{"type": parameter.Type}
+
+			if len(parameter.Enum) > 0 {
+				schema["enum"] = parameter.Enum
+			}
+
+			parameters = append(parameters, map[string]interface{}{
+				"name":        parameter.Name,
+				"in":          parameter.In,
+				"description": parameter.Description,
+				"required":    parameter.Required || parameter.In == "path",
+				"schema":      schema,
+			})
+		}
+
+		content := map[string]interface{}{
+			ContentTypeJSON: map[string]interface{}{"schema": openAPISchema(reflect.TypeOf(route.Response), schemas)},
+		}
+
+		for _, format := range route.Formats {
+			// the other formats are the same data, just not json
+			content[format] = map[string]interface{}{"schema": map[string]interface{}{"type": "string"}}
+		}
+
+		responses := map[string]interface{}{
+			"200": map[string]interface{}{"description": "OK", "content": content},
+			"400": errorResponse,
+			"429": errorResponse,
+		}
+
+		if route.Cached {
+			responses["304"] = map[string]interface{}{"description": "Not modified since the ETag in If-None-Match"}
+		}
+
+		operation := map[string]interface{}{
+			"summary":     route.Summary,
+			"description": route.Description,
+			"operationId": operationID(route.Path),
+			"parameters":  parameters,
+			"responses":   responses,
+		}
+
+		if route.Scope != "" {
+			responses["401"] = errorResponse
+			responses["403"] = errorResponse
+			operation["security"] = []interface{}{map[string]interface{}{"apiKey": []string{route.Scope}}}
+		}
+
+		method := route.Method
+
+		if method == "" {
+			method = http.MethodGet
+		}
+
+		paths[route.Path] = map[string]interface{}{strings.ToLower(method): operation}
+	}
+
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":       "Hive Swap Calculator API",
+			"version":     "1",
+			"description": "Prices, fees and Hive Engine market orders for the SWAP. tokens. Decimals are sent as strings so they stay exact.",
+		},
+		"paths": paths,
+		"components": map[string]interface{}{
+			"schemas": schemas,
+			"securitySchemes": map[string]interface{}{
+				"apiKey": map[string]interface{}{"type": "apiKey", "in": "header", "name": APIKeyHeader},
+			},
+		},
+	}
+}
+
+// operationID turns /api/v1/tokens/{symbol}/orders into tokensSymbolOrders
+func operationID(path string) string {
+	var id string
+
+	for _, part := range strings.Split(strings.TrimPrefix(path, APIV1), "/") {
+		part = strings.Trim(part, "{}")
+
+		if part == "" {
+			continue
+		}
+
+		if id == "" {
+			id = part
+		} else {
+			id += strings.ToUpper(part[:1]) + part[1:]
+		}
+	}
+
+	return id
+}
+
+// openAPISchema describes t, adding any structs to schemas and referencing them
+func openAPISchema(t reflect.Type, schemas map[string]interface{}) map[string]interface{} {
+	if t == decimalType {
+		return map[string]interface{}{"type": "string", "format": "decimal", "example": "0.00123"}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return openAPISchema(t.Elem(), schemas)
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int32, reflect.Uint32:
+		return map[string]interface{}{"type": "integer", "format": "int32"}
+	case reflect.Int64, reflect.Uint64:
+		return map[string]interface{}{"type": "integer", "format": "int64"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": openAPISchema(t.Elem(), schemas)}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": openAPISchema(t.Elem(), schemas)}
+	case reflect.Struct:
+		name := strings.TrimPrefix(t.Name(), "API")
+
+		if _, ok := schemas[name]; !ok {
+			// placeholder first in case the type refers to itself
+			schemas[name] = map[string]interface{}{}
+
+			properties := map[string]interface{}{}
+			var required []string
+
+			for i := 0; i < t.NumField(); i++ {
+				field := t.Field(i)
+				name, options, _ := strings.Cut(field.Tag.Get("json"), ",")
+
+				if name == "-" || !field.IsExported() {
+					continue
+				}
+
+				if name == "" {
+					name = field.Name
+				}
+
+				schema := openAPISchema(field.Type, schemas)
+
+				if doc := field.Tag.Get("doc"); doc != "" {
+					// a $ref can't have siblings in OpenAPI 3.0, so wrap it
+					if _, isRef := schema["$ref"]; isRef {
+						schema = map[string]interface{}{"allOf": []interface{}{schema}}
+					}
+
+					schema["description"] = doc
+				}
+
+				properties[name] = schema
+
+				if !strings.Contains(options, "omitempty") {
+					required = append(required, name)
+				}
+			}
+
+			schema := map[string]interface{}{"type": "object", "properties": properties}
+
+			if len(required) > 0 {
+				schema["required"] = required
+			}
+
+			schemas[name] = schema
+		}
+
+		return map[string]interface{}{"$ref": "#/components/schemas/" + name}
+	}
+
+	return map[string]interface{}{}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestOperationID(t *testing.T) {
+	tests := map[string]string{
+		APIV1 + "/prices":                 "prices",
+		APIV1 + "/tokens/{symbol}/orders": "tokensSymbolOrders",
+		APIV1 + "/orders/{txId}":          "ordersTxId",
+	}
+
+	for path, want := range tests {
+		if got := operationID(path); got != want {
+			t.Errorf("operationID(%s) = %s, want %s", path, got, want)
+		}
+	}
+}
+
+// collectRefs finds every $ref in the document
+func collectRefs(value interface{}, refs map[string]bool) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			if ref, ok := item.(string); ok && key == "$ref" {
+				refs[ref] = true
+			}
+
+			collectRefs(item, refs)
+		}
+	case []interface{}:
+		for _, item := range v {
+			collectRefs(item, refs)
+		}
+	}
+}
+
+func TestOpenAPIDocument(t *testing.T) {
+	routes := AllRoutes()
+	document := OpenAPIDocument(routes)
+
+	paths := document["paths"].(map[string]interface{})
+
+	if len(paths) != len(routes) {
+		t.Fatalf("%d paths for %d routes", len(paths), len(routes))
+	}
+
+	schemas := document["components"].(map[string]interface{})["schemas"].(map[string]interface{})
+
+	refs := map[string]bool{}
+	collectRefs(document, refs)
+
+	for ref := range refs {
+		if _, ok := schemas[strings.TrimPrefix(ref, "#/components/schemas/")]; !ok {
+			t.Errorf("%s doesn't resolve", ref)
+		}
+	}
+
+	operationIDs := map[string]bool{}
+
+	for _, route := range routes {
+		if operationIDs[operationID(route.Path)] {
+			t.Errorf("operation id %s is used twice", operationID(route.Path))
+		}
+
+		operationIDs[operationID(route.Path)] = true
+
+		// every {param} in the path has to be described
+		for _, part := range strings.Split(route.Path, "/") {
+			if !strings.HasPrefix(part, "{") {
+				continue
+			}
+
+			found := false
+
+			for _, parameter := range route.Parameters {
+				found = found || (parameter.In == "path" && parameter.Name == strings.Trim(part, "{}"))
+			}
+
+			if !found {
+				t.Errorf("%s doesn't describe its %s parameter", route.Path, part)
+			}
+		}
+	}
+
+	// decimals are strings so they stay exact
+	price := schemas["Order"].(map[string]interface{})["properties"].(map[string]interface{})["price"].(map[string]interface{})
+
+	if price["type"] != "string" || price["format"] != "decimal" {
+		t.Fatalf("order price schema is %v", price)
+	}
+}
@@ -0,0 +1,418 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/CADawg/hive-swap-calculator/engine"
+	"github.com/CADawg/hive-swap-calculator/market"
+	"github.com/CADawg/hive-swap-calculator/pricing"
+	"github.com/shopspring/decimal"
+)
+
+// StageOpportunities runs the enabled detectors over the finished tokens
+const StageOpportunities = "opportunities"
+
+// Opportunity is an order a detector thinks is worth acting on
+type Opportunity struct {
+	Symbol string
+	Side   string
+	Order  engine.MarketOrder
+	// Score is the detector's own measure of how good it is (higher is better), so it only compares within a detector
+	Score  decimal.Decimal
+	Reason string
+}
+
+// OpportunityDetector is a strategy for picking opportunities out of a snapshot
+type OpportunityDetector interface {
+	// Detect gets the whole snapshot (tokens, books, fees and prices), which it mustn't modify
+	Detect(snapshot *Snapshot) ([]Opportunity, error)
+}
+
+// DetectorFactory makes a detector with the current settings, it's called every refresh so config reloads apply
+type DetectorFactory func(config DetectorConfig) OpportunityDetector
+
+// DetectorResult is what one detector found in a snapshot, best first
+type DetectorResult struct {
+	Detector      string
+	Opportunities []Opportunity
+	// Error is why the detector failed, empty if it worked
+	Error string
+}
+
+// the built in detectors, more can be added with RegisterDetector
+const (
+	DetectorPastReference    = "past_reference"
+	DetectorMinDepth         = "min_depth"
+	DetectorPreferredNetwork = "preferred_network"
+	DetectorAccounts         = "accounts"
+)
+
+var detectorFactories = map[string]DetectorFactory{
+	DetectorPastReference: func(config DetectorConfig) OpportunityDetector {
+		return PastReferenceDetector{}
+	},
+	DetectorMinDepth: func(config DetectorConfig) OpportunityDetector {
+		return MinDepthDetector{MinHiveValue: config.MinDepthHive}
+	},
+	DetectorPreferredNetwork: func(config DetectorConfig) OpportunityDetector {
+		return PreferredNetworkDetector{Networks: config.PreferredNetworks}
+	},
+	DetectorAccounts: func(config DetectorConfig) OpportunityDetector {
+		return AccountsDetector{Accounts: config.Accounts}
+	},
+}
+var detectorFactoriesLock = &sync.RWMutex{}
+
+// RegisterDetector adds a detector that can be enabled (by name) in the config
+func RegisterDetector(name string, factory DetectorFactory) error {
+	detectorFactoriesLock.Lock()
+	defer detectorFactoriesLock.Unlock()
+
+	if name == "" || factory == nil {
+		return errors.New("detectors need a name and a factory")
+	}
+
+	if _, ok := detectorFactories[name]; ok {
+		return errors.New("detector " + name + " is already registered")
+	}
+
+	detectorFactories[name] = factory
+
+	return nil
+}
+
+// DetectorNames are every registered detector, sorted
+func DetectorNames() []string {
+	detectorFactoriesLock.RLock()
+	defer detectorFactoriesLock.RUnlock()
+
+	var names []string
+
+	for name := range detectorFactories {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+func getDetectorFactory(name string) (DetectorFactory, bool) {
+	detectorFactoriesLock.RLock()
+	defer detectorFactoriesLock.RUnlock()
+
+	factory, ok := detectorFactories[name]
+
+	return factory, ok
+}
+
+// RunDetectors runs every enabled detector over the snapshot, a failing detector doesn't stop the others. Whatever
+// they find has to pass filter and have a positive score (an edge over the reference price) to be kept.
+func RunDetectors(snapshot *Snapshot, config DetectorConfig, filter market.OrderFilter, now time.Time) ([]DetectorResult, error) {
+	var results []DetectorResult
+	var failed []string
+
+	tokens := map[string]pricing.TokenData{}
+
+	for _, token := range snapshot.Tokens {
+		tokens[token.Symbol] = token
+	}
+
+	for _, name := range config.Enabled {
+		result := DetectorResult{Detector: name}
+
+		factory, ok := getDetectorFactory(name)
+
+		if !ok {
+			// the config is validated against the registry, so this is only a detector that's gone since
+			result.Error = "no detector called " + name
+		} else {
+			opportunities, err := factory(config).Detect(snapshot)
+
+			if err != nil {
+				result.Error = err.Error()
+			}
+
+			var kept []Opportunity
+
+			for _, opportunity := range opportunities {
+				token, ok := tokens[opportunity.Symbol]
+
+				if ok && opportunity.Score.IsPositive() && filter.Keep(token, opportunity.Order, now) {
+					kept = append(kept, opportunity)
+				}
+			}
+
+			sort.SliceStable(kept, func(i, j int) bool {
+				return kept[i].Score.GreaterThan(kept[j].Score)
+			})
+
+			result.Opportunities = kept
+		}
+
+		if result.Error != "" {
+			failed = append(failed, name+": "+result.Error)
+		}
+
+		results = append(results, result)
+	}
+
+	if len(failed) > 0 {
+		return results, errors.New("detectors failed (" + strings.Join(failed, ", ") + ")")
+	}
+
+	return results, nil
+}
+
+func runOpportunitiesStage(next *Snapshot) error {
+	results, err := RunDetectors(next, GetConfig().Detectors, GetConfig().Filters, next.Now())
+
+	// the detectors that worked are still worth having
+	next.Opportunities = results
+
+	return err
+}
+
+// pastReference is every order in the token's books priced past its reference price, with how far past it is
+func pastReference(token pricing.TokenData, orders []engine.MarketOrder, side string) []Opportunity {
+	var opportunities []Opportunity
+
+	for _, order := range orders {
+		opportunities = append(opportunities, Opportunity{
+			Symbol: token.Symbol,
+			Side:   side,
+			Order:  order,
+			Score:  order.ProfitPercentage,
+			Reason: "priced " + order.ProfitPercentage.StringFixed(2) + "% past the reference price",
+		})
+	}
+
+	return opportunities
+}
+
+// PastReferenceDetector is what the api has always listed, every order priced past the reference price (scored by how far)
+type PastReferenceDetector struct{}
+
+func (d PastReferenceDetector) Detect(snapshot *Snapshot) ([]Opportunity, error) {
+	var opportunities []Opportunity
+
+	for _, token := range snapshot.Tokens {
+		opportunities = append(opportunities, pastReference(token, token.SellOrders, market.SideSell)...)
+		opportunities = append(opportunities, pastReference(token, token.BuyOrders, market.SideBuy)...)
+	}
+
+	return opportunities, nil
+}
+
+// MinDepthDetector is orders priced past the reference that are worth at least MinHiveValue, scored by the HIVE you'd
+// make filling all of it (before fees)
+type MinDepthDetector struct {
+	MinHiveValue decimal.Decimal
+}
+
+func (d MinDepthDetector) Detect(snapshot *Snapshot) ([]Opportunity, error) {
+	var opportunities []Opportunity
+
+	for _, token := range snapshot.Tokens {
+		for _, opportunity := range append(pastReference(token, token.SellOrders, market.SideSell), pastReference(token, token.BuyOrders, market.SideBuy)...) {
+			value := opportunity.Order.Price.Mul(opportunity.Order.Quantity)
+
+			if value.LessThan(d.MinHiveValue) {
+				continue
+			}
+
+			opportunity.Score = token.HIVEPrice.Sub(opportunity.Order.Price).Abs().Mul(opportunity.Order.Quantity)
+			opportunity.Reason = "worth " + value.StringFixed(3) + " HIVE, " + opportunity.Score.StringFixed(3) + " HIVE past the reference price"
+
+			opportunities = append(opportunities, opportunity)
+		}
+	}
+
+	return opportunities, nil
+}
+
+// PreferredNetworkDetector is orders priced past the reference for tokens withdrawn on one of Networks (tokens with
+// no flat fee network are left out)
+type PreferredNetworkDetector struct {
+	Networks []string
+}
+
+func (d PreferredNetworkDetector) Detect(snapshot *Snapshot) ([]Opportunity, error) {
+	var opportunities []Opportunity
+
+	for _, token := range snapshot.Tokens {
+		if token.Network == "" || !market.ContainsString(d.Networks, token.Network) {
+			continue
+		}
+
+		for _, opportunity := range append(pastReference(token, token.SellOrders, market.SideSell), pastReference(token, token.BuyOrders, market.SideBuy)...) {
+			opportunity.Reason += ", withdrawn on " + token.Network
+
+			opportunities = append(opportunities, opportunity)
+		}
+	}
+
+	return opportunities, nil
+}
+
+// AccountsDetector is every order from Accounts priced past the reference price (however little), scored by how far
+// past it is
+type AccountsDetector struct {
+	Accounts []string
+}
+
+func (d AccountsDetector) Detect(snapshot *Snapshot) ([]Opportunity, error) {
+	var opportunities []Opportunity
+
+	tokens := map[string]pricing.TokenData{}
+
+	for _, token := range snapshot.Tokens {
+		tokens[token.SwapSymbol] = token
+	}
+
+	books := []struct {
+		side   string
+		orders []engine.MarketOrder
+	}{
+		{market.SideSell, snapshot.SellBook},
+		{market.SideBuy, snapshot.BuyBook},
+	}
+
+	for _, book := range books {
+		for _, order := range book.orders {
+			token, ok := tokens[order.Symbol]
+
+			if !ok || token.HIVEPrice.IsZero() || !market.ContainsString(d.Accounts, order.Account) {
+				continue
+			}
+
+			// positive when it's a good deal for us, selling under the reference or buying over it
+			past := token.HIVEPrice.Sub(order.Price)
+
+			if book.side == market.SideBuy {
+				past = past.Neg()
+			}
+
+			// no edge, no opportunity
+			if !past.IsPositive() {
+				continue
+			}
+
+			score := past.Div(token.HIVEPrice).Mul(decimal.NewFromInt(100))
+
+			opportunities = append(opportunities, Opportunity{
+				Symbol: token.Symbol,
+				Side:   book.side,
+				Order:  order,
+				Score:  score,
+				Reason: "from " + order.Account + ", " + score.StringFixed(2) + "% past the reference price",
+			})
+		}
+	}
+
+	return opportunities, nil
+}
+
+// APIOpportunity is an order a detector picked out
+type APIOpportunity struct {
+	Symbol string          `json:"symbol" doc:"Symbol, e.g. BTC"`
+	Side   string          `json:"side" doc:"buy or sell"`
+	Score  decimal.Decimal `json:"score" doc:"How good the detector thinks it is, higher is better (only comparable within one detector)"`
+	Reason string          `json:"reason" doc:"Why the detector picked it"`
+	Order  APIOrder        `json:"order" doc:"The order"`
+}
+
+// APIDetectorResult is what one detector found in the current snapshot
+type APIDetectorResult struct {
+	Detector      string           `json:"detector" doc:"Detector name"`
+	Error         string           `json:"error,omitempty" doc:"Why the detector failed"`
+	Opportunities []APIOpportunity `json:"opportunities" doc:"What it found, best first"`
+}
+
+// APIOpportunities is what every enabled detector found in the current snapshot
+type APIOpportunities struct {
+	SnapshotVersion uint64              `json:"snapshot_version" doc:"Snapshot the detectors ran on"`
+	Detectors       []APIDetectorResult `json:"detectors" doc:"Every enabled detector, in the order they're configured"`
+}
+
+func NewAPIDetectorResult(result DetectorResult) APIDetectorResult {
+	apiResult := APIDetectorResult{
+		Detector:      result.Detector,
+		Error:         result.Error,
+		Opportunities: make([]APIOpportunity, 0, len(result.Opportunities)),
+	}
+
+	for _, opportunity := range result.Opportunities {
+		apiResult.Opportunities = append(apiResult.Opportunities, APIOpportunity{
+			Symbol: opportunity.Symbol,
+			Side:   opportunity.Side,
+			Score:  opportunity.Score,
+			Reason: opportunity.Reason,
+			Order:  NewAPIOrderWithSide(opportunity.Order, opportunity.Side),
+		})
+	}
+
+	return apiResult
+}
+
+// OpportunityRoutes are the detector results
+func OpportunityRoutes() []APIRoute {
+	return []APIRoute{
+		{
+			Path:           APIV1 + "/opportunities",
+			Summary:        "What every enabled opportunity detector found",
+			Description:    "Detectors are switched on in the config (detectors.enabled), the built in ones are " + strings.Join(DetectorNames(), ", ") + ".",
+			Response:       APIOpportunities{},
+			Scope:          ScopeReadPrices,
+			RateLimitClass: RateLimitDefault,
+			Cached:         true,
+			Handler:        handleOpportunities,
+		},
+		{
+			Path:    APIV1 + "/opportunities/{detector}",
+			Summary: "What one opportunity detector found",
+			Parameters: []APIParameter{
+				{Name: "detector", In: "path", Type: "string", Description: "Detector name, it has to be enabled"},
+			},
+			Response:       APIDetectorResult{},
+			Scope:          ScopeReadPrices,
+			RateLimitClass: RateLimitDefault,
+			Cached:         true,
+			Handler:        handleDetectorOpportunities,
+		},
+	}
+}
+
+func handleOpportunities(w http.ResponseWriter, r *http.Request) {
+	snapshot := CurrentSnapshot()
+
+	setSnapshotHeaders(w, snapshot)
+
+	output := APIOpportunities{SnapshotVersion: snapshot.Version, Detectors: make([]APIDetectorResult, 0, len(snapshot.Opportunities))}
+
+	for _, result := range snapshot.Opportunities {
+		output.Detectors = append(output.Detectors, NewAPIDetectorResult(result))
+	}
+
+	writeJSON(w, output)
+}
+
+func handleDetectorOpportunities(w http.ResponseWriter, r *http.Request) {
+	snapshot := CurrentSnapshot()
+
+	setSnapshotHeaders(w, snapshot)
+
+	for _, result := range snapshot.Opportunities {
+		if result.Detector == PathParam(r, "detector") {
+			writeJSON(w, NewAPIDetectorResult(result))
+			return
+		}
+	}
+
+	WriteAPIError(w, http.StatusNotFound, "no enabled detector called "+PathParam(r, "detector"))
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/CADawg/hive-swap-calculator/engine"
+	"github.com/CADawg/hive-swap-calculator/market"
+	"github.com/CADawg/hive-swap-calculator/pricing"
+	"github.com/goccy/go-json"
+	"github.com/shopspring/decimal"
+)
+
+func detectorOrder(txID string, account string, price int64, quantity int64, profit int64) engine.MarketOrder {
+	return engine.MarketOrder{
+		TransactionID:    txID,
+		Account:          account,
+		Symbol:           "SWAP.BTC",
+		Price:            decimal.NewFromInt(price),
+		Quantity:         decimal.NewFromInt(quantity),
+		ProfitPercentage: decimal.NewFromInt(profit),
+	}
+}
+
+// detectorSnapshot has BTC at 100 HIVE, with alice selling under it, bob buying over it and carol's orders not past it
+func detectorSnapshot() *Snapshot {
+	aliceSell := detectorOrder("alice-sell", "alice", 90, 1, 10)
+	bobBuy := detectorOrder("bob-buy", "bob", 120, 2, 20)
+	carolSell := detectorOrder("carol-sell", "carol", 105, 1, 0)
+	carolBuy := detectorOrder("carol-buy", "carol", 95, 1, 0)
+
+	return &Snapshot{
+		Tokens: []pricing.TokenData{
+			{
+				Symbol:     "BTC",
+				SwapSymbol: "SWAP.BTC",
+				HIVEPrice:  decimal.NewFromInt(100),
+				Network:    "Ethereum",
+				SellOrders: []engine.MarketOrder{aliceSell},
+				BuyOrders:  []engine.MarketOrder{bobBuy},
+			},
+			{Symbol: "ETH", SwapSymbol: "SWAP.ETH", HIVEPrice: decimal.NewFromInt(10)},
+		},
+		SellBook: []engine.MarketOrder{aliceSell, carolSell},
+		BuyBook:  []engine.MarketOrder{bobBuy, carolBuy},
+	}
+}
+
+func opportunityIDs(opportunities []Opportunity) []string {
+	ids := []string{}
+
+	for _, opportunity := range opportunities {
+		ids = append(ids, opportunity.Order.TransactionID)
+	}
+
+	return ids
+}
+
+func TestDetectors(t *testing.T) {
+	tests := []struct {
+		name     string
+		detector OpportunityDetector
+		want     []string
+	}{
+		{"past reference", PastReferenceDetector{}, []string{"alice-sell", "bob-buy"}},
+		{"min depth", MinDepthDetector{MinHiveValue: decimal.NewFromInt(100)}, []string{"bob-buy"}},
+		{"preferred network", PreferredNetworkDetector{Networks: []string{"Ethereum"}}, []string{"alice-sell", "bob-buy"}},
+		{"other network", PreferredNetworkDetector{Networks: []string{"Polygon (Matic)"}}, []string{}},
+		// carol's orders aren't past the reference price, so there's nothing in them
+		{"accounts", AccountsDetector{Accounts: []string{"alice", "carol"}}, []string{"alice-sell"}},
+		{"accounts on both sides", AccountsDetector{Accounts: []string{"bob", "carol"}}, []string{"bob-buy"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			opportunities, err := test.detector.Detect(detectorSnapshot())
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if ids := opportunityIDs(opportunities); !equalStrings(ids, test.want) {
+				t.Fatalf("found %v, want %v", ids, test.want)
+			}
+
+			for _, opportunity := range opportunities {
+				if !opportunity.Score.IsPositive() {
+					t.Fatalf("%s scored %s", opportunity.Order.TransactionID, opportunity.Score)
+				}
+			}
+		})
+	}
+}
+
+func TestMinDepthScore(t *testing.T) {
+	opportunities, _ := MinDepthDetector{}.Detect(detectorSnapshot())
+
+	// bob buys 2 at 20 over the reference
+	if len(opportunities) != 2 || !opportunities[1].Score.Equal(decimal.NewFromInt(40)) {
+		t.Fatalf("opportunities = %+v", opportunities)
+	}
+}
+
+type fixedDetector struct {
+	opportunities []Opportunity
+	err           error
+}
+
+func (d fixedDetector) Detect(snapshot *Snapshot) ([]Opportunity, error) {
+	return d.opportunities, d.err
+}
+
+func TestRunDetectors(t *testing.T) {
+	snapshot := detectorSnapshot()
+
+	opportunity := func(txID string, account string, score int64) Opportunity {
+		return Opportunity{Symbol: "BTC", Side: market.SideSell, Order: detectorOrder(txID, account, 90, 1, score), Score: decimal.NewFromInt(score)}
+	}
+
+	_ = RegisterDetector("test_fixed", func(config DetectorConfig) OpportunityDetector {
+		return fixedDetector{opportunities: []Opportunity{
+			opportunity("low", "alice", 1),
+			opportunity("high", "alice", 5),
+			opportunity("no-edge", "alice", 0),
+			opportunity("behind", "alice", -3),
+			opportunity("denied", "mallory", 9),
+			{Symbol: "NOPE", Order: detectorOrder("unknown-token", "alice", 90, 1, 9), Score: decimal.NewFromInt(9)},
+		}}
+	})
+
+	_ = RegisterDetector("test_failing", func(config DetectorConfig) OpportunityDetector {
+		return fixedDetector{opportunities: []Opportunity{opportunity("partial", "alice", 2)}, err: errors.New("broken")}
+	})
+
+	config := DetectorConfig{Enabled: []string{"test_fixed", "test_failing", DetectorPastReference}}
+	filter := market.OrderFilter{DenyAccounts: []string{"mallory"}}
+
+	results, err := RunDetectors(snapshot, config, filter, time.Now())
+
+	if err == nil {
+		t.Fatal("a failing detector wasn't reported")
+	}
+
+	if len(results) != 3 {
+		t.Fatalf("got %d results, want one for each enabled detector", len(results))
+	}
+
+	// best first, and only the ones with an edge that pass the filters
+	if ids := opportunityIDs(results[0].Opportunities); !equalStrings(ids, []string{"high", "low"}) {
+		t.Fatalf("test_fixed found %v", ids)
+	}
+
+	// a failing detector keeps what it found, and doesn't stop the others
+	if results[1].Error != "broken" || len(results[1].Opportunities) != 1 || len(results[2].Opportunities) != 2 {
+		t.Fatalf("results = %+v", results)
+	}
+
+	// the configured filters apply to the built in detectors too
+	results, _ = RunDetectors(snapshot, DetectorConfig{Enabled: []string{DetectorPastReference}}, market.OrderFilter{DenyAccounts: []string{"bob"}}, time.Now())
+
+	if ids := opportunityIDs(results[0].Opportunities); !equalStrings(ids, []string{"alice-sell"}) {
+		t.Fatalf("past_reference with bob denied found %v", ids)
+	}
+
+	results, _ = RunDetectors(snapshot, DetectorConfig{Enabled: []string{DetectorPastReference}}, market.OrderFilter{MinHiveValue: decimal.NewFromInt(100)}, time.Now())
+
+	if ids := opportunityIDs(results[0].Opportunities); !equalStrings(ids, []string{"bob-buy"}) {
+		t.Fatalf("past_reference with a min value found %v", ids)
+	}
+}
+
+func TestOpportunityHandlers(t *testing.T) {
+	snapshot := detectorSnapshot()
+	snapshot.Opportunities, _ = RunDetectors(snapshot, DetectorConfig{Enabled: []string{DetectorPastReference}}, market.OrderFilter{}, time.Now())
+
+	PublishSnapshot(snapshot)
+
+	mux, _, key := adminTestMux(t)
+
+	get := func(path string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", path, nil)
+		r.Header.Set(APIKeyHeader, key)
+
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+
+		return w
+	}
+
+	var all APIOpportunities
+
+	if err := json.Unmarshal(get("/api/v1/opportunities").Body.Bytes(), &all); err != nil {
+		t.Fatal(err)
+	}
+
+	if all.SnapshotVersion != snapshot.Version || len(all.Detectors) != 1 || len(all.Detectors[0].Opportunities) != 2 {
+		t.Fatalf("opportunities = %+v", all)
+	}
+
+	// bob's is 20% past, alice's 10%
+	if first := all.Detectors[0].Opportunities[0]; first.Order.TransactionID != "bob-buy" || first.Side != market.SideBuy {
+		t.Fatalf("best opportunity = %+v", first)
+	}
+
+	if code := get("/api/v1/opportunities/" + DetectorPastReference).Code; code != http.StatusOK {
+		t.Fatalf("one detector got %d", code)
+	}
+
+	if code := get("/api/v1/opportunities/" + DetectorAccounts).Code; code != http.StatusNotFound {
+		t.Fatalf("a detector that isn't enabled got %d", code)
+	}
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/CADawg/hive-swap-calculator/engine"
+	"github.com/CADawg/hive-swap-calculator/gateway"
+	"github.com/CADawg/hive-swap-calculator/pricing"
+	"github.com/goccy/go-json"
+	"github.com/shopspring/decimal"
+)
+
+// savedSnapshot is what's on disk, enough to serve something (and skip the wait for the withdrawal fees) straight after a restart.
+// The stage results are there too, so a stage that fails on the first refresh has something to fall back to.
+type savedSnapshot struct {
+	SavedAt               time.Time                   `json:"saved_at"`
+	Tokens                []pricing.TokenData         `json:"tokens"`
+	TokenNetworkDataStore []gateway.TokenNetworkData  `json:"token_network_data_store"`
+	Prices                []pricing.TokenData         `json:"prices,omitempty"`
+	HBDRate               decimal.Decimal             `json:"hbd_rate"`
+	Fees                  map[string]gateway.TokenFee `json:"fees,omitempty"`
+	SellBook              []engine.MarketOrder        `json:"sell_book,omitempty"`
+	BuyBook               []engine.MarketOrder        `json:"buy_book,omitempty"`
+}
+
+// SaveSnapshot writes the snapshot and the gateway tokens to the snapshot file, replacing it in one go so a crash
+// half way through can't leave a broken file. Errors are just logged, the next refresh will try again.
+func SaveSnapshot(snapshot *Snapshot) {
+	path := GetConfig().SnapshotFile
+
+	// a replay (or a simulation) isn't the market as it is now, it mustn't replace what's saved
+	if path == "" || GetConfig().Capture.Mode == CaptureReplay || GetConfig().Simulate.Enabled {
+		return
+	}
+
+	// some of it is still what's on disk, saving it again would make that look as new as the rest
+	if snapshot.Stale {
+		return
+	}
+
+	saved := savedSnapshot{
+		SavedAt:  snapshot.UpdatedAt,
+		Tokens:   snapshot.Tokens,
+		Prices:   snapshot.Prices,
+		HBDRate:  snapshot.HBDRate,
+		Fees:     snapshot.Fees,
+		SellBook: snapshot.SellBook,
+		BuyBook:  snapshot.BuyBook,
+	}
+
+	saved.TokenNetworkDataStore, _ = WithdrawalFees.Tokens()
+
+	err := writeSnapshotFile(path, saved)
+
+	if err != nil {
+		fmt.Println("error saving snapshot:", err)
+	}
+}
+
+func writeSnapshotFile(path string, snapshot savedSnapshot) error {
+	data, err := json.Marshal(snapshot)
+
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".snapshot-*")
+
+	if err != nil {
+		return err
+	}
+
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(data)
+
+	// make sure it's really on disk before it replaces the old one
+	if err == nil {
+		err = tmp.Sync()
+	}
+
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+// LoadSnapshot publishes the saved snapshot (marked stale) and restores the gateway tokens, so we have something
+// to serve while the first refresh runs. A missing file isn't an error, it's just a first start.
+func LoadSnapshot(path string) error {
+	if path == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(path)
+
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	var snapshot savedSnapshot
+
+	err = json.Unmarshal(data, &snapshot)
+
+	if err != nil {
+		return err
+	}
+
+	if len(snapshot.TokenNetworkDataStore) > 0 {
+		// the saved fees are good enough to start with, the fees stage refreshes them on its first pass
+		WithdrawalFees.Restore(snapshot.TokenNetworkDataStore)
+	}
+
+	// every stage's data is from disk, a refresh only clears that for the stages that work
+	var stages []StageStatus
+
+	for _, stage := range RefreshPipeline.Stages() {
+		stages = append(stages, StageStatus{Stage: stage, LastSuccess: snapshot.SavedAt, Stale: true})
+	}
+
+	PublishSnapshot(&Snapshot{
+		UpdatedAt: snapshot.SavedAt,
+		Stale:     true,
+		Stages:    stages,
+		Prices:    snapshot.Prices,
+		HBDRate:   snapshot.HBDRate,
+		Fees:      snapshot.Fees,
+		SellBook:  snapshot.SellBook,
+		BuyBook:   snapshot.BuyBook,
+		Tokens:    snapshot.Tokens,
+	})
+
+	fmt.Println("Loaded snapshot from", snapshot.SavedAt.Format(time.RFC3339), "(serving it as stale until every stage has refreshed)")
+
+	return nil
+}
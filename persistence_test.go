@@ -0,0 +1,154 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/CADawg/hive-swap-calculator/gateway"
+	"github.com/shopspring/decimal"
+)
+
+func TestSaveAndLoadSnapshot(t *testing.T) {
+	previous := GetConfig()
+	defer SetConfig(previous)
+
+	previousFees := WithdrawalFees
+	defer func() { WithdrawalFees = previousFees }()
+
+	path := filepath.Join(t.TempDir(), "snapshot.json")
+
+	config := DefaultConfig()
+	config.SnapshotFile = path
+	SetConfig(config)
+
+	saved := gateway.TokenNetworkData{HiveEngineSymbol: "SWAP.BTC", Network: "Ethereum", FixedFee: decimal.RequireFromString("0.5"), FeeLookedUp: true}
+
+	WithdrawalFees = gateway.NewStore(nil)
+	WithdrawalFees.Restore([]gateway.TokenNetworkData{saved})
+
+	snapshot := &Snapshot{
+		UpdatedAt: time.Unix(1_700_000_000, 0),
+		Tokens:    grpcTestTokens(),
+		HBDRate:   decimal.RequireFromString("3.5"),
+		Fees:      map[string]gateway.TokenFee{"BTC": {FlatFee: decimal.NewFromInt(1)}},
+	}
+
+	SaveSnapshot(snapshot)
+
+	// a restart, with nothing looked up yet
+	WithdrawalFees = gateway.NewStore(nil)
+
+	if err := LoadSnapshot(path); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded := CurrentSnapshot()
+
+	if !loaded.Stale || !loaded.UpdatedAt.Equal(snapshot.UpdatedAt) || !loaded.HBDRate.Equal(snapshot.HBDRate) {
+		t.Fatalf("loaded %+v", loaded)
+	}
+
+	if len(loaded.Tokens) != 1 || len(loaded.Tokens[0].BuyOrders) != 1 || !loaded.Fees["BTC"].FlatFee.Equal(decimal.NewFromInt(1)) {
+		t.Fatalf("loaded tokens %+v and fees %+v", loaded.Tokens, loaded.Fees)
+	}
+
+	if _, ok := loaded.Index.Token("BTC"); !ok {
+		t.Fatal("the loaded snapshot wasn't indexed")
+	}
+
+	// every stage's data is from disk until it works again
+	if len(loaded.Stages) != len(RefreshPipeline.Stages()) {
+		t.Fatalf("loaded stages %+v", loaded.Stages)
+	}
+
+	for _, stage := range loaded.Stages {
+		if !stage.Stale || !stage.LastSuccess.Equal(snapshot.UpdatedAt) {
+			t.Fatalf("loaded stage %+v", stage)
+		}
+	}
+
+	// saving it again would make the data from disk look new
+	before, err := os.ReadFile(path)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	SaveSnapshot(&Snapshot{UpdatedAt: time.Now(), Stale: true, Tokens: loaded.Tokens})
+
+	if after, _ := os.ReadFile(path); string(after) != string(before) {
+		t.Fatal("a stale snapshot was saved")
+	}
+
+	tokens, ready := WithdrawalFees.Tokens()
+
+	if !ready || len(tokens) != 1 || !tokens[0].FixedFee.Equal(saved.FixedFee) || !tokens[0].FeeLookedUp {
+		t.Fatalf("gateway tokens came back as %+v (ready %v)", tokens, ready)
+	}
+
+	// no temporary files are left behind
+	entries, err := os.ReadDir(filepath.Dir(path))
+
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("snapshot dir has %d entries (%v)", len(entries), err)
+	}
+}
+
+func TestSaveSnapshotSkipsReplays(t *testing.T) {
+	previous := GetConfig()
+	defer SetConfig(previous)
+
+	path := filepath.Join(t.TempDir(), "snapshot.json")
+
+	config := DefaultConfig()
+	config.SnapshotFile = path
+	config.Capture = CaptureConfig{Mode: CaptureReplay, Dir: t.TempDir()}
+	SetConfig(config)
+
+	SaveSnapshot(&Snapshot{Tokens: grpcTestTokens()})
+
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("a replay was saved (%v)", err)
+	}
+
+	config.Capture = CaptureConfig{}
+	config.Simulate.Enabled = true
+	SetConfig(config)
+
+	SaveSnapshot(&Snapshot{Tokens: grpcTestTokens()})
+
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("a simulation was saved (%v)", err)
+	}
+}
+
+func TestLoadSnapshotFile(t *testing.T) {
+	dir := t.TempDir()
+
+	// nothing saved yet is just a first start
+	if err := LoadSnapshot(filepath.Join(dir, "missing.json")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := LoadSnapshot(""); err != nil {
+		t.Fatal(err)
+	}
+
+	broken := filepath.Join(dir, "broken.json")
+
+	if err := os.WriteFile(broken, []byte(`{"tokens": [`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	version := CurrentSnapshot().Version
+
+	if err := LoadSnapshot(broken); err == nil {
+		t.Fatal("loaded a broken snapshot")
+	}
+
+	if CurrentSnapshot().Version != version {
+		t.Fatal("a broken snapshot was published")
+	}
+}
@@ -0,0 +1,650 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/CADawg/hive-swap-calculator/gateway"
+	"github.com/CADawg/hive-swap-calculator/market"
+	"github.com/CADawg/hive-swap-calculator/pricing"
+	"github.com/CADawg/hive-swap-calculator/upstream"
+)
+
+// the stages of a refresh, each one falls back to its result in the previous snapshot so one failing doesn't take the others down with it
+const (
+	StageHBDRate  = "hbd_rate"
+	StageSellBook = "sell_book"
+	StageBuyBook  = "buy_book"
+	// StageTokens puts the other stages' results together into the tokens we serve
+	StageTokens = "tokens"
+)
+
+// StageStatus is how a stage did on its last attempt, and how old the data we're using from it is
+type StageStatus struct {
+	Stage       string
+	LastSuccess time.Time
+	// Error is from the last attempt, empty if it worked
+	Error string
+	// Stale is set while the stage's data is still from the snapshot loaded from disk at startup (it, or a stage it
+	// depends on, hasn't worked since)
+	Stale bool
+}
+
+// Degraded means the last attempt failed, so the snapshot has older data from this stage (or none at all)
+func (s StageStatus) Degraded() bool {
+	return s.Error != ""
+}
+
+// stage is the status of a stage in this snapshot
+func (s *Snapshot) stage(stage string) (StageStatus, bool) {
+	for _, status := range s.Stages {
+		if status.Stage == stage {
+			return status, true
+		}
+	}
+
+	return StageStatus{}, false
+}
+
+// PipelineStage is one step of building a snapshot
+type PipelineStage struct {
+	Name string
+	// Source is the scheduled source the stage fetches, it's skipped in cycles where that source isn't due. Stages
+	// without one (that work on what the others fetched) run every cycle.
+	Source string
+	// DependsOn are the stages that have to finish before this one starts, anything else runs at the same time
+	DependsOn []string
+	// Required stages fail the whole cycle if they fail, there's nothing worth publishing without them
+	Required bool
+	// Run fills in the stage's part of next, which starts out with everything from the previous snapshot (so leaving
+	// it alone on an error falls back to the last good result). Stages can run at the same time, so each one must only
+	// write its own fields, and only read the fields of stages it depends on.
+	Run func(next *Snapshot) error
+}
+
+// StageReport is how one stage went in one cycle
+type StageReport struct {
+	Stage     string
+	Source    string
+	DependsOn []string
+	// Skipped is set when the stage's source wasn't due, it kept its result from the previous snapshot
+	Skipped bool
+	// Started is how far into the cycle the stage started (after waiting for its dependencies)
+	Started     time.Duration
+	Duration    time.Duration
+	Error       string
+	RateLimited bool
+	// DependencyFailed is set when the stage failed after a stage it depends on failed in the same cycle, so the
+	// error probably isn't its own source's fault
+	DependencyFailed bool
+}
+
+// CycleReport is how one run of the pipeline went, stage by stage
+type CycleReport struct {
+	// Cycle counts up from 1 each run, captures are tagged with it
+	Cycle     uint64
+	StartedAt time.Time
+	Duration  time.Duration
+	// Error is why nothing was published, empty if the cycle worked
+	Error  string
+	Stages []StageReport
+}
+
+// SourceResult is how a source's stages did in the cycle, for the scheduler
+func (r *CycleReport) SourceResult(source string) SourceResult {
+	var result SourceResult
+
+	for _, stage := range r.Stages {
+		if stage.Source == source && stage.Error != "" && !stage.DependencyFailed {
+			result.Failed = true
+			result.RateLimited = result.RateLimited || stage.RateLimited
+		}
+	}
+
+	return result
+}
+
+// Pipeline runs stages in dependency order to build each snapshot
+type Pipeline struct {
+	stages []PipelineStage
+	cycles atomic.Uint64
+
+	reportLock sync.RWMutex
+	lastReport *CycleReport
+	// failing are the sources that failed the last time they were polled, with why
+	failing map[string]string
+}
+
+func NewPipeline() *Pipeline {
+	return &Pipeline{failing: map[string]string{}}
+}
+
+// Register adds a stage to the end of the pipeline. Everything it depends on has to be registered first, so there
+// can't be a cycle.
+func (p *Pipeline) Register(stage PipelineStage) error {
+	if stage.Name == "" || stage.Run == nil {
+		return errors.New("stages need a name and a run function")
+	}
+
+	for _, existing := range p.stages {
+		if existing.Name == stage.Name {
+			return errors.New("stage " + stage.Name + " is already registered")
+		}
+	}
+
+	for _, dependency := range stage.DependsOn {
+		if !market.ContainsString(p.Stages(), dependency) {
+			return errors.New("stage " + stage.Name + " depends on " + dependency + ", which isn't registered (yet)")
+		}
+	}
+
+	p.stages = append(p.stages, stage)
+
+	return nil
+}
+
+// Stages are the names of every stage, in the order they were registered
+func (p *Pipeline) Stages() []string {
+	var names []string
+
+	for _, stage := range p.stages {
+		names = append(names, stage.Name)
+	}
+
+	return names
+}
+
+// LastReport is how the last cycle went, nil before the first one
+func (p *Pipeline) LastReport() *CycleReport {
+	p.reportLock.RLock()
+	defer p.reportLock.RUnlock()
+
+	return p.lastReport
+}
+
+// Run builds the next snapshot with every stage, see RunSources
+func (p *Pipeline) Run(previous *Snapshot) (*Snapshot, error) {
+	next, _, err := p.RunSources(previous, AllSources)
+
+	return next, err
+}
+
+// RunSources builds the next snapshot, polling only the given sources (the rest keep their result from previous).
+// Each stage starts as soon as the ones it depends on are done. A stage that fails falls back to its result in
+// previous, unless it's required, in which case the whole cycle fails. The new snapshot isn't published, that's up to
+// the caller.
+func (p *Pipeline) RunSources(previous *Snapshot, sources []string) (*Snapshot, *CycleReport, error) {
+	return p.RunSourcesAt(previous, sources, time.Time{})
+}
+
+// RunSourcesAt is RunSources with clock as the snapshot's Clock (zero for the real time), replays pass the time the
+// cycle was recorded
+func (p *Pipeline) RunSourcesAt(previous *Snapshot, sources []string, clock time.Time) (*Snapshot, *CycleReport, error) {
+	started := time.Now()
+	cycle := p.cycles.Add(1)
+
+	captureCycleStarted(cycle, sources)
+
+	next := &Snapshot{
+		UpdatedAt: started,
+		Clock:     clock,
+		Prices:    previous.Prices,
+		HBDRate:   previous.HBDRate,
+		Fees:      previous.Fees,
+		SellBook:  previous.SellBook,
+		BuyBook:   previous.BuyBook,
+	}
+
+	done := map[string]chan struct{}{}
+	index := map[string]int{}
+
+	for i, stage := range p.stages {
+		done[stage.Name] = make(chan struct{})
+		index[stage.Name] = i
+	}
+
+	// each stage only writes its own report (and the channels make sure its dependencies are finished with next)
+	reports := make([]StageReport, len(p.stages))
+
+	var wg sync.WaitGroup
+
+	for i, stage := range p.stages {
+		wg.Add(1)
+
+		go func(i int, stage PipelineStage) {
+			defer wg.Done()
+			defer close(done[stage.Name])
+
+			reports[i] = StageReport{Stage: stage.Name, Source: stage.Source, DependsOn: stage.DependsOn}
+
+			if stage.Source != "" && !market.ContainsString(sources, stage.Source) {
+				reports[i].Skipped = true
+				return
+			}
+
+			for _, dependency := range stage.DependsOn {
+				<-done[dependency]
+			}
+
+			stageStarted := time.Now()
+
+			err := stage.Run(next)
+
+			reports[i].Started = stageStarted.Sub(started)
+			reports[i].Duration = time.Since(stageStarted)
+
+			if err != nil {
+				fmt.Println("error in stage "+stage.Name+":", err)
+
+				reports[i].Error = err.Error()
+				reports[i].RateLimited = errors.Is(err, upstream.ErrRateLimited)
+
+				// the dependencies are done, so their reports are safe to read
+				for _, dependency := range stage.DependsOn {
+					reports[i].DependencyFailed = reports[i].DependencyFailed || reports[index[dependency]].Error != ""
+				}
+			}
+		}(i, stage)
+	}
+
+	wg.Wait()
+
+	report := &CycleReport{Cycle: cycle, StartedAt: started, Duration: time.Since(started), Stages: reports}
+
+	var failed []string
+
+	// by stage, the dependencies come first so their statuses are always in here by the time they're needed
+	stale := map[string]bool{}
+
+	for i, stage := range p.stages {
+		status := StageStatus{Stage: stage.Name, LastSuccess: started.Add(reports[i].Started + reports[i].Duration)}
+
+		// fresh work on data that's still from disk is only as fresh as that data
+		for _, dependency := range stage.DependsOn {
+			status.Stale = status.Stale || stale[dependency]
+		}
+
+		if reports[i].Skipped {
+			// nothing's changed since the previous snapshot
+			status, _ = previous.stage(stage.Name)
+			status.Stage = stage.Name
+		} else if reports[i].Error != "" {
+			// the data from this stage is as old as its last success in the previous snapshot
+			status, _ = previous.stage(stage.Name)
+			status.Stage = stage.Name
+			status.Error = reports[i].Error
+
+			if stage.Required {
+				failed = append(failed, stage.Name+": "+reports[i].Error)
+			}
+		}
+
+		stale[stage.Name] = status.Stale
+		next.Stale = next.Stale || status.Stale
+
+		next.Stages = append(next.Stages, status)
+	}
+
+	var err error
+
+	if len(failed) > 0 {
+		err = errors.New("required stages failed (" + strings.Join(failed, ", ") + ")")
+		report.Error = err.Error()
+	}
+
+	p.reportLock.Lock()
+	p.lastReport = report
+	upstreamEvents := p.upstreamEvents(report, sources)
+	p.reportLock.Unlock()
+
+	Events.Publish(upstreamEvents...)
+
+	fmt.Println("Refresh of "+strings.Join(sources, ", ")+" took", report.Duration)
+
+	if err != nil {
+		return nil, report, err
+	}
+
+	return next, report, nil
+}
+
+// upstreamEvents are the sources polled in report that have started failing or recovered. A stage that failed after
+// one it depends on did doesn't count either way, we can't tell if its own source is working. Call it with reportLock
+// held.
+func (p *Pipeline) upstreamEvents(report *CycleReport, sources []string) []Event {
+	var events []Event
+
+	for _, source := range sources {
+		var failure string
+		var unknown bool
+
+		for _, stage := range report.Stages {
+			if stage.Source != source || stage.Error == "" {
+				continue
+			}
+
+			if stage.DependencyFailed {
+				unknown = true
+			} else if failure == "" {
+				failure = stage.Stage + ": " + stage.Error
+			}
+		}
+
+		_, wasFailing := p.failing[source]
+
+		switch {
+		case failure == "" && unknown:
+			// leave it as it was until we hear from the source itself
+		case failure != "" && !wasFailing:
+			p.failing[source] = failure
+			events = append(events, Event{Type: EventUpstreamFailed, Source: source, Error: failure})
+		case failure == "" && wasFailing:
+			delete(p.failing, source)
+			events = append(events, Event{Type: EventUpstreamRecovered, Source: source})
+		}
+	}
+
+	return events
+}
+
+// RefreshPipeline builds every snapshot we serve (other than ones followed from a primary)
+var RefreshPipeline = NewRefreshPipeline()
+
+// NewRefreshPipeline has the built in stages, the upstream fetches run at the same time
+func NewRefreshPipeline() *Pipeline {
+	pipeline := NewPipeline()
+
+	stages := []PipelineStage{
+		{Name: StagePrices, Source: SourcePrices, Run: runPricesStage},
+		{Name: StageHBDRate, Source: SourceHBDRate, Run: runHBDRateStage},
+		// the withdrawal fees are priced in ETH, BNB and MATIC
+		{Name: StageFees, Source: SourceFees, DependsOn: []string{StagePrices}, Run: runFeesStage},
+		{Name: StageSellBook, Source: SourceBooks, Run: runSellBookStage},
+		{Name: StageBuyBook, Source: SourceBooks, Run: runBuyBookStage},
+		{Name: StageTokens, DependsOn: []string{StagePrices, StageHBDRate, StageFees, StageSellBook, StageBuyBook}, Required: true, Run: runTokensStage},
+		{Name: StageOpportunities, DependsOn: []string{StageTokens}, Run: runOpportunitiesStage},
+	}
+
+	for _, stage := range stages {
+		if err := pipeline.Register(stage); err != nil {
+			panic("error registering refresh stage: " + err.Error())
+		}
+	}
+
+	return pipeline
+}
+
+// Refresh builds the next snapshot from the upstreams with the RefreshPipeline. It only fails if we've never managed
+// to get prices (there's nothing to build on).
+func Refresh(previous *Snapshot) (*Snapshot, error) {
+	return RefreshPipeline.Run(previous)
+}
+
+func runPricesStage(next *Snapshot) error {
+	prices, err := CoinGecko.LoadPriceAndSymbolData(GetConfig().Tokens)
+
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("Loaded price and symbol data")
+
+	next.Prices = prices
+
+	return nil
+}
+
+func runHBDRateStage(next *Snapshot) error {
+	rate, err := Hive.FetchBlockchainHiveHBDRate()
+
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("Fetched blockchain hive/hbd rate", rate)
+
+	next.HBDRate = rate
+
+	return nil
+}
+
+func runFeesStage(next *Snapshot) error {
+	err := WithdrawalFees.UpdateWithdrawalFees(pricing.HivePrices(next.Prices))
+
+	if err != nil {
+		return err
+	}
+
+	var symbols []string
+
+	for _, token := range next.Prices {
+		symbols = append(symbols, token.Symbol)
+	}
+
+	config := GetConfig().Fees
+
+	fees, err := WithdrawalFees.Fees(symbols, config.PercentageFee, config.GatewayPercentageFee)
+
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("Added network fee data")
+
+	next.Fees = fees
+
+	return nil
+}
+
+func runSellBookStage(next *Snapshot) error {
+	sellBook, err := Engine.GetAllSwapSellOrders()
+
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("Loaded the sell book")
+
+	next.SellBook = sellBook
+
+	return nil
+}
+
+func runBuyBookStage(next *Snapshot) error {
+	buyBook, err := Engine.GetAllSwapBuyOrders()
+
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("Loaded the buy book")
+
+	next.BuyBook = buyBook
+
+	return nil
+}
+
+func runTokensStage(next *Snapshot) error {
+	if next.Prices == nil {
+		return errors.New("no prices yet")
+	}
+
+	// the prices are shared with the previous snapshot, so work on a copy
+	data := append([]pricing.TokenData{}, next.Prices...)
+
+	if !next.HBDRate.IsZero() {
+		for i := range data {
+			if data[i].Symbol == "HBD" {
+				data[i].HIVEPrice = next.HBDRate // update the hive/hbd rate using our blockchain data (this is more accurate than coingecko for most people and has no delay)
+			}
+		}
+	}
+
+	// Prevent data with wrong fee info from reaching the site: nothing until the fees have worked once, and then only
+	// the tokens we know the fee of
+	if next.Fees == nil {
+		return errors.New("no fees yet")
+	}
+
+	data = withKnownFees(data, next.Fees)
+	data = market.ApplyNetworkFees(data, next.Fees, GetConfig().Fees.PercentageFee)
+	data = market.GetUnderpricedMarketSellOrders(data, next.SellBook)
+	data = market.GetUnderpricedMarketBuyOrders(data, next.BuyBook)
+
+	PrettyPrintTokenData(data)
+
+	next.Tokens = data
+	next.Index = NewTokenIndex(data)
+
+	return nil
+}
+
+// withKnownFees drops the tokens that aren't in fees (gateway tokens whose withdrawal fee hasn't been looked up yet)
+func withKnownFees(data []pricing.TokenData, fees map[string]gateway.TokenFee) []pricing.TokenData {
+	known := make([]pricing.TokenData, 0, len(data))
+
+	for _, token := range data {
+		if _, ok := fees[token.Symbol]; !ok {
+			fmt.Println("Leaving out", token.Symbol, "until its withdrawal fee has been looked up")
+			continue
+		}
+
+		known = append(known, token)
+	}
+
+	return known
+}
+
+// APIStageStatus is how fresh one stage of the current snapshot is
+type APIStageStatus struct {
+	Stage         string `json:"stage" doc:"prices, hbd_rate, fees, sell_book, buy_book, tokens or opportunities"`
+	Degraded      bool   `json:"degraded" doc:"The stage failed last time, so the snapshot has older data from it (or none, if it's never worked)"`
+	LastSuccessAt int64  `json:"last_success_at" doc:"Unix time the stage last worked, 0 if it never has"`
+	AgeSeconds    int64  `json:"age_seconds" doc:"Seconds since the stage last worked, -1 if it never has"`
+	Error         string `json:"error,omitempty" doc:"Why the last attempt failed"`
+	Stale         bool   `json:"stale" doc:"The stage's data is still from the snapshot loaded from disk after a restart"`
+}
+
+// APIStageReport is how one stage went in the last refresh cycle
+type APIStageReport struct {
+	Stage        string   `json:"stage" doc:"Stage name"`
+	Source       string   `json:"source,omitempty" doc:"Source the stage polls, left out for stages that run every cycle"`
+	DependsOn    []string `json:"depends_on" doc:"Stages it waited for"`
+	Skipped      bool     `json:"skipped" doc:"Its source wasn't due, so it kept the previous result"`
+	StartedAfter int64    `json:"started_after_ms" doc:"Milliseconds into the cycle the stage started"`
+	Duration     int64    `json:"duration_ms" doc:"Milliseconds the stage took"`
+	Error        string   `json:"error,omitempty" doc:"Why the stage failed"`
+	RateLimited  bool     `json:"rate_limited" doc:"The upstream said we're asking too often"`
+}
+
+// APISourceSchedule is when a source will next be polled
+type APISourceSchedule struct {
+	Source    string `json:"source" doc:"prices, hbd_rate, books or fees"`
+	NextRunAt int64  `json:"next_run_at" doc:"Unix time it's next due, 0 if it's due now"`
+	Failures  int    `json:"failures" doc:"Polls in a row that have failed (a rate limit counts twice), each one doubles the wait"`
+	Fast      bool   `json:"fast" doc:"It's being polled at its fast interval because there's an opportunity open"`
+}
+
+// APICycleReport is how the last refresh cycle went
+type APICycleReport struct {
+	Cycle     uint64           `json:"cycle" doc:"Cycle number, counting from 1 since the server started (captures are tagged with it)"`
+	StartedAt int64            `json:"started_at" doc:"Unix time the cycle started"`
+	Duration  int64            `json:"duration_ms" doc:"Milliseconds the whole cycle took"`
+	Error     string           `json:"error,omitempty" doc:"Why nothing was published (a required stage failed)"`
+	Stages    []APIStageReport `json:"stages" doc:"Every stage, in the order they were registered"`
+}
+
+// APIStatus is how fresh the current snapshot is, stage by stage
+type APIStatus struct {
+	SnapshotVersion uint64              `json:"snapshot_version" doc:"Current snapshot version"`
+	UpdatedAt       int64               `json:"updated_at" doc:"Unix time the snapshot was published"`
+	Stale           bool                `json:"stale" doc:"Some of the snapshot is still from the one loaded from disk after a restart (the stages that haven't worked since)"`
+	Degraded        bool                `json:"degraded" doc:"At least one stage is degraded"`
+	Stages          []APIStageStatus    `json:"stages" doc:"Every stage, in the order they run"`
+	LastCycle       *APICycleReport     `json:"last_cycle,omitempty" doc:"Timings of the last refresh cycle, left out if we haven't run one (a replica following its primary)"`
+	Schedule        []APISourceSchedule `json:"schedule" doc:"When each source will next be polled"`
+}
+
+func NewAPIStageStatuses(statuses []StageStatus, now time.Time) []APIStageStatus {
+	apiStatuses := make([]APIStageStatus, 0, len(statuses))
+
+	for _, status := range statuses {
+		apiStatus := APIStageStatus{Stage: status.Stage, Degraded: status.Degraded(), AgeSeconds: -1, Error: status.Error, Stale: status.Stale}
+
+		if !status.LastSuccess.IsZero() {
+			apiStatus.LastSuccessAt = status.LastSuccess.Unix()
+			apiStatus.AgeSeconds = int64(now.Sub(status.LastSuccess).Seconds())
+		}
+
+		apiStatuses = append(apiStatuses, apiStatus)
+	}
+
+	return apiStatuses
+}
+
+func NewAPICycleReport(report *CycleReport) *APICycleReport {
+	if report == nil {
+		return nil
+	}
+
+	apiReport := &APICycleReport{
+		Cycle:     report.Cycle,
+		StartedAt: report.StartedAt.Unix(),
+		Duration:  report.Duration.Milliseconds(),
+		Error:     report.Error,
+		Stages:    make([]APIStageReport, 0, len(report.Stages)),
+	}
+
+	for _, stage := range report.Stages {
+		apiReport.Stages = append(apiReport.Stages, APIStageReport{
+			Stage:        stage.Stage,
+			Source:       stage.Source,
+			DependsOn:    append([]string{}, stage.DependsOn...),
+			Skipped:      stage.Skipped,
+			StartedAfter: stage.Started.Milliseconds(),
+			Duration:     stage.Duration.Milliseconds(),
+			Error:        stage.Error,
+			RateLimited:  stage.RateLimited,
+		})
+	}
+
+	return apiReport
+}
+
+func handleStatus(w http.ResponseWriter, r *http.Request) {
+	snapshot := CurrentSnapshot()
+
+	status := APIStatus{
+		SnapshotVersion: snapshot.Version,
+		Stale:           snapshot.Stale,
+		Stages:          NewAPIStageStatuses(snapshot.Stages, time.Now()),
+		LastCycle:       NewAPICycleReport(RefreshPipeline.LastReport()),
+	}
+
+	if !snapshot.UpdatedAt.IsZero() {
+		status.UpdatedAt = snapshot.UpdatedAt.Unix()
+	}
+
+	for _, source := range RefreshScheduler.Statuses() {
+		schedule := APISourceSchedule{Source: source.Source, Failures: source.Failures, Fast: source.Fast}
+
+		if !source.NextRun.IsZero() {
+			schedule.NextRunAt = source.NextRun.Unix()
+		}
+
+		status.Schedule = append(status.Schedule, schedule)
+	}
+
+	for _, stage := range status.Stages {
+		status.Degraded = status.Degraded || stage.Degraded
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+
+	writeJSON(w, status)
+}
@@ -0,0 +1,342 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/CADawg/hive-swap-calculator/gateway"
+	"github.com/CADawg/hive-swap-calculator/pricing"
+	"github.com/CADawg/hive-swap-calculator/upstream"
+	"github.com/shopspring/decimal"
+)
+
+func TestTokensStageNeedsKnownFees(t *testing.T) {
+	next := &Snapshot{Prices: []pricing.TokenData{
+		{Symbol: "HIVE", HIVEPrice: decimal.NewFromInt(1)},
+		{Symbol: "BTC", HIVEPrice: decimal.NewFromInt(100000)},
+	}}
+
+	// nothing until the fees have worked at least once
+	if err := runTokensStage(next); err == nil {
+		t.Fatal("built tokens without any fees")
+	}
+
+	// BTC goes through a gateway whose fee hasn't been looked up, so it's left out rather than served with no flat fee
+	next.Fees = map[string]gateway.TokenFee{"HIVE": {PercentageFee: decimal.NewFromInt(1)}}
+
+	if err := runTokensStage(next); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(next.Tokens) != 1 || next.Tokens[0].Symbol != "HIVE" || !next.Tokens[0].NetworkPercentageFee.Equal(decimal.NewFromInt(1)) {
+		t.Fatalf("tokens = %+v", next.Tokens)
+	}
+
+	// the prices are left alone
+	if len(next.Prices) != 2 {
+		t.Fatal("the tokens stage changed the prices")
+	}
+}
+
+func TestPipelineRegister(t *testing.T) {
+	pipeline := NewPipeline()
+	run := func(next *Snapshot) error { return nil }
+
+	if err := pipeline.Register(PipelineStage{Name: "a"}); err == nil {
+		t.Fatal("registered a stage without a run function")
+	}
+
+	if err := pipeline.Register(PipelineStage{Name: "b", DependsOn: []string{"a"}, Run: run}); err == nil {
+		t.Fatal("registered a stage before what it depends on")
+	}
+
+	if err := pipeline.Register(PipelineStage{Name: "a", Run: run}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := pipeline.Register(PipelineStage{Name: "a", Run: run}); err == nil {
+		t.Fatal("registered the same stage twice")
+	}
+
+	if err := pipeline.Register(PipelineStage{Name: "b", DependsOn: []string{"a"}, Run: run}); err != nil {
+		t.Fatal(err)
+	}
+
+	if stages := pipeline.Stages(); len(stages) != 2 || stages[0] != "a" || stages[1] != "b" {
+		t.Fatalf("Stages() = %v", stages)
+	}
+}
+
+func TestPipelineRunsDependenciesFirst(t *testing.T) {
+	pipeline := NewPipeline()
+
+	var lock sync.Mutex
+	var order []string
+
+	stage := func(name string, delay time.Duration) func(next *Snapshot) error {
+		return func(next *Snapshot) error {
+			time.Sleep(delay)
+
+			lock.Lock()
+			order = append(order, name)
+			lock.Unlock()
+
+			return nil
+		}
+	}
+
+	// the slow stage is registered first but the one after it doesn't wait for it
+	for _, registered := range []PipelineStage{
+		{Name: "slow", Run: stage("slow", 50*time.Millisecond)},
+		{Name: "fast", Run: stage("fast", 0)},
+		{Name: "after-fast", DependsOn: []string{"fast"}, Run: stage("after-fast", 0)},
+		{Name: "after-both", DependsOn: []string{"slow", "after-fast"}, Run: stage("after-both", 0)},
+	} {
+		if err := pipeline.Register(registered); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	_, report, err := pipeline.RunSources(&Snapshot{}, AllSources)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if fmt.Sprint(order) != "[fast after-fast slow after-both]" {
+		t.Fatalf("stages ran in the order %v", order)
+	}
+
+	if report.Stages[3].Started < 50*time.Millisecond {
+		t.Fatalf("after-both started %s in, before slow finished", report.Stages[3].Started)
+	}
+
+	if pipeline.LastReport() != report {
+		t.Fatal("the report wasn't kept")
+	}
+}
+
+func TestPipelineFailuresAndSkips(t *testing.T) {
+	pipeline := NewPipeline()
+	hbdRate := decimal.NewFromInt(3)
+
+	failPrices := false
+
+	stages := []PipelineStage{
+		{Name: "prices", Source: SourcePrices, Run: func(next *Snapshot) error {
+			if failPrices {
+				return fmt.Errorf("coingecko: %w", upstream.ErrRateLimited)
+			}
+
+			next.Prices = []pricing.TokenData{{Symbol: "HIVE"}}
+
+			return nil
+		}},
+		{Name: "hbd_rate", Source: SourceHBDRate, Run: func(next *Snapshot) error {
+			next.HBDRate = hbdRate
+			return nil
+		}},
+		{Name: "tokens", DependsOn: []string{"prices", "hbd_rate"}, Required: true, Run: func(next *Snapshot) error {
+			if next.Prices == nil {
+				return errors.New("no prices yet")
+			}
+
+			next.Tokens = next.Prices
+
+			return nil
+		}},
+	}
+
+	for _, stage := range stages {
+		if err := pipeline.Register(stage); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// a required stage failing fails the cycle
+	failPrices = true
+
+	_, report, err := pipeline.RunSources(&Snapshot{}, AllSources)
+
+	if err == nil || report.Error == "" {
+		t.Fatal("the cycle worked without prices")
+	}
+
+	if result := report.SourceResult(SourcePrices); !result.Failed || !result.RateLimited {
+		t.Fatalf("prices result = %+v", result)
+	}
+
+	failPrices = false
+
+	first, _, err := pipeline.RunSources(&Snapshot{}, AllSources)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a failing stage that isn't required falls back to the previous result, and says so
+	failPrices = true
+	hbdRate = decimal.NewFromInt(4)
+
+	second, report, err := pipeline.RunSources(first, AllSources)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	status, _ := second.stage("prices")
+	previousStatus, _ := first.stage("prices")
+
+	if len(second.Tokens) != 1 || !status.Degraded() || !status.LastSuccess.Equal(previousStatus.LastSuccess) || !second.HBDRate.Equal(hbdRate) {
+		t.Fatalf("second snapshot = %+v", second)
+	}
+
+	// a source that isn't due is skipped, keeping its result and status
+	failPrices = false
+	hbdRate = decimal.NewFromInt(5)
+
+	third, report, err := pipeline.RunSources(second, []string{SourcePrices})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !report.Stages[1].Skipped || !third.HBDRate.Equal(decimal.NewFromInt(4)) {
+		t.Fatalf("hbd_rate wasn't skipped: %+v", report.Stages[1])
+	}
+
+	if status, _ = third.stage("hbd_rate"); status != second.Stages[1] {
+		t.Fatalf("skipped stage status = %+v, was %+v", status, second.Stages[1])
+	}
+
+	if status, _ = third.stage("prices"); status.Degraded() {
+		t.Fatal("prices is still degraded after working")
+	}
+
+	// stages without a source run every cycle
+	if report.Stages[2].Skipped {
+		t.Fatal("the tokens stage was skipped")
+	}
+}
+
+func TestPipelineDependencyFailed(t *testing.T) {
+	pipeline := NewPipeline()
+
+	stages := []PipelineStage{
+		{Name: "prices", Source: SourcePrices, Run: func(next *Snapshot) error { return errors.New("down") }},
+		{Name: "fees", Source: SourceFees, DependsOn: []string{"prices"}, Run: func(next *Snapshot) error {
+			return errors.New("no prices")
+		}},
+	}
+
+	for _, stage := range stages {
+		if err := pipeline.Register(stage); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	_, report, err := pipeline.RunSources(&Snapshot{}, AllSources)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if report.Stages[0].DependencyFailed || !report.Stages[1].DependencyFailed {
+		t.Fatalf("stages = %+v", report.Stages)
+	}
+
+	if report.SourceResult(SourceFees).Failed || !report.SourceResult(SourcePrices).Failed {
+		t.Fatal("the failure was put on the wrong source")
+	}
+}
+
+func TestPipelineFallbackToDiskIsStale(t *testing.T) {
+	pipeline := NewPipeline()
+	failPrices := true
+
+	stages := []PipelineStage{
+		{Name: "prices", Source: SourcePrices, Run: func(next *Snapshot) error {
+			if failPrices {
+				return errors.New("down")
+			}
+
+			return nil
+		}},
+		{Name: "hbd_rate", Source: SourceHBDRate, Run: func(next *Snapshot) error { return nil }},
+		{Name: "tokens", DependsOn: []string{"prices", "hbd_rate"}, Run: func(next *Snapshot) error { return nil }},
+	}
+
+	for _, stage := range stages {
+		if err := pipeline.Register(stage); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// what LoadSnapshot publishes after a restart
+	savedAt := time.Now().Add(-72 * time.Hour)
+	loaded := &Snapshot{Stale: true, Prices: []pricing.TokenData{{Symbol: "HIVE"}}}
+
+	for _, stage := range pipeline.Stages() {
+		loaded.Stages = append(loaded.Stages, StageStatus{Stage: stage, LastSuccess: savedAt, Stale: true})
+	}
+
+	next, _, err := pipeline.RunSources(loaded, AllSources)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the prices are still the ones from disk, and so is everything built on them
+	prices, _ := next.stage("prices")
+	hbdRate, _ := next.stage("hbd_rate")
+	tokens, _ := next.stage("tokens")
+
+	if !next.Stale || !prices.Stale || !prices.LastSuccess.Equal(savedAt) || hbdRate.Stale || !tokens.Stale {
+		t.Fatalf("stale = %v, stages = %+v", next.Stale, next.Stages)
+	}
+
+	// a stage that isn't due keeps its stale flag too
+	failPrices = false
+
+	next, _, err = pipeline.RunSources(next, []string{SourceHBDRate})
+
+	if err != nil || !next.Stale {
+		t.Fatalf("skipping prices cleared the stale flag (%v)", err)
+	}
+
+	next, _, err = pipeline.RunSources(next, AllSources)
+
+	if err != nil || next.Stale {
+		t.Fatalf("still stale after every stage worked (%v): %+v", err, next.Stages)
+	}
+}
+
+func TestPipelineRunSourcesAtUsesTheClock(t *testing.T) {
+	pipeline := NewPipeline()
+	recordedAt := time.Unix(1_700_000_000, 0)
+
+	var seen time.Time
+
+	err := pipeline.Register(PipelineStage{Name: "detect", Run: func(next *Snapshot) error {
+		seen = next.Now()
+		return nil
+	}})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	next, _, err := pipeline.RunSourcesAt(&Snapshot{}, AllSources, recordedAt)
+
+	if err != nil || !seen.Equal(recordedAt) || !next.Now().Equal(recordedAt) {
+		t.Fatalf("stages saw %s and the snapshot says %s (%v), want %s", seen, next.Now(), err, recordedAt)
+	}
+
+	// the real time otherwise
+	if _, _, err = pipeline.RunSources(&Snapshot{}, AllSources); err != nil || time.Since(seen) > time.Minute {
+		t.Fatalf("stages saw %s (%v)", seen, err)
+	}
+}
@@ -3,10 +3,12 @@ package main
 import (
 	"fmt"
 
+	"github.com/CADawg/hive-swap-calculator/engine"
+	"github.com/CADawg/hive-swap-calculator/pricing"
 	"github.com/shopspring/decimal"
 )
 
-func PrettyPrintTokenData(data []TokenData) {
+func PrettyPrintTokenData(data []pricing.TokenData) {
 	for _, token := range data {
 		//LoadAndShowCryptoIcon(token)
 		fmt.Println("Token: ", token.Symbol)
@@ -33,11 +35,11 @@ func PrettyPrintTokenData(data []TokenData) {
 	}
 }
 
-func PrettyPrintOrderOneLine(order EngineMarketOrder, side string) string {
+func PrettyPrintOrderOneLine(order engine.MarketOrder, side string) string {
 	return fmt.Sprintf("%s %s %s at %s SWAP.HIVE (@%s) (%s%%)", side, order.Quantity.String(), order.Symbol, order.Price.String(), order.Account, order.ProfitPercentage.StringFixed(2))
 }
 
-/*func LoadAndShowCryptoIcon(token TokenData) {
+/*func LoadAndShowCryptoIcon(token pricing.TokenData) {
 	// fetch crypto icon from api https://cryptoicons.org/api/:style/:currency/:size/:color
 
 	// fetch the icon
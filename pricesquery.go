@@ -0,0 +1,211 @@
+package main
+
+import (
+	"errors"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/CADawg/hive-swap-calculator/engine"
+	"github.com/CADawg/hive-swap-calculator/market"
+	"github.com/CADawg/hive-swap-calculator/pricing"
+	"github.com/goccy/go-json"
+	"github.com/shopspring/decimal"
+)
+
+// SideBoth is for asking for both sides of the book (market.SideBuy and market.SideSell are the sides themselves)
+const SideBoth = "both"
+
+// orderSorts are the values allowed for ?sort= (prefix with - for descending)
+var orderSorts = map[string]func(a, b engine.MarketOrder) bool{
+	"profit":     func(a, b engine.MarketOrder) bool { return a.ProfitPercentage.LessThan(b.ProfitPercentage) },
+	"price":      func(a, b engine.MarketOrder) bool { return a.Price.LessThan(b.Price) },
+	"quantity":   func(a, b engine.MarketOrder) bool { return a.Quantity.LessThan(b.Quantity) },
+	"value":      func(a, b engine.MarketOrder) bool { return a.Quantity.Mul(a.Price).LessThan(b.Quantity.Mul(b.Price)) },
+	"expiration": func(a, b engine.MarketOrder) bool { return a.Expiration < b.Expiration },
+	"timestamp":  func(a, b engine.MarketOrder) bool { return a.Timestamp < b.Timestamp },
+}
+
+// tokenFields is every json field name of APIToken, used to validate ?fields=
+var tokenFields = jsonFieldNames(reflect.TypeOf(APIToken{}))
+
+// PricesQuery is everything a client can ask of /prices through the query string
+type PricesQuery struct {
+	Symbols   []string
+	Side      string
+	MinProfit decimal.Decimal
+	Limit     int
+	Sort      string
+	Fields    []string
+	Filter    market.OrderFilter
+}
+
+// ParsePricesQuery validates the query string, anything not set falls back to the config defaults
+func ParsePricesQuery(query url.Values, config Config) (PricesQuery, error) {
+	q := PricesQuery{Side: SideBoth}
+
+	var err error
+
+	q.Filter, err = market.ParseOrderFilterQuery(config.Filters, query)
+
+	if err != nil {
+		return q, err
+	}
+
+	// symbols can be given as either BTC or SWAP.BTC
+	for _, symbol := range market.SplitList(query.Get("symbols")) {
+		q.Symbols = append(q.Symbols, strings.TrimPrefix(strings.ToUpper(symbol), "SWAP."))
+	}
+
+	if value := query.Get("side"); value != "" {
+		switch strings.ToLower(value) {
+		case SideBoth, market.SideBuy, market.SideSell:
+			q.Side = strings.ToLower(value)
+		default:
+			return q, errors.New("side must be one of buy, sell or both")
+		}
+	}
+
+	if value := query.Get("min_profit"); value != "" {
+		q.MinProfit, err = decimal.NewFromString(value)
+
+		if err != nil {
+			return q, errors.New("min_profit must be a number")
+		}
+	}
+
+	if value := query.Get("limit"); value != "" {
+		q.Limit, err = strconv.Atoi(value)
+
+		if err != nil || q.Limit < 1 {
+			return q, errors.New("limit must be a whole number greater than 0")
+		}
+	}
+
+	if value := query.Get("sort"); value != "" {
+		if _, ok := orderSorts[strings.TrimPrefix(value, "-")]; !ok {
+			return q, errors.New("sort must be one of profit, price, quantity, value, expiration or timestamp (prefix with - for descending)")
+		}
+
+		q.Sort = value
+	}
+
+	for _, field := range market.SplitList(query.Get("fields")) {
+		if !market.ContainsString(tokenFields, field) {
+			return q, errors.New("unknown field " + field + ", must be one of " + strings.Join(tokenFields, ", "))
+		}
+
+		q.Fields = append(q.Fields, field)
+	}
+
+	return q, nil
+}
+
+// Apply returns the tokens and orders the query asks for (tokens is left untouched)
+func (q PricesQuery) Apply(tokens []pricing.TokenData, now time.Time) []pricing.TokenData {
+	var output []pricing.TokenData
+
+	for _, token := range q.Filter.FilterTokens(tokens, now) {
+		if len(q.Symbols) > 0 && !market.ContainsString(q.Symbols, token.Symbol) {
+			continue
+		}
+
+		token.SellOrders = q.applyToOrders(token.SellOrders)
+		token.BuyOrders = q.applyToOrders(token.BuyOrders)
+
+		switch q.Side {
+		case market.SideBuy:
+			token.SellOrders = nil
+		case market.SideSell:
+			token.BuyOrders = nil
+		}
+
+		output = append(output, token)
+	}
+
+	return output
+}
+
+func (q PricesQuery) applyToOrders(orders []engine.MarketOrder) []engine.MarketOrder {
+	var kept []engine.MarketOrder
+
+	for _, order := range orders {
+		if order.ProfitPercentage.GreaterThanOrEqual(q.MinProfit) {
+			kept = append(kept, order)
+		}
+	}
+
+	if q.Sort != "" {
+		less := orderSorts[strings.TrimPrefix(q.Sort, "-")]
+
+		if strings.HasPrefix(q.Sort, "-") {
+			sort.SliceStable(kept, func(i, j int) bool { return less(kept[j], kept[i]) })
+		} else {
+			sort.SliceStable(kept, func(i, j int) bool { return less(kept[i], kept[j]) })
+		}
+	}
+
+	if q.Limit > 0 && len(kept) > q.Limit {
+		kept = kept[:q.Limit]
+	}
+
+	return kept
+}
+
+// Output converts tokens to what the api sends, cut down to the requested fields if there are any
+func (q PricesQuery) Output(tokens []pricing.TokenData) (interface{}, error) {
+	apiTokens := NewAPITokens(tokens)
+
+	if len(q.Fields) == 0 {
+		return apiTokens, nil
+	}
+
+	output := make([]map[string]json.RawMessage, 0, len(apiTokens))
+
+	for _, token := range apiTokens {
+		// go through json so the field names (and decimal formatting) match the full response exactly
+		data, err := json.Marshal(token)
+
+		if err != nil {
+			return nil, err
+		}
+
+		var all map[string]json.RawMessage
+
+		err = json.Unmarshal(data, &all)
+
+		if err != nil {
+			return nil, err
+		}
+
+		sparse := make(map[string]json.RawMessage, len(q.Fields))
+
+		for _, field := range q.Fields {
+			if value, ok := all[field]; ok {
+				sparse[field] = value
+			}
+		}
+
+		output = append(output, sparse)
+	}
+
+	return output, nil
+}
+
+// jsonFieldNames lists the json names of a struct's fields
+func jsonFieldNames(t reflect.Type) []string {
+	var names []string
+
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+
+		if name != "" && name != "-" {
+			names = append(names, name)
+		}
+	}
+
+	return names
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/CADawg/hive-swap-calculator/engine"
+	"github.com/CADawg/hive-swap-calculator/pricing"
+	"github.com/goccy/go-json"
+	"github.com/shopspring/decimal"
+)
+
+func TestParsePricesQueryErrors(t *testing.T) {
+	tests := []string{
+		"side=up",
+		"min_profit=lots",
+		"limit=0",
+		"limit=-1",
+		"limit=many",
+		"sort=age",
+		"sort=--price",
+		"fields=usd,colour",
+		"min_hive_value=-1",
+	}
+
+	for _, test := range tests {
+		t.Run(test, func(t *testing.T) {
+			query, _ := url.ParseQuery(test)
+
+			if _, err := ParsePricesQuery(query, DefaultConfig()); err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+}
+
+func TestParsePricesQuery(t *testing.T) {
+	query, _ := url.ParseQuery("symbols=btc,SWAP.eth&side=SELL&min_profit=2.5&limit=3&sort=-value&fields=symbol,hive")
+
+	q, err := ParsePricesQuery(query, DefaultConfig())
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(q.Symbols) != 2 || q.Symbols[0] != "BTC" || q.Symbols[1] != "ETH" {
+		t.Fatalf("symbols = %v, want [BTC ETH]", q.Symbols)
+	}
+
+	if q.Side != "sell" || !q.MinProfit.Equal(decimal.RequireFromString("2.5")) || q.Limit != 3 || q.Sort != "-value" {
+		t.Fatalf("unexpected query %+v", q)
+	}
+
+	if len(q.Fields) != 2 || q.Fields[0] != "symbol" || q.Fields[1] != "hive" {
+		t.Fatalf("fields = %v, want [symbol hive]", q.Fields)
+	}
+
+	q, err = ParsePricesQuery(url.Values{}, DefaultConfig())
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if q.Side != SideBoth || q.Limit != 0 || q.Sort != "" || q.Symbols != nil {
+		t.Fatalf("empty query should be everything, got %+v", q)
+	}
+}
+
+func queryOrder(txID string, profit int64, price int64) engine.MarketOrder {
+	return engine.MarketOrder{
+		TransactionID:    txID,
+		ProfitPercentage: decimal.NewFromInt(profit),
+		Price:            decimal.NewFromInt(price),
+		Quantity:         decimal.NewFromInt(1),
+	}
+}
+
+func orderIDs(orders []engine.MarketOrder) []string {
+	var ids []string
+
+	for _, order := range orders {
+		ids = append(ids, order.TransactionID)
+	}
+
+	return ids
+}
+
+func TestPricesQueryApply(t *testing.T) {
+	tokens := []pricing.TokenData{
+		{
+			Symbol:     "BTC",
+			SellOrders: []engine.MarketOrder{queryOrder("s1", 1, 30), queryOrder("s2", 5, 10), queryOrder("s3", 3, 20)},
+			BuyOrders:  []engine.MarketOrder{queryOrder("b1", 4, 1)},
+		},
+		{Symbol: "ETH", SellOrders: []engine.MarketOrder{queryOrder("e1", 9, 1)}},
+	}
+
+	tests := []struct {
+		query string
+		want  map[string][2][]string
+	}{
+		{"", map[string][2][]string{"BTC": {{"s1", "s2", "s3"}, {"b1"}}, "ETH": {{"e1"}, nil}}},
+		{"symbols=btc", map[string][2][]string{"BTC": {{"s1", "s2", "s3"}, {"b1"}}}},
+		{"side=buy", map[string][2][]string{"BTC": {nil, {"b1"}}, "ETH": {nil, nil}}},
+		{"side=sell&symbols=btc", map[string][2][]string{"BTC": {{"s1", "s2", "s3"}, nil}}},
+		{"min_profit=3", map[string][2][]string{"BTC": {{"s2", "s3"}, {"b1"}}, "ETH": {{"e1"}, nil}}},
+		{"sort=-profit&limit=2&symbols=BTC", map[string][2][]string{"BTC": {{"s2", "s3"}, {"b1"}}}},
+		{"sort=price&symbols=BTC", map[string][2][]string{"BTC": {{"s2", "s3", "s1"}, {"b1"}}}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.query, func(t *testing.T) {
+			query, _ := url.ParseQuery(test.query)
+			q, err := ParsePricesQuery(query, DefaultConfig())
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			output := q.Apply(tokens, time.Now())
+
+			if len(output) != len(test.want) {
+				t.Fatalf("got %d tokens, want %d", len(output), len(test.want))
+			}
+
+			for _, token := range output {
+				want, ok := test.want[token.Symbol]
+
+				if !ok {
+					t.Fatalf("unexpected token %s", token.Symbol)
+				}
+
+				if got := orderIDs(token.SellOrders); !equalStrings(got, want[0]) {
+					t.Fatalf("%s sell orders = %v, want %v", token.Symbol, got, want[0])
+				}
+
+				if got := orderIDs(token.BuyOrders); !equalStrings(got, want[1]) {
+					t.Fatalf("%s buy orders = %v, want %v", token.Symbol, got, want[1])
+				}
+			}
+		})
+	}
+
+	// the snapshot's tokens must never be changed by a query
+	if got := orderIDs(tokens[0].SellOrders); !equalStrings(got, []string{"s1", "s2", "s3"}) {
+		t.Fatalf("Apply changed its input: %v", got)
+	}
+}
+
+func TestPricesQueryOutputFields(t *testing.T) {
+	query, _ := url.ParseQuery("fields=symbol,hive")
+	q, err := ParsePricesQuery(query, DefaultConfig())
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	output, err := q.Output([]pricing.TokenData{{Symbol: "BTC", HIVEPrice: decimal.RequireFromString("1.5"), USDPrice: decimal.NewFromInt(2)}})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := json.Marshal(output)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(data) != `[{"hive":"1.5","symbol":"BTC"}]` {
+		t.Fatalf("got %s", data)
+	}
+}
+
+func equalStrings(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,104 @@
+// Package pricing gets token prices from CoinGecko and works them out in HIVE
+package pricing
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/CADawg/hive-swap-calculator/engine"
+	"github.com/CADawg/hive-swap-calculator/upstream"
+	"github.com/shopspring/decimal"
+)
+
+// DefaultCoinGeckoAPI is CoinGecko's public api
+const DefaultCoinGeckoAPI = "https://api.coingecko.com/api/v3"
+
+type TokenData struct {
+	USDPrice     decimal.Decimal `json:"usd"`
+	USD24HChange decimal.Decimal `json:"usd_24h_change"`
+	BTCPrice     decimal.Decimal `json:"btc"`
+	BTC24HChange decimal.Decimal `json:"btc_24h_change"`
+	LastUpdated  int64           `json:"last_updated_at"`
+
+	// These are not part of the JSON response
+	// but are added by the program
+	HIVEPrice decimal.Decimal `json:"hive,omitempty"`
+
+	NetworkPercentageFee decimal.Decimal `json:"network_percentage_fee,omitempty"`
+	NetworkFlatFee       decimal.Decimal `json:"network_flat_fee,omitempty"`
+	Network              string          `json:"network,omitempty"`
+
+	CoinGeckoName string `json:"name,omitempty"`
+	Symbol        string `json:"symbol,omitempty"`
+	SwapSymbol    string `json:"swap_symbol,omitempty"`
+
+	SellOrders []engine.MarketOrder `json:"sell_orders,omitempty"`
+	BuyOrders  []engine.MarketOrder `json:"buy_orders,omitempty"`
+}
+
+// RegistryToken links a CoinGecko id to a Hive Engine symbol
+type RegistryToken struct {
+	CoinGeckoID string `json:"coingecko_id"`
+	Symbol      string `json:"symbol"`
+}
+
+// Client talks to CoinGecko
+type Client struct {
+	api  string
+	http *http.Client
+}
+
+// NewClient makes a client for api (e.g. DefaultCoinGeckoAPI) that sends its requests through httpClient
+func NewClient(api string, httpClient *http.Client) *Client {
+	return &Client{api: api, http: httpClient}
+}
+
+// API is the api the client talks to
+func (c *Client) API() string {
+	return c.api
+}
+
+// LoadPriceAndSymbolData prices every token in registry, in USD, BTC and HIVE (so it has to include HIVE)
+func (c *Client) LoadPriceAndSymbolData(registry []RegistryToken) ([]TokenData, error) {
+	var ids []string
+
+	for _, token := range registry {
+		ids = append(ids, url.QueryEscape(token.CoinGeckoID))
+	}
+
+	tokens, err := upstream.GetJSON[map[string]TokenData](c.http, c.api+"/simple/price?ids="+strings.Join(ids, ",")+"&vs_currencies=usd,btc&include_24hr_change=true&include_last_updated_at=true&precision=full")
+
+	if err != nil {
+		return nil, err
+	}
+
+	tokensArray := MapToTokenWithName(*tokens)
+
+	tokensWithData := AddHivePriceInformation(AddAllSymbolInformation(tokensArray, registry))
+
+	return tokensWithData, nil
+}
+
+func MapToTokenWithName(data map[string]TokenData) []TokenData {
+	var dataParsed []TokenData
+
+	for key, value := range data {
+		value.CoinGeckoName = key
+
+		dataParsed = append(dataParsed, value)
+	}
+
+	return dataParsed
+}
+
+// HivePrices is the price in HIVE of each token, by symbol
+func HivePrices(tokens []TokenData) map[string]decimal.Decimal {
+	prices := map[string]decimal.Decimal{}
+
+	for _, token := range tokens {
+		prices[strings.ToUpper(token.Symbol)] = token.HIVEPrice
+	}
+
+	return prices
+}
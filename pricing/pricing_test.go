@@ -0,0 +1,93 @@
+package pricing
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"testing"
+
+	"github.com/CADawg/hive-swap-calculator/upstream"
+	"github.com/shopspring/decimal"
+)
+
+var testRegistry = []RegistryToken{
+	{CoinGeckoID: "hive", Symbol: "HIVE"},
+	{CoinGeckoID: "bitcoin", Symbol: "BTC"},
+}
+
+func TestLoadPriceAndSymbolData(t *testing.T) {
+	var query string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.Query().Get("ids")
+
+		_, _ = w.Write([]byte(`{"hive":{"usd":0.5,"btc":0.00001},"bitcoin":{"usd":50000,"btc":1,"usd_24h_change":-2.5}}`))
+	}))
+	defer server.Close()
+
+	tokens, err := NewClient(server.URL, server.Client()).LoadPriceAndSymbolData(testRegistry)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if query != "hive,bitcoin" {
+		t.Fatalf("asked for ids %q", query)
+	}
+
+	sort.Slice(tokens, func(i, j int) bool { return tokens[i].Symbol < tokens[j].Symbol })
+
+	if len(tokens) != 2 {
+		t.Fatalf("got %d tokens", len(tokens))
+	}
+
+	btc, hive := tokens[0], tokens[1]
+
+	if btc.Symbol != "BTC" || btc.SwapSymbol != "SWAP.BTC" || btc.CoinGeckoName != "bitcoin" || !btc.HIVEPrice.Equal(decimal.NewFromInt(100000)) || !btc.USD24HChange.Equal(decimal.RequireFromString("-2.5")) {
+		t.Fatalf("BTC = %+v", btc)
+	}
+
+	if hive.Symbol != "HIVE" || !hive.HIVEPrice.Equal(decimal.NewFromInt(1)) {
+		t.Fatalf("HIVE = %+v", hive)
+	}
+
+	if prices := HivePrices(tokens); !prices["BTC"].Equal(decimal.NewFromInt(100000)) || !prices["HIVE"].Equal(decimal.NewFromInt(1)) {
+		t.Fatalf("HivePrices() = %v", prices)
+	}
+}
+
+func TestLoadPriceAndSymbolDataErrors(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		status  int
+		body    string
+		limited bool
+	}{
+		{"rate limited", http.StatusTooManyRequests, "", true},
+		{"broken json", http.StatusOK, `{"hive":`, false},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(test.status)
+				_, _ = w.Write([]byte(test.body))
+			}))
+			defer server.Close()
+
+			_, err := NewClient(server.URL, server.Client()).LoadPriceAndSymbolData(testRegistry)
+
+			if err == nil || errors.Is(err, upstream.ErrRateLimited) != test.limited {
+				t.Fatalf("got %v", err)
+			}
+		})
+	}
+}
+
+func TestAddSymbolInformation(t *testing.T) {
+	token := AddSymbolInformation(TokenData{}, "Bitcoin", testRegistry)
+
+	// CoinGecko ids are matched whatever their case
+	if token.Symbol != "BTC" || token.SwapSymbol != "SWAP.BTC" || token.CoinGeckoName != "Bitcoin" {
+		t.Fatalf("got %+v", token)
+	}
+}
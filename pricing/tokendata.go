@@ -0,0 +1,49 @@
+package pricing
+
+import (
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+func AddAllSymbolInformation(tokens []TokenData, registry []RegistryToken) []TokenData {
+	for i, token := range tokens {
+		tokens[i] = AddSymbolInformation(token, token.CoinGeckoName, registry)
+	}
+
+	return tokens
+}
+
+func AddSymbolInformation(data TokenData, name string, registry []RegistryToken) TokenData {
+	data.CoinGeckoName = name
+
+	for _, token := range registry {
+		if strings.EqualFold(token.CoinGeckoID, name) {
+			data.Symbol = token.Symbol
+		}
+	}
+
+	data.SwapSymbol = "SWAP." + data.Symbol
+
+	return data
+}
+
+func AddHivePriceInformation(tokens []TokenData) []TokenData {
+	hivePrice := decimal.Decimal{}
+
+	for _, token := range tokens {
+		if token.Symbol == "HIVE" {
+			hivePrice = token.USDPrice
+		}
+	}
+
+	for i, token := range tokens {
+		tokens[i].HIVEPrice = token.USDPrice.Div(hivePrice)
+
+		if token.Symbol == "HIVE" {
+			tokens[i].HIVEPrice = decimal.NewFromInt(1)
+		}
+	}
+
+	return tokens
+}
@@ -0,0 +1,298 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             (unknown)
+// source: hiveswap/v1/hiveswap.proto
+
+// the same data the http api serves, for clients that would rather speak grpc.
+// decimals are strings so nothing gets rounded on the way (e.g. "0.00012345").
+
+package hiveswapv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	HiveSwapService_GetSnapshot_FullMethodName    = "/hiveswap.v1.HiveSwapService/GetSnapshot"
+	HiveSwapService_GetToken_FullMethodName       = "/hiveswap.v1.HiveSwapService/GetToken"
+	HiveSwapService_GetOrder_FullMethodName       = "/hiveswap.v1.HiveSwapService/GetOrder"
+	HiveSwapService_GetQuote_FullMethodName       = "/hiveswap.v1.HiveSwapService/GetQuote"
+	HiveSwapService_WatchSnapshots_FullMethodName = "/hiveswap.v1.HiveSwapService/WatchSnapshots"
+)
+
+// HiveSwapServiceClient is the client API for HiveSwapService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type HiveSwapServiceClient interface {
+	// GetSnapshot returns every token (with its orders and fees) from the latest refresh
+	GetSnapshot(ctx context.Context, in *GetSnapshotRequest, opts ...grpc.CallOption) (*Snapshot, error)
+	// GetToken returns one token from the latest refresh
+	GetToken(ctx context.Context, in *GetTokenRequest, opts ...grpc.CallOption) (*Token, error)
+	// GetOrder returns one order by its transaction id
+	GetOrder(ctx context.Context, in *GetOrderRequest, opts ...grpc.CallOption) (*Order, error)
+	// GetQuote works out the most profitable route for an amount of HIVE
+	GetQuote(ctx context.Context, in *GetQuoteRequest, opts ...grpc.CallOption) (*Quote, error)
+	// WatchSnapshots sends the current snapshot, then a new one after every refresh
+	WatchSnapshots(ctx context.Context, in *WatchSnapshotsRequest, opts ...grpc.CallOption) (HiveSwapService_WatchSnapshotsClient, error)
+}
+
+type hiveSwapServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewHiveSwapServiceClient(cc grpc.ClientConnInterface) HiveSwapServiceClient {
+	return &hiveSwapServiceClient{cc}
+}
+
+func (c *hiveSwapServiceClient) GetSnapshot(ctx context.Context, in *GetSnapshotRequest, opts ...grpc.CallOption) (*Snapshot, error) {
+	out := new(Snapshot)
+	err := c.cc.Invoke(ctx, HiveSwapService_GetSnapshot_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *hiveSwapServiceClient) GetToken(ctx context.Context, in *GetTokenRequest, opts ...grpc.CallOption) (*Token, error) {
+	out := new(Token)
+	err := c.cc.Invoke(ctx, HiveSwapService_GetToken_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *hiveSwapServiceClient) GetOrder(ctx context.Context, in *GetOrderRequest, opts ...grpc.CallOption) (*Order, error) {
+	out := new(Order)
+	err := c.cc.Invoke(ctx, HiveSwapService_GetOrder_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *hiveSwapServiceClient) GetQuote(ctx context.Context, in *GetQuoteRequest, opts ...grpc.CallOption) (*Quote, error) {
+	out := new(Quote)
+	err := c.cc.Invoke(ctx, HiveSwapService_GetQuote_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *hiveSwapServiceClient) WatchSnapshots(ctx context.Context, in *WatchSnapshotsRequest, opts ...grpc.CallOption) (HiveSwapService_WatchSnapshotsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &HiveSwapService_ServiceDesc.Streams[0], HiveSwapService_WatchSnapshots_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &hiveSwapServiceWatchSnapshotsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type HiveSwapService_WatchSnapshotsClient interface {
+	Recv() (*Snapshot, error)
+	grpc.ClientStream
+}
+
+type hiveSwapServiceWatchSnapshotsClient struct {
+	grpc.ClientStream
+}
+
+func (x *hiveSwapServiceWatchSnapshotsClient) Recv() (*Snapshot, error) {
+	m := new(Snapshot)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// HiveSwapServiceServer is the server API for HiveSwapService service.
+// All implementations must embed UnimplementedHiveSwapServiceServer
+// for forward compatibility
+type HiveSwapServiceServer interface {
+	// GetSnapshot returns every token (with its orders and fees) from the latest refresh
+	GetSnapshot(context.Context, *GetSnapshotRequest) (*Snapshot, error)
+	// GetToken returns one token from the latest refresh
+	GetToken(context.Context, *GetTokenRequest) (*Token, error)
+	// GetOrder returns one order by its transaction id
+	GetOrder(context.Context, *GetOrderRequest) (*Order, error)
+	// GetQuote works out the most profitable route for an amount of HIVE
+	GetQuote(context.Context, *GetQuoteRequest) (*Quote, error)
+	// WatchSnapshots sends the current snapshot, then a new one after every refresh
+	WatchSnapshots(*WatchSnapshotsRequest, HiveSwapService_WatchSnapshotsServer) error
+	mustEmbedUnimplementedHiveSwapServiceServer()
+}
+
+// UnimplementedHiveSwapServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedHiveSwapServiceServer struct {
+}
+
+func (UnimplementedHiveSwapServiceServer) GetSnapshot(context.Context, *GetSnapshotRequest) (*Snapshot, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetSnapshot not implemented")
+}
+func (UnimplementedHiveSwapServiceServer) GetToken(context.Context, *GetTokenRequest) (*Token, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetToken not implemented")
+}
+func (UnimplementedHiveSwapServiceServer) GetOrder(context.Context, *GetOrderRequest) (*Order, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetOrder not implemented")
+}
+func (UnimplementedHiveSwapServiceServer) GetQuote(context.Context, *GetQuoteRequest) (*Quote, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetQuote not implemented")
+}
+func (UnimplementedHiveSwapServiceServer) WatchSnapshots(*WatchSnapshotsRequest, HiveSwapService_WatchSnapshotsServer) error {
+	return status.Errorf(codes.Unimplemented, "method WatchSnapshots not implemented")
+}
+func (UnimplementedHiveSwapServiceServer) mustEmbedUnimplementedHiveSwapServiceServer() {}
+
+// UnsafeHiveSwapServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to HiveSwapServiceServer will
+// result in compilation errors.
+type UnsafeHiveSwapServiceServer interface {
+	mustEmbedUnimplementedHiveSwapServiceServer()
+}
+
+func RegisterHiveSwapServiceServer(s grpc.ServiceRegistrar, srv HiveSwapServiceServer) {
+	s.RegisterService(&HiveSwapService_ServiceDesc, srv)
+}
+
+func _HiveSwapService_GetSnapshot_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetSnapshotRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(HiveSwapServiceServer).GetSnapshot(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: HiveSwapService_GetSnapshot_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(HiveSwapServiceServer).GetSnapshot(ctx, req.(*GetSnapshotRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _HiveSwapService_GetToken_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetTokenRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(HiveSwapServiceServer).GetToken(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: HiveSwapService_GetToken_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(HiveSwapServiceServer).GetToken(ctx, req.(*GetTokenRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _HiveSwapService_GetOrder_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetOrderRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(HiveSwapServiceServer).GetOrder(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: HiveSwapService_GetOrder_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(HiveSwapServiceServer).GetOrder(ctx, req.(*GetOrderRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _HiveSwapService_GetQuote_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetQuoteRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(HiveSwapServiceServer).GetQuote(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: HiveSwapService_GetQuote_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(HiveSwapServiceServer).GetQuote(ctx, req.(*GetQuoteRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _HiveSwapService_WatchSnapshots_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchSnapshotsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(HiveSwapServiceServer).WatchSnapshots(m, &hiveSwapServiceWatchSnapshotsServer{stream})
+}
+
+type HiveSwapService_WatchSnapshotsServer interface {
+	Send(*Snapshot) error
+	grpc.ServerStream
+}
+
+type hiveSwapServiceWatchSnapshotsServer struct {
+	grpc.ServerStream
+}
+
+func (x *hiveSwapServiceWatchSnapshotsServer) Send(m *Snapshot) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+// HiveSwapService_ServiceDesc is the grpc.ServiceDesc for HiveSwapService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var HiveSwapService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "hiveswap.v1.HiveSwapService",
+	HandlerType: (*HiveSwapServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetSnapshot",
+			Handler:    _HiveSwapService_GetSnapshot_Handler,
+		},
+		{
+			MethodName: "GetToken",
+			Handler:    _HiveSwapService_GetToken_Handler,
+		},
+		{
+			MethodName: "GetOrder",
+			Handler:    _HiveSwapService_GetOrder_Handler,
+		},
+		{
+			MethodName: "GetQuote",
+			Handler:    _HiveSwapService_GetQuote_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchSnapshots",
+			Handler:       _HiveSwapService_WatchSnapshots_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "hiveswap/v1/hiveswap.proto",
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// RateLimitDefault is for cheap endpoints like /prices
+	RateLimitDefault = "default"
+	// RateLimitHeavy is for endpoints that do real work per request (e.g. route computation)
+	RateLimitHeavy = "heavy"
+)
+
+// buckets nobody has touched for this long are forgotten
+const bucketIdleTimeout = 10 * time.Minute
+
+// RateLimitConfig is the limits for each class of endpoint and who we trust to tell us the client's ip
+type RateLimitConfig struct {
+	// TrustedProxies are ips or cidrs allowed to set X-Forwarded-For (e.g. our load balancer)
+	TrustedProxies []string             `json:"trusted_proxies"`
+	Limits         map[string]RateLimit `json:"limits"`
+}
+
+// RateLimit is a token bucket, a client can make Burst requests at once and then RequestsPerSecond after that
+type RateLimit struct {
+	RequestsPerSecond float64 `json:"requests_per_second"`
+	Burst             int     `json:"burst"`
+}
+
+type tokenBucket struct {
+	tokens   float64
+	lastSeen time.Time
+}
+
+// RateLimiter keeps a token bucket for each client in each endpoint class
+type RateLimiter struct {
+	lock        sync.Mutex
+	buckets     map[string]*tokenBucket
+	lastCleanup time.Time
+}
+
+func NewRateLimiter() *RateLimiter {
+	return &RateLimiter{buckets: map[string]*tokenBucket{}, lastCleanup: time.Now()}
+}
+
+// Allow takes a token from key's bucket, if there isn't one it says how long until there will be
+func (l *RateLimiter) Allow(key string, limit RateLimit, now time.Time) (bool, time.Duration) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if now.Sub(l.lastCleanup) > bucketIdleTimeout {
+		for bucketKey, bucket := range l.buckets {
+			if now.Sub(bucket.lastSeen) > bucketIdleTimeout {
+				delete(l.buckets, bucketKey)
+			}
+		}
+
+		l.lastCleanup = now
+	}
+
+	bucket, ok := l.buckets[key]
+
+	if !ok {
+		bucket = &tokenBucket{tokens: float64(limit.Burst), lastSeen: now}
+		l.buckets[key] = bucket
+	}
+
+	// refill for the time since we last saw them
+	bucket.tokens = math.Min(float64(limit.Burst), bucket.tokens+now.Sub(bucket.lastSeen).Seconds()*limit.RequestsPerSecond)
+	bucket.lastSeen = now
+
+	if bucket.tokens >= 1 {
+		bucket.tokens--
+		return true, 0
+	}
+
+	if limit.RequestsPerSecond <= 0 {
+		return false, bucketIdleTimeout
+	}
+
+	return false, time.Duration((1 - bucket.tokens) / limit.RequestsPerSecond * float64(time.Second))
+}
+
+// Limit rate limits a handler using the limit for class in the config, or the api key's own limit if it has one.
+// A class with no limit isn't limited.
+func (l *RateLimiter) Limit(class string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		config := GetConfig().RateLimit
+
+		limit, ok := config.Limits[class]
+
+		if key, hasKey := APIKeyFromContext(r.Context()); hasKey {
+			if keyLimit, hasKeyLimit := key.RateLimits[class]; hasKeyLimit {
+				limit, ok = keyLimit, true
+			}
+		}
+
+		if !ok || r.Method == http.MethodOptions {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		allowed, retryAfter := l.Allow(class+"|"+RateLimitKey(r, config.TrustedProxies), limit, time.Now())
+
+		if !allowed {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+			WriteAPIError(w, http.StatusTooManyRequests, "rate limit exceeded, try again later")
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// RateLimitKey is who a request counts against, the id of its api key once Authenticate has validated it, or its ip.
+// Never the raw X-API-Key header, or a made up key per request would get a new bucket each time.
+func RateLimitKey(r *http.Request, trustedProxies []string) string {
+	if key, ok := APIKeyFromContext(r.Context()); ok {
+		return "key:" + key.ID
+	}
+
+	return "ip:" + ClientIP(r, trustedProxies)
+}
+
+// ClientIP is the ip of whoever made the request, only believing X-Forwarded-For when it was added by a trusted proxy
+func ClientIP(r *http.Request, trustedProxies []string) string {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+
+	if !ipTrusted(ip, trustedProxies) {
+		return ip
+	}
+
+	// walk back from the closest hop, the first one we don't trust is the client
+	var hops []string
+
+	for _, header := range r.Header.Values("X-Forwarded-For") {
+		hops = append(hops, strings.Split(header, ",")...)
+	}
+
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+
+		if net.ParseIP(hop) == nil {
+			break
+		}
+
+		ip = hop
+
+		if !ipTrusted(hop, trustedProxies) {
+			break
+		}
+	}
+
+	return ip
+}
+
+func ipTrusted(ip string, trustedProxies []string) bool {
+	parsed := net.ParseIP(ip)
+
+	if parsed == nil {
+		return false
+	}
+
+	for _, trusted := range trustedProxies {
+		if strings.Contains(trusted, "/") {
+			_, network, err := net.ParseCIDR(trusted)
+
+			if err == nil && network.Contains(parsed) {
+				return true
+			}
+		} else if trustedIP := net.ParseIP(trusted); trustedIP != nil && trustedIP.Equal(parsed) {
+			return true
+		}
+	}
+
+	return false
+}
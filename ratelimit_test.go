@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterAllow(t *testing.T) {
+	limiter := NewRateLimiter()
+	limit := RateLimit{RequestsPerSecond: 2, Burst: 3}
+	now := time.Now()
+
+	for i := 0; i < 3; i++ {
+		if allowed, _ := limiter.Allow("client", limit, now); !allowed {
+			t.Fatalf("request %d of the burst was refused", i+1)
+		}
+	}
+
+	allowed, retryAfter := limiter.Allow("client", limit, now)
+
+	if allowed || retryAfter != 500*time.Millisecond {
+		t.Fatalf("got allowed=%v retry after %s, want refused for 500ms", allowed, retryAfter)
+	}
+
+	// other clients have their own bucket
+	if allowed, _ := limiter.Allow("someone else", limit, now); !allowed {
+		t.Fatal("another client was limited")
+	}
+
+	// refills at RequestsPerSecond, never past the burst
+	if allowed, _ := limiter.Allow("client", limit, now.Add(500*time.Millisecond)); !allowed {
+		t.Fatal("the bucket didn't refill")
+	}
+
+	later := now.Add(time.Hour)
+
+	for i := 0; i < 3; i++ {
+		if allowed, _ := limiter.Allow("client", limit, later); !allowed {
+			t.Fatalf("request %d after refilling was refused", i+1)
+		}
+	}
+
+	if allowed, _ := limiter.Allow("client", limit, later); allowed {
+		t.Fatal("the bucket refilled past its burst")
+	}
+
+	if allowed, retryAfter := limiter.Allow("none", RateLimit{Burst: 1}, now); !allowed || retryAfter != 0 {
+		t.Fatal("the first request with a zero rate should use the burst")
+	}
+
+	if allowed, retryAfter := limiter.Allow("none", RateLimit{Burst: 1}, now); allowed || retryAfter != bucketIdleTimeout {
+		t.Fatalf("a zero rate should never refill, got retry after %s", retryAfter)
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	trusted := []string{"10.0.0.0/8", "192.168.1.1"}
+
+	tests := []struct {
+		remoteAddr string
+		forwarded  string
+		want       string
+	}{
+		{"1.2.3.4:5000", "", "1.2.3.4"},
+		// only a trusted proxy can set X-Forwarded-For
+		{"1.2.3.4:5000", "9.9.9.9", "1.2.3.4"},
+		{"10.0.0.1:5000", "9.9.9.9", "9.9.9.9"},
+		{"10.0.0.1:5000", "8.8.8.8, 9.9.9.9, 10.0.0.2", "9.9.9.9"},
+		{"192.168.1.1:5000", "garbage, 9.9.9.9", "9.9.9.9"},
+		{"10.0.0.1:5000", "garbage", "10.0.0.1"},
+	}
+
+	for _, test := range tests {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.RemoteAddr = test.remoteAddr
+
+		if test.forwarded != "" {
+			r.Header.Set("X-Forwarded-For", test.forwarded)
+		}
+
+		if got := ClientIP(r, trusted); got != test.want {
+			t.Errorf("%s via %q = %s, want %s", test.remoteAddr, test.forwarded, got, test.want)
+		}
+	}
+}
+
+func TestRateLimitKeysByIPUnlessTheKeyValidates(t *testing.T) {
+	previous := GetConfig()
+	defer SetConfig(previous)
+
+	config := DefaultConfig()
+	config.RateLimit.Limits = map[string]RateLimit{RateLimitDefault: {RequestsPerSecond: 0.001, Burst: 2}}
+	SetConfig(config)
+
+	// the real middleware chain, so the order of auth and limiting is tested too
+	mux, _, rawKey := adminTestMux(t)
+
+	request := func(ip string, key string) int {
+		r := httptest.NewRequest("GET", "/api/v1/prices", nil)
+		r.RemoteAddr = ip + ":5000"
+
+		if key != "" {
+			r.Header.Set(APIKeyHeader, key)
+		}
+
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+
+		return w.Code
+	}
+
+	// a made up key is a 401, but it still costs the ip a request, so a new one each time doesn't get around the limit
+	for i := 0; i < 2; i++ {
+		if code := request("1.2.3.4", "made-up-"+strconv.Itoa(i)); code != http.StatusUnauthorized {
+			t.Fatalf("made up key %d got %d, want 401", i+1, code)
+		}
+	}
+
+	if code := request("1.2.3.4", "made-up-3"); code != http.StatusTooManyRequests {
+		t.Fatalf("guessing keys got past the ip's limit with %d", code)
+	}
+
+	// and it's the same bucket as requests without a key
+	if code := request("1.2.3.4", ""); code != http.StatusTooManyRequests {
+		t.Fatalf("the ip got another request after using its limit guessing keys, got %d", code)
+	}
+
+	// a valid key from the same ip has its own bucket
+	for i := 0; i < 2; i++ {
+		if code := request("1.2.3.4", rawKey); code == http.StatusTooManyRequests || code == http.StatusUnauthorized {
+			t.Fatalf("keyed request %d got %d", i+1, code)
+		}
+	}
+
+	if code := request("1.2.3.4", rawKey); code != http.StatusTooManyRequests {
+		t.Fatalf("the key's bucket wasn't limited, got %d", code)
+	}
+
+	// other ips are unaffected
+	if code := request("5.6.7.8", ""); code == http.StatusTooManyRequests {
+		t.Fatal("another ip was limited")
+	}
+}
+
+func TestRateLimitKey(t *testing.T) {
+	r := httptest.NewRequest("GET", "/api/v1/prices", nil)
+	r.RemoteAddr = "1.2.3.4:5000"
+	r.Header.Set(APIKeyHeader, "made-up")
+
+	if key := RateLimitKey(r, nil); key != "ip:1.2.3.4" {
+		t.Fatalf("an unvalidated key got %s", key)
+	}
+
+	r = r.WithContext(context.WithValue(r.Context(), apiKeyContextKey{}, &APIKey{ID: "partner"}))
+
+	if key := RateLimitKey(r, nil); key != "key:partner" {
+		t.Fatalf("a validated key got %s", key)
+	}
+}
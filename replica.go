@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"time"
+
+	"github.com/CADawg/hive-swap-calculator/gateway"
+	hiveswapv1 "github.com/CADawg/hive-swap-calculator/proto/hiveswap/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+)
+
+// FollowPrimary is the refresh loop of a replica. It republishes every snapshot the primary streams to us, and if
+// the primary has been gone for longer than the failover time it refreshes from the upstreams itself (still trying
+// to get back to the primary every cycle).
+func FollowPrimary(configWatcher *ConfigWatcher) {
+	// give the primary the failover time to answer before we start hitting the upstreams
+	lastSnapshot := time.Now()
+
+	for {
+		configWatcher.ApplyPending()
+
+		config := GetConfig().Replica
+
+		err := followPrimaryStream(config, configWatcher, &lastSnapshot)
+
+		fmt.Println("error following primary "+config.PrimaryAddr+":", err)
+
+		if time.Since(lastSnapshot) >= time.Duration(config.FailoverAfterSeconds)*time.Second {
+			fmt.Println("primary unreachable, refreshing from the upstreams ourselves")
+
+			next, err := Refresh(CurrentSnapshot())
+
+			// keep serving the last good snapshot (from the primary or us) rather than nothing
+			if err != nil {
+				fmt.Println("error:", err)
+			} else {
+				PublishSnapshot(next)
+				SaveSnapshot(next)
+			}
+		}
+
+		RefreshScheduler.Sleep(RefreshInterval)
+	}
+}
+
+// followPrimaryStream republishes snapshots from the primary until the stream breaks, or goes quiet for longer than the failover time
+func followPrimaryStream(config ReplicaConfig, configWatcher *ConfigWatcher, lastSnapshot *time.Time) error {
+	failoverAfter := time.Duration(config.FailoverAfterSeconds) * time.Second
+
+	transport := insecure.NewCredentials()
+
+	if config.TLS {
+		transport = credentials.NewTLS(&tls.Config{MinVersion: tls.VersionTLS12})
+	}
+
+	conn, err := grpc.Dial(config.PrimaryAddr, grpc.WithTransportCredentials(transport))
+
+	if err != nil {
+		return err
+	}
+
+	defer conn.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if config.APIKey != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, grpcAPIKeyMetadata, config.APIKey)
+	}
+
+	// a primary that's up but stuck is as bad as one that's down
+	watchdog := time.AfterFunc(failoverAfter, cancel)
+	defer watchdog.Stop()
+
+	// we want everything, our own filters are applied when we serve it
+	stream, err := hiveswapv1.NewHiveSwapServiceClient(conn).WatchSnapshots(ctx, &hiveswapv1.WatchSnapshotsRequest{Unfiltered: true})
+
+	if err != nil {
+		return err
+	}
+
+	for {
+		snapshot, err := stream.Recv()
+
+		if err != nil {
+			return err
+		}
+
+		watchdog.Reset(failoverAfter)
+
+		followed, err := followedSnapshot(snapshot)
+
+		if err != nil {
+			return err
+		}
+
+		// between snapshots is our version of between refresh cycles
+		configWatcher.ApplyPending()
+
+		PublishSnapshot(followed)
+		SaveSnapshot(followed)
+
+		*lastSnapshot = time.Now()
+
+		fmt.Println("Followed primary snapshot", snapshot.Version)
+	}
+}
+
+// followedSnapshot is our copy of a snapshot from the primary. If the primary is serving from disk then so are we.
+// The primary only sends its tokens, so the fees and books (just the orders past the reference price) are worked
+// out from them, and the detectors run here on those with our config. A failover refresh starts from scratch for the
+// prices.
+func followedSnapshot(snapshot *hiveswapv1.Snapshot) (*Snapshot, error) {
+	tokens, err := tokensFromProto(snapshot)
+
+	if err != nil {
+		return nil, err
+	}
+
+	updatedAt := time.Now()
+
+	if snapshot.UpdatedAt > 0 {
+		updatedAt = time.Unix(snapshot.UpdatedAt, 0)
+	}
+
+	followed := &Snapshot{
+		UpdatedAt: updatedAt,
+		Stale:     snapshot.Stale,
+		Stages:    stagesFromProto(snapshot),
+		Fees:      map[string]gateway.TokenFee{},
+		Tokens:    tokens,
+	}
+
+	for _, token := range tokens {
+		followed.Fees[token.Symbol] = gateway.TokenFee{PercentageFee: token.NetworkPercentageFee, FlatFee: token.NetworkFlatFee, Network: token.Network}
+		followed.SellBook = append(followed.SellBook, token.SellOrders...)
+		followed.BuyBook = append(followed.BuyBook, token.BuyOrders...)
+	}
+
+	config := GetConfig()
+
+	// like the opportunities stage, a detector failing doesn't lose the snapshot
+	followed.Opportunities, err = RunDetectors(followed, config.Detectors, config.Filters, time.Now())
+
+	if err != nil {
+		fmt.Println("error:", err)
+	}
+
+	return followed, nil
+}
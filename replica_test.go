@@ -0,0 +1,64 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFollowedSnapshot(t *testing.T) {
+	previous := GetConfig()
+	defer SetConfig(previous)
+
+	config := DefaultConfig()
+	config.Detectors.Enabled = []string{DetectorPastReference, DetectorAccounts}
+	config.Detectors.Accounts = []string{"bob"}
+	SetConfig(config)
+
+	primary := &Snapshot{Version: 42, UpdatedAt: time.Unix(1_700_000_000, 0), Stale: true, Tokens: grpcTestTokens(), Stages: []StageStatus{{Stage: "prices", Error: "timeout"}}}
+
+	followed, err := followedSnapshot(newProtoSnapshot(primary, primary.Tokens, false))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the version is ours to give out when it's published
+	if followed.Version != 0 {
+		t.Fatalf("took the primary's version %d", followed.Version)
+	}
+
+	if !followed.UpdatedAt.Equal(primary.UpdatedAt) || !followed.Stale || len(followed.Stages) != 1 || followed.Stages[0].Stage != "prices" {
+		t.Fatalf("unexpected snapshot %+v", followed)
+	}
+
+	if len(followed.Tokens) != 1 || followed.Tokens[0].Symbol != "BTC" || len(followed.Tokens[0].BuyOrders) != 1 {
+		t.Fatalf("tokens came over as %+v", followed.Tokens)
+	}
+
+	// the fees and books come from the tokens, so the detectors have something to work on
+	if fee := followed.Fees["BTC"]; fee.Network != "Ethereum" || !fee.FlatFee.Equal(primary.Tokens[0].NetworkFlatFee) || !fee.PercentageFee.Equal(primary.Tokens[0].NetworkPercentageFee) {
+		t.Fatalf("fees came over as %+v", followed.Fees)
+	}
+
+	if len(followed.SellBook) != 0 || len(followed.BuyBook) != 1 || followed.BuyBook[0].TransactionID != "buy-1" {
+		t.Fatalf("books came over as %+v and %+v", followed.SellBook, followed.BuyBook)
+	}
+
+	if len(followed.Opportunities) != 2 || len(followed.Opportunities[0].Opportunities) != 1 || len(followed.Opportunities[1].Opportunities) != 1 {
+		t.Fatalf("the detectors found %+v", followed.Opportunities)
+	}
+
+	// a primary that doesn't say when falls back to now
+	protoSnapshot := newProtoSnapshot(primary, primary.Tokens, false)
+	protoSnapshot.UpdatedAt = 0
+
+	if followed, err = followedSnapshot(protoSnapshot); err != nil || time.Since(followed.UpdatedAt) > time.Minute {
+		t.Fatalf("followedSnapshot() = %+v, %v", followed, err)
+	}
+
+	protoSnapshot.Tokens[0].UsdPrice = "lots"
+
+	if _, err = followedSnapshot(protoSnapshot); err == nil {
+		t.Fatal("a bad snapshot from the primary was accepted")
+	}
+}
@@ -0,0 +1,272 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/CADawg/hive-swap-calculator/market"
+)
+
+// the upstreams we poll, each on its own schedule. A source is one or more pipeline stages.
+const (
+	SourcePrices  = "prices"
+	SourceHBDRate = "hbd_rate"
+	SourceBooks   = "books"
+	SourceFees    = "fees"
+)
+
+var AllSources = []string{SourcePrices, SourceHBDRate, SourceBooks, SourceFees}
+
+// SourceSchedule is how often to poll a source
+type SourceSchedule struct {
+	IntervalSeconds float64 `json:"interval_seconds"`
+	// FastIntervalSeconds is used instead while there's an opportunity open (0 to never speed up)
+	FastIntervalSeconds float64 `json:"fast_interval_seconds"`
+	// Jitter moves each poll by up to this fraction of the interval either way, so sources don't all line up
+	Jitter float64 `json:"jitter"`
+	// MaxBackoffSeconds caps how far errors and rate limits push the next poll out (0 means ten times the interval)
+	MaxBackoffSeconds float64 `json:"max_backoff_seconds"`
+}
+
+func (s SourceSchedule) interval(fast bool) time.Duration {
+	if fast && s.FastIntervalSeconds > 0 {
+		return time.Duration(s.FastIntervalSeconds * float64(time.Second))
+	}
+
+	return time.Duration(s.IntervalSeconds * float64(time.Second))
+}
+
+func (s SourceSchedule) maxBackoff() time.Duration {
+	if s.MaxBackoffSeconds > 0 {
+		return time.Duration(s.MaxBackoffSeconds * float64(time.Second))
+	}
+
+	return 10 * s.interval(false)
+}
+
+// SourceResult is how a source did when it was polled
+type SourceResult struct {
+	Failed      bool
+	RateLimited bool
+}
+
+// SourceStatus is where a source is in its schedule
+type SourceStatus struct {
+	Source  string
+	NextRun time.Time
+	// Failures is how many polls in a row have failed (a rate limit counts twice), it's what the backoff is based on
+	Failures int
+	// Fast is whether the last poll was scheduled at the fast interval
+	Fast bool
+}
+
+// Scheduler decides when each source is due, it doesn't run anything itself
+type Scheduler struct {
+	lock    sync.Mutex
+	sources map[string]*SourceStatus
+	// wake cuts a wait short, buffered so a trigger during a refresh isn't lost
+	wake chan struct{}
+}
+
+// NewScheduler has every source due straight away
+func NewScheduler() *Scheduler {
+	scheduler := &Scheduler{sources: map[string]*SourceStatus{}, wake: make(chan struct{}, 1)}
+
+	for _, source := range AllSources {
+		scheduler.sources[source] = &SourceStatus{Source: source}
+	}
+
+	return scheduler
+}
+
+// RefreshScheduler is the schedule the refresh loop follows
+var RefreshScheduler = NewScheduler()
+
+// scheduleFor is the configured schedule of a source, or the default if it's not in the config
+func scheduleFor(source string) SourceSchedule {
+	if schedule, ok := GetConfig().Schedule[source]; ok {
+		return schedule
+	}
+
+	return DefaultConfig().Schedule[source]
+}
+
+// Due lists the sources that should be polled now, in AllSources order
+func (s *Scheduler) Due(now time.Time) []string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	var due []string
+
+	for _, source := range AllSources {
+		if !s.sources[source].NextRun.After(now) {
+			due = append(due, source)
+		}
+	}
+
+	return due
+}
+
+// Done schedules the next poll of a source. Errors back off exponentially (rate limits twice as fast), and while
+// there's an opportunity open sources with a fast interval use it.
+func (s *Scheduler) Done(source string, result SourceResult, opportunityOpen bool, now time.Time) {
+	schedule := scheduleFor(source)
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	status := s.sources[source]
+
+	switch {
+	case result.RateLimited:
+		status.Failures += 2
+	case result.Failed:
+		status.Failures++
+	default:
+		status.Failures = 0
+	}
+
+	status.Fast = opportunityOpen && schedule.FastIntervalSeconds > 0 && status.Failures == 0
+
+	delay := schedule.interval(status.Fast)
+
+	for i := 0; i < status.Failures && delay < schedule.maxBackoff(); i++ {
+		delay *= 2
+	}
+
+	if schedule.Jitter > 0 {
+		delay += time.Duration((rand.Float64()*2 - 1) * schedule.Jitter * float64(delay))
+	}
+
+	if delay > schedule.maxBackoff() {
+		delay = schedule.maxBackoff()
+	}
+
+	status.NextRun = now.Add(delay)
+}
+
+// Trigger makes sources due now (all of them if none are given) and wakes the refresh loop
+func (s *Scheduler) Trigger(sources []string) ([]string, error) {
+	if len(sources) == 0 {
+		sources = AllSources
+	}
+
+	for _, source := range sources {
+		if !market.ContainsString(AllSources, source) {
+			return nil, errors.New("unknown source " + source + ", must be one of " + strings.Join(AllSources, ", "))
+		}
+	}
+
+	s.lock.Lock()
+	for _, source := range sources {
+		s.sources[source].NextRun = time.Time{}
+	}
+	s.lock.Unlock()
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+		// already waiting to be woken
+	}
+
+	return sources, nil
+}
+
+// NextRun is when the next source is due
+func (s *Scheduler) NextRun() time.Time {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	var next time.Time
+
+	for _, status := range s.sources {
+		if next.IsZero() || status.NextRun.Before(next) {
+			next = status.NextRun
+		}
+	}
+
+	return next
+}
+
+// Wait sleeps until the next source is due, or something is triggered
+func (s *Scheduler) Wait() {
+	s.Sleep(time.Until(s.NextRun()))
+}
+
+// Sleep sleeps for d, or until something is triggered
+func (s *Scheduler) Sleep(d time.Duration) {
+	if d <= 0 {
+		return
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+	case <-s.wake:
+	}
+}
+
+// Statuses is where every source is in its schedule, in AllSources order
+func (s *Scheduler) Statuses() []SourceStatus {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	var statuses []SourceStatus
+
+	for _, source := range AllSources {
+		statuses = append(statuses, *s.sources[source])
+	}
+
+	return statuses
+}
+
+// RunScheduledRefreshes is the refresh loop, it polls each source when it's due and publishes a snapshot whenever
+// anything was polled. beforeCycle runs first each time round (it's where config reloads are swapped in).
+func RunScheduledRefreshes(beforeCycle func()) {
+	for {
+		beforeCycle()
+
+		due := RefreshScheduler.Due(time.Now())
+
+		if len(due) > 0 {
+			snapshot, report, err := RefreshPipeline.RunSources(CurrentSnapshot(), due)
+
+			// a failed refresh means we've never had prices, keep whatever we're serving (maybe from disk) rather than nothing
+			if err != nil {
+				fmt.Println("error:", err)
+			} else {
+				PublishSnapshot(snapshot)
+				SaveSnapshot(snapshot)
+			}
+
+			opportunityOpen := opportunityOpen(CurrentSnapshot())
+			now := time.Now()
+
+			for _, source := range due {
+				RefreshScheduler.Done(source, report.SourceResult(source), opportunityOpen, now)
+			}
+		}
+
+		RefreshScheduler.Wait()
+	}
+}
+
+// opportunityOpen is whether any detector found an order with an edge (a positive score), the results only hold
+// orders that passed the configured filters (RunDetectors drops the rest)
+func opportunityOpen(snapshot *Snapshot) bool {
+	for _, result := range snapshot.Opportunities {
+		for _, opportunity := range result.Opportunities {
+			if opportunity.Score.IsPositive() {
+				return true
+			}
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+func withSchedule(t *testing.T, schedule SourceSchedule) {
+	t.Helper()
+
+	previous := GetConfig()
+	t.Cleanup(func() { SetConfig(previous) })
+
+	config := DefaultConfig()
+	config.Schedule = map[string]SourceSchedule{}
+
+	for _, source := range AllSources {
+		config.Schedule[source] = schedule
+	}
+
+	SetConfig(config)
+}
+
+func TestSchedulerBackoff(t *testing.T) {
+	withSchedule(t, SourceSchedule{IntervalSeconds: 10, FastIntervalSeconds: 2, MaxBackoffSeconds: 60})
+
+	scheduler := NewScheduler()
+	now := time.Unix(1_000_000, 0)
+
+	if due := scheduler.Due(now); len(due) != len(AllSources) {
+		t.Fatalf("a new scheduler has %v due, want everything", due)
+	}
+
+	delay := func() time.Duration {
+		for _, status := range scheduler.Statuses() {
+			if status.Source == SourcePrices {
+				return status.NextRun.Sub(now)
+			}
+		}
+
+		return 0
+	}
+
+	tests := []struct {
+		name   string
+		result SourceResult
+		open   bool
+		want   time.Duration
+	}{
+		{"worked", SourceResult{}, false, 10 * time.Second},
+		{"worked with an opportunity open", SourceResult{}, true, 2 * time.Second},
+		{"failed once", SourceResult{Failed: true}, true, 20 * time.Second},
+		{"failed twice", SourceResult{Failed: true}, false, 40 * time.Second},
+		{"rate limited", SourceResult{Failed: true, RateLimited: true}, false, 60 * time.Second},
+		{"worked again", SourceResult{}, false, 10 * time.Second},
+		{"rate limits count twice", SourceResult{Failed: true, RateLimited: true}, false, 40 * time.Second},
+	}
+
+	for _, test := range tests {
+		scheduler.Done(SourcePrices, test.result, test.open, now)
+
+		if got := delay(); got != test.want {
+			t.Fatalf("%s: next poll in %s, want %s", test.name, got, test.want)
+		}
+	}
+
+	if due := scheduler.Due(now); len(due) != len(AllSources)-1 || due[0] == SourcePrices {
+		t.Fatalf("due = %v, want everything but prices", due)
+	}
+}
+
+func TestSchedulerDefaultMaxBackoff(t *testing.T) {
+	withSchedule(t, SourceSchedule{IntervalSeconds: 10})
+
+	scheduler := NewScheduler()
+	now := time.Unix(1_000_000, 0)
+
+	for i := 0; i < 20; i++ {
+		scheduler.Done(SourceBooks, SourceResult{Failed: true}, false, now)
+	}
+
+	// ten times the interval, and no fast interval to speed up to
+	if next := scheduler.Statuses()[2].NextRun; next.Sub(now) != 100*time.Second {
+		t.Fatalf("backed off to %s, want 100s", next.Sub(now))
+	}
+
+	scheduler.Done(SourceBooks, SourceResult{}, true, now)
+
+	if status := scheduler.Statuses()[2]; status.Fast || status.Failures != 0 || status.NextRun.Sub(now) != 10*time.Second {
+		t.Fatalf("status after recovering = %+v", status)
+	}
+}
+
+func TestSchedulerJitter(t *testing.T) {
+	withSchedule(t, SourceSchedule{IntervalSeconds: 10, Jitter: 0.2})
+
+	scheduler := NewScheduler()
+	now := time.Unix(1_000_000, 0)
+
+	seen := map[time.Duration]bool{}
+
+	for i := 0; i < 100; i++ {
+		scheduler.Done(SourceFees, SourceResult{}, false, now)
+
+		delay := scheduler.Statuses()[3].NextRun.Sub(now)
+
+		if delay < 8*time.Second || delay > 12*time.Second {
+			t.Fatalf("jittered delay %s is more than 20%% off 10s", delay)
+		}
+
+		seen[delay] = true
+	}
+
+	if len(seen) < 2 {
+		t.Fatal("the jitter never moved the poll")
+	}
+}
+
+func TestSchedulerTrigger(t *testing.T) {
+	withSchedule(t, SourceSchedule{IntervalSeconds: 10})
+
+	scheduler := NewScheduler()
+	now := time.Now()
+
+	for _, source := range AllSources {
+		scheduler.Done(source, SourceResult{}, false, now)
+	}
+
+	if _, err := scheduler.Trigger([]string{"nope"}); err == nil {
+		t.Fatal("triggered an unknown source")
+	}
+
+	if _, err := scheduler.Trigger([]string{SourceHBDRate}); err != nil {
+		t.Fatal(err)
+	}
+
+	if due := scheduler.Due(now); len(due) != 1 || due[0] != SourceHBDRate {
+		t.Fatalf("due = %v, want [%s]", due, SourceHBDRate)
+	}
+
+	// the trigger wakes whatever's waiting
+	started := time.Now()
+	scheduler.Sleep(5 * time.Second)
+
+	if time.Since(started) > 2*time.Second {
+		t.Fatal("the trigger didn't cut the sleep short")
+	}
+}
+
+func TestOpportunityOpen(t *testing.T) {
+	opportunity := func(score int64) Opportunity {
+		return Opportunity{Score: decimal.NewFromInt(score)}
+	}
+
+	tests := []struct {
+		name    string
+		results []DetectorResult
+		want    bool
+	}{
+		{"no detectors", nil, false},
+		{"nothing found", []DetectorResult{{Detector: DetectorPastReference}}, false},
+		{"no edge", []DetectorResult{{Detector: DetectorAccounts, Opportunities: []Opportunity{opportunity(0), opportunity(-5)}}}, false},
+		{"an edge", []DetectorResult{{Detector: DetectorAccounts}, {Detector: DetectorPastReference, Opportunities: []Opportunity{opportunity(3)}}}, true},
+	}
+
+	for _, test := range tests {
+		if open := opportunityOpen(&Snapshot{Opportunities: test.results}); open != test.want {
+			t.Errorf("%s: opportunityOpen() = %v, want %v", test.name, open, test.want)
+		}
+	}
+}
+
+func TestUpstreamClientTimesOut(t *testing.T) {
+	if upstreamClient.Timeout <= 0 {
+		t.Fatal("upstream requests can hang forever")
+	}
+}
@@ -0,0 +1,445 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/CADawg/hive-swap-calculator/frontend"
+	"github.com/CADawg/hive-swap-calculator/market"
+	"github.com/CADawg/hive-swap-calculator/pricing"
+	"github.com/goccy/go-json"
+)
+
+// APIV1 is the prefix of the versioned api, anything under it only changes in backwards compatible ways
+const APIV1 = "/api/v1"
+
+// the order filter and prices query parameters, shared by every route that returns orders
+var orderQueryParameters = []APIParameter{
+	{Name: "side", In: "query", Type: "string", Enum: []string{SideBoth, market.SideBuy, market.SideSell}, Description: "Only return orders on this side"},
+	{Name: "min_profit", In: "query", Type: "number", Description: "Only return orders at least this far past the reference price (compared with profit_percentage, so a percentage for sell orders and a fraction for buy orders)"},
+	{Name: "limit", In: "query", Type: "integer", Description: "Maximum number of orders per side"},
+	{Name: "sort", In: "query", Type: "string", Description: "Sort orders by profit, price, quantity, value, expiration or timestamp (prefix with - for descending)"},
+	{Name: "min_hive_value", In: "query", Type: "number", Description: "Hide orders worth less than this in HIVE"},
+	{Name: "min_seconds_to_expiry", In: "query", Type: "integer", Description: "Hide orders expiring sooner than this"},
+	{Name: "exclude_dust", In: "query", Type: "boolean", Description: "Hide orders that wouldn't cover the flat withdrawal fee"},
+	{Name: "allow_accounts", In: "query", Type: "string", Description: "Comma separated accounts, only their orders are returned"},
+	{Name: "deny_accounts", In: "query", Type: "string", Description: "Comma separated accounts whose orders are hidden"},
+	{Name: "format", In: "query", Type: "string", Enum: []string{"json", "ndjson", "csv", "msgpack"}, Description: "Response format, overrides the Accept header"},
+}
+
+var allFormats = []string{ContentTypeNDJSON, ContentTypeCSV, ContentTypeMsgPack}
+
+// APIRoutes are all the routes of the versioned api
+func APIRoutes() []APIRoute {
+	return []APIRoute{
+		{
+			Path:        APIV1 + "/prices",
+			Summary:     "Every token with its prices, fees and orders",
+			Description: "Also served at /prices for older clients.",
+			Parameters: append([]APIParameter{
+				{Name: "symbols", In: "query", Type: "string", Description: "Comma separated symbols (BTC or SWAP.BTC) to return"},
+				{Name: "fields", In: "query", Type: "string", Description: "Comma separated token fields to return, the rest are left out"},
+			}, orderQueryParameters...),
+			Response:       []APIToken{},
+			Formats:        allFormats,
+			RateLimitClass: RateLimitDefault,
+			Cached:         true,
+			Handler:        handlePrices,
+		},
+		{
+			Path:    APIV1 + "/tokens",
+			Summary: "Every token with its prices and fees, without orders",
+			Parameters: []APIParameter{
+				{Name: "symbols", In: "query", Type: "string", Description: "Comma separated symbols (BTC or SWAP.BTC) to return"},
+				{Name: "fields", In: "query", Type: "string", Description: "Comma separated token fields to return, the rest are left out"},
+				{Name: "format", In: "query", Type: "string", Enum: []string{"json", "ndjson", "csv", "msgpack"}, Description: "Response format, overrides the Accept header"},
+			},
+			Response:       []APIToken{},
+			Formats:        allFormats,
+			Scope:          ScopeReadPrices,
+			RateLimitClass: RateLimitDefault,
+			Cached:         true,
+			Handler:        handleTokens,
+		},
+		{
+			Path:    APIV1 + "/tokens/{symbol}",
+			Summary: "One token with its prices, fees and orders",
+			Parameters: append([]APIParameter{
+				{Name: "symbol", In: "path", Type: "string", Description: "Symbol, e.g. BTC or SWAP.BTC"},
+			}, orderQueryParameters...),
+			Response:       APIToken{},
+			Formats:        allFormats,
+			Scope:          ScopeReadPrices,
+			RateLimitClass: RateLimitDefault,
+			Cached:         true,
+			Handler:        handleToken,
+		},
+		{
+			Path:    APIV1 + "/tokens/{symbol}/orders",
+			Summary: "A token's orders that are priced past its reference price",
+			Parameters: append([]APIParameter{
+				{Name: "symbol", In: "path", Type: "string", Description: "Symbol, e.g. BTC or SWAP.BTC"},
+			}, orderQueryParameters...),
+			Response:       []APIOrder{},
+			Formats:        allFormats,
+			Scope:          ScopeReadPrices,
+			RateLimitClass: RateLimitDefault,
+			Cached:         true,
+			Handler:        handleTokenOrders,
+		},
+		{
+			Path:    APIV1 + "/orders/{txId}",
+			Summary: "One order by its Hive Engine transaction id",
+			Parameters: []APIParameter{
+				{Name: "txId", In: "path", Type: "string", Description: "Hive Engine transaction id"},
+				{Name: "format", In: "query", Type: "string", Enum: []string{"json", "ndjson", "csv", "msgpack"}, Description: "Response format, overrides the Accept header"},
+			},
+			Response:       APIOrder{},
+			Formats:        allFormats,
+			Scope:          ScopeReadPrices,
+			RateLimitClass: RateLimitDefault,
+			Cached:         true,
+			Handler:        handleOrder,
+		},
+		{
+			Path:           APIV1 + "/status",
+			Summary:        "How fresh the current snapshot is, stage by stage",
+			Description:    "Every response with a snapshot has an X-Degraded-Stages header listing the stages that failed last time (their data is older), this has the details, along with how long each stage of the last refresh took.",
+			Response:       APIStatus{},
+			RateLimitClass: RateLimitDefault,
+			Handler:        handleStatus,
+		},
+		{
+			Path:        APIV1 + "/deltas",
+			Summary:     "What's changed since a snapshot version",
+			Description: "Every response with a full snapshot has an X-Snapshot-Version header, send that as since to get just the changes after it. With wait the request is held open until there's a newer version.",
+			Parameters: []APIParameter{
+				{Name: "since", In: "query", Type: "integer", Required: true, Description: "Snapshot version you have (0 for everything)"},
+				{Name: "wait", In: "query", Type: "integer", Description: "Seconds to wait for a newer version if there isn't one yet (at most 60)"},
+			},
+			Response:       APIDelta{},
+			Scope:          ScopeReadPrices,
+			RateLimitClass: RateLimitDefault,
+			Handler:        handleDeltas,
+		},
+	}
+}
+
+// AllRoutes is the public api (with the opportunity detectors) and the admin api together
+func AllRoutes() []APIRoute {
+	return append(append(APIRoutes(), OpportunityRoutes()...), AdminRoutes()...)
+}
+
+// NewServeMux sets up every route, the api (with its middleware), the frontend config and the frontend itself
+func NewServeMux(limiter *RateLimiter) *http.ServeMux {
+	mux := http.NewServeMux()
+	routes := AllRoutes()
+	handlers := make([]http.Handler, len(routes))
+
+	for i, route := range routes {
+		handler := http.Handler(onlyGet(route.Handler))
+
+		if route.Method != "" && route.Method != http.MethodGet {
+			handler = onlyMethod(route.Method, route.Handler)
+		}
+
+		if route.Cached {
+			handler = CacheAndCompress(handler, TimeUntilNextRefresh)
+		}
+
+		if route.Scope != "" {
+			handler = RequireScope(route.Scope, handler)
+		}
+
+		// public routes are still rate limited, an api key just gets you your own limits. Bad keys count against the
+		// client's ip like any other request, so guessing keys isn't free.
+		handlers[i] = Authenticate(APIKeys, limiter.Limit(route.RateLimitClass, http.HandlerFunc(InvalidAPIKey)), limiter.Limit(route.RateLimitClass, handler))
+
+		// older clients
+		if route.Path == APIV1+"/prices" {
+			mux.Handle("/prices", CORS(handlers[i]))
+		}
+	}
+
+	// the mux can't match {params} (on go 1.20), so everything under the api goes through here
+	mux.Handle(APIV1+"/", CORS(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for i, route := range routes {
+			params, ok := matchPath(route.Path, r.URL.Path)
+
+			if ok {
+				handlers[i].ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), pathParamsKey{}, params)))
+				return
+			}
+		}
+
+		WriteAPIError(w, http.StatusNotFound, "no such api endpoint")
+	})))
+
+	openAPI, err := json.Marshal(OpenAPIDocument(routes))
+
+	if err != nil {
+		panic("error building openapi document: " + err.Error())
+	}
+
+	mux.Handle(APIV1+"/openapi.json", CORS(onlyGet(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(openAPI)
+	})))
+
+	// lets the frontend know where the api is when it's hosted somewhere else
+	mux.Handle("/config.json", CORS(onlyGet(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "no-cache")
+
+		writeJSON(w, FrontendConfig{APIBaseURL: GetConfig().APIBaseURL})
+	})))
+
+	// static files at / apart from the api
+	mux.Handle("/", http.FileServer(http.FS(frontend.RootDir)))
+
+	return mux
+}
+
+type pathParamsKey struct{}
+
+// PathParam is the value of a {name} segment of the route's path
+func PathParam(r *http.Request, name string) string {
+	params, _ := r.Context().Value(pathParamsKey{}).(map[string]string)
+	return params[name]
+}
+
+// matchPath matches a path against a pattern like /api/v1/tokens/{symbol}, returning the {params}
+func matchPath(pattern string, path string) (map[string]string, bool) {
+	patternParts := strings.Split(strings.Trim(pattern, "/"), "/")
+	pathParts := strings.Split(strings.Trim(path, "/"), "/")
+
+	if len(patternParts) != len(pathParts) {
+		return nil, false
+	}
+
+	params := map[string]string{}
+
+	for i, part := range patternParts {
+		if strings.HasPrefix(part, "{") && strings.HasSuffix(part, "}") {
+			value, err := url.PathUnescape(pathParts[i])
+
+			if err != nil || value == "" {
+				return nil, false
+			}
+
+			params[strings.Trim(part, "{}")] = value
+		} else if part != pathParts[i] {
+			return nil, false
+		}
+	}
+
+	return params, true
+}
+
+func onlyGet(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			WriteAPIError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+func onlyMethod(method string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != method {
+			w.Header().Set("Allow", method)
+			WriteAPIError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// WriteAPIError sends an APIError with the status
+func WriteAPIError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+
+	_ = json.NewEncoder(w).Encode(APIError{Error: message})
+}
+
+func handlePrices(w http.ResponseWriter, r *http.Request) {
+	// the config filters can be overridden per request
+	query, err := ParsePricesQuery(r.URL.Query(), GetConfig())
+
+	if err != nil {
+		WriteAPIError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	snapshot := CurrentSnapshot()
+
+	output, err := query.Output(query.Apply(snapshot.Tokens, snapshot.Now()))
+
+	setSnapshotHeaders(w, snapshot)
+
+	if err != nil {
+		WriteAPIError(w, http.StatusInternalServerError, "error encoding prices")
+		return
+	}
+
+	WriteTokens(w, r, output)
+}
+
+// setSnapshotHeaders tells the client which version they've got (so they can ask for deltas since it), whether it's
+// an old snapshot from disk we're serving until the first refresh after a restart, and which stages are degraded
+// (the details are at /api/v1/status)
+func setSnapshotHeaders(w http.ResponseWriter, snapshot *Snapshot) {
+	w.Header().Set("X-Snapshot-Version", strconv.FormatUint(snapshot.Version, 10))
+
+	if snapshot.Stale {
+		w.Header().Set("X-Snapshot-Stale", "true")
+	}
+
+	var degraded []string
+
+	for _, stage := range snapshot.Stages {
+		if stage.Degraded() {
+			degraded = append(degraded, stage.Stage)
+		}
+	}
+
+	if len(degraded) > 0 {
+		w.Header().Set("X-Degraded-Stages", strings.Join(degraded, ","))
+	}
+}
+
+func handleTokens(w http.ResponseWriter, r *http.Request) {
+	query, err := ParsePricesQuery(r.URL.Query(), GetConfig())
+
+	if err != nil {
+		WriteAPIError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	snapshot := CurrentSnapshot()
+
+	tokens := query.Apply(snapshot.Tokens, snapshot.Now())
+
+	setSnapshotHeaders(w, snapshot)
+
+	// orders have their own endpoints
+	for i := range tokens {
+		tokens[i].SellOrders = nil
+		tokens[i].BuyOrders = nil
+	}
+
+	output, err := query.Output(tokens)
+
+	if err != nil {
+		WriteAPIError(w, http.StatusInternalServerError, "error encoding tokens")
+		return
+	}
+
+	WriteTokens(w, r, output)
+}
+
+// lookupToken gets the {symbol} token from the current index, with the query applied to its orders
+func lookupToken(w http.ResponseWriter, r *http.Request) (pricing.TokenData, PricesQuery, bool) {
+	query, err := ParsePricesQuery(r.URL.Query(), GetConfig())
+
+	if err != nil {
+		WriteAPIError(w, http.StatusBadRequest, err.Error())
+		return pricing.TokenData{}, query, false
+	}
+
+	snapshot := CurrentSnapshot()
+
+	setSnapshotHeaders(w, snapshot)
+
+	token, ok := snapshot.Index.Token(PathParam(r, "symbol"))
+
+	if !ok {
+		WriteAPIError(w, http.StatusNotFound, "no token with symbol "+PathParam(r, "symbol"))
+		return pricing.TokenData{}, query, false
+	}
+
+	// the symbol comes from the path, not ?symbols=
+	query.Symbols = nil
+
+	return query.Apply([]pricing.TokenData{token}, snapshot.Now())[0], query, true
+}
+
+func handleToken(w http.ResponseWriter, r *http.Request) {
+	token, query, ok := lookupToken(w, r)
+
+	if !ok {
+		return
+	}
+
+	output, err := query.Output([]pricing.TokenData{token})
+
+	if err != nil {
+		WriteAPIError(w, http.StatusInternalServerError, "error encoding token")
+		return
+	}
+
+	writeFirstToken(w, r, output)
+}
+
+// writeFirstToken writes the one token in what PricesQuery.Output made
+func writeFirstToken(w http.ResponseWriter, r *http.Request, output interface{}) {
+	switch tokens := output.(type) {
+	case []APIToken:
+		WriteToken(w, r, tokens[0])
+	case []map[string]json.RawMessage:
+		WriteToken(w, r, tokens[0])
+	default:
+		// Output has grown a new type this hasn't caught up with, better a clear error than an empty 200
+		WriteAPIError(w, http.StatusInternalServerError, "error encoding token: unexpected output type")
+	}
+}
+
+func handleTokenOrders(w http.ResponseWriter, r *http.Request) {
+	token, _, ok := lookupToken(w, r)
+
+	if !ok {
+		return
+	}
+
+	orders := make([]APIOrder, 0, len(token.SellOrders)+len(token.BuyOrders))
+
+	for _, order := range token.SellOrders {
+		orders = append(orders, NewAPIOrderWithSide(order, market.SideSell))
+	}
+
+	for _, order := range token.BuyOrders {
+		orders = append(orders, NewAPIOrderWithSide(order, market.SideBuy))
+	}
+
+	WriteOrders(w, r, orders)
+}
+
+func handleOrder(w http.ResponseWriter, r *http.Request) {
+	snapshot := CurrentSnapshot()
+
+	setSnapshotHeaders(w, snapshot)
+
+	order, ok := snapshot.Index.FilteredOrder(PathParam(r, "txId"), GetConfig().Filters, snapshot.Now())
+
+	if !ok {
+		WriteAPIError(w, http.StatusNotFound, "no order with transaction id "+PathParam(r, "txId"))
+		return
+	}
+
+	WriteOrder(w, r, NewAPIOrderWithSide(order.Order, order.Side))
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestMatchPath(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		params  map[string]string
+		ok      bool
+	}{
+		{"/api/v1/prices", "/api/v1/prices", map[string]string{}, true},
+		{"/api/v1/prices", "/api/v1/prices/", map[string]string{}, true},
+		{"/api/v1/tokens/{symbol}", "/api/v1/tokens/BTC", map[string]string{"symbol": "BTC"}, true},
+		{"/api/v1/tokens/{symbol}", "/api/v1/tokens/SWAP%2EBTC", map[string]string{"symbol": "SWAP.BTC"}, true},
+		{"/api/v1/tokens/{symbol}", "/api/v1/tokens", nil, false},
+		{"/api/v1/tokens/{symbol}", "/api/v1/tokens/BTC/orders", nil, false},
+		{"/api/v1/tokens/{symbol}/orders", "/api/v1/tokens/BTC/orders", map[string]string{"symbol": "BTC"}, true},
+		{"/api/v1/tokens/{symbol}/orders", "/api/v1/tokens/BTC/trades", nil, false},
+		{"/api/v1/tokens/{symbol}", "/api/v1/tokens/%zz", nil, false},
+	}
+
+	for _, test := range tests {
+		params, ok := matchPath(test.pattern, test.path)
+
+		if ok != test.ok || (ok && !reflect.DeepEqual(params, test.params)) {
+			t.Errorf("matchPath(%s, %s) = %v, %v, want %v, %v", test.pattern, test.path, params, ok, test.params, test.ok)
+		}
+	}
+}
+
+func TestServeMuxRoutes(t *testing.T) {
+	mux := NewServeMux(NewRateLimiter())
+
+	tests := []struct {
+		method string
+		path   string
+		want   int
+	}{
+		{"GET", "/api/v1/prices", http.StatusOK},
+		{"HEAD", "/api/v1/prices", http.StatusOK},
+		{"GET", "/prices", http.StatusOK},
+		{"POST", "/api/v1/prices", http.StatusMethodNotAllowed},
+		{"GET", "/api/v1/nothing", http.StatusNotFound},
+		// the richer endpoints need a key, /prices doesn't
+		{"GET", "/api/v1/tokens/NOPE", http.StatusUnauthorized},
+		{"GET", "/api/v1/orders/nope", http.StatusUnauthorized},
+		{"GET", "/api/v1/deltas?since=0", http.StatusUnauthorized},
+		{"GET", "/api/v1/opportunities", http.StatusUnauthorized},
+		{"GET", "/api/v1/status", http.StatusOK},
+		{"GET", "/api/v1/prices?limit=0", http.StatusBadRequest},
+		{"GET", "/api/v1/openapi.json", http.StatusOK},
+		{"GET", "/config.json", http.StatusOK},
+		{"GET", "/api/v1/admin/state", http.StatusUnauthorized},
+	}
+
+	for _, test := range tests {
+		r := httptest.NewRequest(test.method, test.path, nil)
+		w := httptest.NewRecorder()
+
+		mux.ServeHTTP(w, r)
+
+		if w.Code != test.want {
+			t.Errorf("%s %s got %d, want %d: %s", test.method, test.path, w.Code, test.want, w.Body.String())
+		}
+	}
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/CADawg/hive-swap-calculator/engine"
+	"github.com/CADawg/hive-swap-calculator/gateway"
+	"github.com/CADawg/hive-swap-calculator/hive"
+	"github.com/CADawg/hive-swap-calculator/pricing"
+	"github.com/CADawg/hive-swap-calculator/upstream"
+)
+
+var simulatedTestTokens = []pricing.RegistryToken{
+	{CoinGeckoID: "hive", Symbol: "HIVE"},
+	{CoinGeckoID: "hive_dollar", Symbol: "HBD"},
+	{CoinGeckoID: "bitcoin", Symbol: "BTC"},
+	{CoinGeckoID: "tether", Symbol: "USDT"},
+}
+
+// simulatedTestConfig is the default simulation, seeded, with no ticks, latency or faults in the way
+func simulatedTestConfig() SimulateConfig {
+	config := DefaultConfig().Simulate
+	config.Seed = 42
+	config.TickSeconds = 3600
+	config.LatencyMs = 0
+	config.OrdersPerBook = 5
+
+	return config
+}
+
+func simulatedClient(market *SimulatedMarket) *http.Client {
+	return &http.Client{Transport: market}
+}
+
+func TestSimulatedMarketIsDeterministic(t *testing.T) {
+	books := func() ([]engine.MarketOrder, []engine.MarketOrder) {
+		client := engine.NewClient(engine.DefaultNode, simulatedClient(NewSimulatedMarket(simulatedTestConfig(), simulatedTestTokens)))
+
+		sellBook, err := client.GetAllSwapSellOrders()
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		buyBook, err := client.GetAllSwapBuyOrders()
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return sellBook, buyBook
+	}
+
+	firstSells, firstBuys := books()
+	secondSells, secondBuys := books()
+
+	if len(firstSells) != len(secondSells) || len(firstBuys) != len(secondBuys) {
+		t.Fatal("the same seed made books of different sizes")
+	}
+
+	for i := range firstSells {
+		if firstSells[i].TransactionID != secondSells[i].TransactionID || !firstSells[i].Price.Equal(secondSells[i].Price) || firstSells[i].Account != secondSells[i].Account {
+			t.Fatalf("sell order %d differs: %+v and %+v", i, firstSells[i], secondSells[i])
+		}
+	}
+
+	for i := range firstBuys {
+		if firstBuys[i].TransactionID != secondBuys[i].TransactionID || !firstBuys[i].Quantity.Equal(secondBuys[i].Quantity) {
+			t.Fatalf("buy order %d differs: %+v and %+v", i, firstBuys[i], secondBuys[i])
+		}
+	}
+}
+
+func TestSimulatedMarketServesItsTokens(t *testing.T) {
+	previous := GetConfig()
+	defer SetConfig(previous)
+
+	market := NewSimulatedMarket(simulatedTestConfig(), simulatedTestTokens)
+	client := simulatedClient(market)
+
+	// a reload changing the tokens doesn't change the market, its books and gateways stay in step
+	config := DefaultConfig()
+	config.Tokens = append(config.Tokens, pricing.RegistryToken{CoinGeckoID: "made-up", Symbol: "MADE"})
+	SetConfig(config)
+
+	sellBook, err := engine.NewClient(engine.DefaultNode, client).GetAllSwapSellOrders()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	perSymbol := map[string]int{}
+
+	for _, order := range sellBook {
+		perSymbol[order.Symbol]++
+	}
+
+	// every token but HIVE, which everything's priced in
+	if len(perSymbol) != 3 || perSymbol["SWAP.BTC"] != 5 || perSymbol["SWAP.HBD"] != 5 || perSymbol["SWAP.USDT"] != 5 {
+		t.Fatalf("sell book has %v", perSymbol)
+	}
+
+	prices, err := pricing.NewClient(pricing.DefaultCoinGeckoAPI, client).LoadPriceAndSymbolData(simulatedTestTokens)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(prices) != len(simulatedTestTokens) {
+		t.Fatalf("got %d prices, want %d", len(prices), len(simulatedTestTokens))
+	}
+
+	rate, err := hive.NewClient(hive.DefaultNode, client).FetchBlockchainHiveHBDRate()
+
+	if err != nil || !rate.IsPositive() {
+		t.Fatalf("HBD rate = %s, %v", rate, err)
+	}
+
+	gateways := gateway.NewClient(client, Gateways.Networks())
+
+	tokens, err := gateways.LoadTokenNetworkData()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(tokens) != len(gateway.DefaultTokens)+5 {
+		t.Fatalf("got %d gateway tokens, want the defaults and the 5 simulated ones", len(tokens))
+	}
+
+	fee, err := gateways.WithdrawalFee("Ethereum", "SWAP.USDT")
+
+	if err != nil || !fee.IsPositive() {
+		t.Fatalf("withdrawal fee = %s, %v", fee, err)
+	}
+}
+
+func TestSimulatedMarketFaults(t *testing.T) {
+	config := simulatedTestConfig()
+	config.RateLimitRate = 1
+
+	_, err := engine.NewClient(engine.DefaultNode, simulatedClient(NewSimulatedMarket(config, simulatedTestTokens))).GetAllSwapSellOrders()
+
+	if !errors.Is(err, upstream.ErrRateLimited) {
+		t.Fatalf("got %v, want a rate limit", err)
+	}
+
+	config.RateLimitRate = 0
+	config.ErrorRate = 1
+
+	if _, err = hive.NewClient(hive.DefaultNode, simulatedClient(NewSimulatedMarket(config, simulatedTestTokens))).FetchBlockchainHiveHBDRate(); err == nil {
+		t.Fatal("expected an error")
+	}
+
+	// anything else isn't simulated
+	if _, err = upstream.GetJSON[map[string]interface{}](simulatedClient(NewSimulatedMarket(simulatedTestConfig(), nil)), "https://example.com/"); err == nil {
+		t.Fatal("simulated a host it doesn't know")
+	}
+}
+
+func TestSimulatedMarketRoundTrip(t *testing.T) {
+	config := simulatedTestConfig()
+	config.ErrorRate = 1
+
+	req, err := http.NewRequest("GET", CoinGecko.API()+"/simple/price?ids=hive", nil)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := NewSimulatedMarket(config, simulatedTestTokens).RoundTrip(req)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a response like a real transport's, status line and all
+	if resp.StatusCode != http.StatusInternalServerError || resp.Status != "500 Internal Server Error" || resp.Request != req ||
+		resp.ContentLength != int64(len(body)) || !strings.Contains(string(body), "simulated error") {
+		t.Fatalf("got %+v with %q", resp, body)
+	}
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/CADawg/hive-swap-calculator/engine"
+	"github.com/CADawg/hive-swap-calculator/gateway"
+	"github.com/CADawg/hive-swap-calculator/pricing"
+	"github.com/shopspring/decimal"
+)
+
+// RefreshInterval is how long a replica waits between tries at the primary (and how long clients cache a snapshot
+// when nothing's scheduled, like on a replica)
+const RefreshInterval = 10 * time.Second
+
+// how many past versions we can give deltas from (an hour's worth at the normal refresh interval)
+const maxTokensHistory = 360
+
+// Snapshot is everything one refresh produced. It's never modified once it's published, so anyone holding one can
+// read it without a lock (and without seeing half of the next refresh).
+type Snapshot struct {
+	// Version goes up by one every time a snapshot is published, clients use it to ask for what's changed
+	Version   uint64
+	UpdatedAt time.Time
+	// Stale is set on a snapshot loaded from disk, and stays set until every stage has worked since the restart (a
+	// stage that fails falls back to the data from disk)
+	Stale bool
+	// Stages is how each stage of the refresh did
+	Stages []StageStatus
+	// Clock is the time orders are filtered (and detected) at, zero for the real time. A replay sets it to when the
+	// cycle was recorded, so the same capture always gives the same books.
+	Clock time.Time
+
+	// Prices are straight from CoinGecko (with symbols added), before the HBD rate, fees or orders
+	Prices  []pricing.TokenData
+	HBDRate decimal.Decimal
+	// Fees are by symbol
+	Fees map[string]gateway.TokenFee
+	// SellBook and BuyBook are every SWAP. order, not just the ones past the reference price (except on a replica
+	// following a primary, which only sends those)
+	SellBook []engine.MarketOrder
+	BuyBook  []engine.MarketOrder
+
+	// Tokens is what the api serves, the prices with the HBD rate, fees and orders past the reference price added
+	Tokens []pricing.TokenData
+	Index  *TokenIndex
+
+	// Opportunities are what each enabled detector found in the tokens and books
+	Opportunities []DetectorResult
+}
+
+var currentSnapshot atomic.Pointer[Snapshot]
+
+// snapshotsLock is only for publishing (and waiting for) new versions, readers use CurrentSnapshot
+var snapshotsLock sync.Mutex
+
+// snapshotChanged is closed (and replaced) whenever a new version is published, so long polls can wait on it
+var snapshotChanged = make(chan struct{})
+
+// snapshotHistory is the index of each recent version, for working out deltas. They're filtered the way the api served
+// them when they were published, so a delta never has an order the client couldn't have seen.
+var snapshotHistory = map[uint64]*TokenIndex{}
+
+// versionEpoch is this process's first version, the time it started in milliseconds. Versions don't survive a restart,
+// so this keeps them from being reused: one from before a restart is always lower than ours and can only get a reset,
+// never a delta against whatever we've since published under the same number. (Catching up with the next process's
+// epoch would take over 1000 snapshots a second.)
+var versionEpoch = uint64(time.Now().UnixMilli())
+
+func init() {
+	currentSnapshot.Store(&Snapshot{Index: NewTokenIndex(nil)})
+}
+
+// Now is the time to filter the snapshot's orders at, see Clock
+func (s *Snapshot) Now() time.Time {
+	if s.Clock.IsZero() {
+		return time.Now()
+	}
+
+	return s.Clock
+}
+
+// CurrentSnapshot is the latest published snapshot (an empty one before the first)
+func CurrentSnapshot() *Snapshot {
+	return currentSnapshot.Load()
+}
+
+// PublishSnapshot makes snapshot the current one, under the next version, and tells the event bus what changed. Don't
+// touch it after this.
+func PublishSnapshot(snapshot *Snapshot) {
+	if snapshot.Index == nil {
+		snapshot.Index = NewTokenIndex(snapshot.Tokens)
+	}
+
+	snapshotsLock.Lock()
+	defer snapshotsLock.Unlock()
+
+	previous := CurrentSnapshot()
+
+	snapshot.Version = previous.Version + 1
+
+	if snapshot.Version < versionEpoch {
+		snapshot.Version = versionEpoch
+	}
+
+	currentSnapshot.Store(snapshot)
+
+	// under the lock, so subscribers get each version's events in order
+	Events.Publish(snapshotEvents(previous, snapshot)...)
+
+	snapshotHistory[snapshot.Version] = snapshot.Index.Filtered(GetConfig().Filters, snapshot.Now())
+
+	if snapshot.Version > maxTokensHistory {
+		delete(snapshotHistory, snapshot.Version-maxTokensHistory)
+	}
+
+	close(snapshotChanged)
+	snapshotChanged = make(chan struct{})
+}
+
+// SnapshotsSince waits (up to wait, or until ctx is done) for a version newer than since, then returns the index of
+// since and the snapshot it's been replaced by. The old index is nil if since is too old to remember (or 0), or isn't
+// one of ours at all (e.g. it's from before a restart), there's no waiting for those.
+func SnapshotsSince(ctx context.Context, since uint64, wait time.Duration) (fromIndex *TokenIndex, to *Snapshot) {
+	snapshotsLock.Lock()
+	changed := snapshotChanged
+	current := CurrentSnapshot().Version
+	snapshotsLock.Unlock()
+
+	if current == since && wait > 0 {
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+
+		select {
+		case <-changed:
+		case <-timer.C:
+		case <-ctx.Done():
+		}
+	}
+
+	snapshotsLock.Lock()
+	defer snapshotsLock.Unlock()
+
+	return snapshotHistory[since], CurrentSnapshot()
+}
+
+// TimeUntilNextRefresh is how long clients can cache what we've got now
+func TimeUntilNextRefresh() time.Duration {
+	snapshot := CurrentSnapshot()
+
+	// a new snapshot is published whenever any source is polled
+	next := RefreshScheduler.NextRun()
+
+	if next.IsZero() {
+		next = snapshot.UpdatedAt.Add(RefreshInterval)
+	}
+
+	remaining := time.Until(next)
+
+	// the first refresh could land any moment
+	if remaining < 0 || snapshot.Stale {
+		return 0
+	}
+
+	return remaining
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/CADawg/hive-swap-calculator/pricing"
+)
+
+func TestPublishSnapshot(t *testing.T) {
+	published := &Snapshot{Tokens: []pricing.TokenData{{Symbol: "BTC", SwapSymbol: "SWAP.BTC"}}}
+
+	PublishSnapshot(published)
+
+	current := CurrentSnapshot()
+
+	if current != published {
+		t.Fatal("the published snapshot isn't the current one")
+	}
+
+	// an index is built if the snapshot didn't come with one
+	if _, ok := current.Index.Token("SWAP.BTC"); !ok {
+		t.Fatal("the published snapshot wasn't indexed")
+	}
+
+	// every version is remembered for a while, then forgotten
+	for i := 0; i < maxTokensHistory; i++ {
+		PublishSnapshot(&Snapshot{})
+	}
+
+	if fromIndex, _ := SnapshotsSince(context.Background(), current.Version+1, 0); fromIndex == nil {
+		t.Fatal("forgot a version that should still be in the history")
+	}
+
+	if fromIndex, _ := SnapshotsSince(context.Background(), current.Version, 0); fromIndex != nil {
+		t.Fatal("remembered a version that's too old")
+	}
+
+	snapshotsLock.Lock()
+	remembered := len(snapshotHistory)
+	snapshotsLock.Unlock()
+
+	if remembered > maxTokensHistory {
+		t.Fatalf("the history has %d versions, the most is %d", remembered, maxTokensHistory)
+	}
+}
+
+func TestPublishSnapshotConcurrently(t *testing.T) {
+	PublishSnapshot(&Snapshot{})
+	first := CurrentSnapshot().Version
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+
+		go func() {
+			defer wg.Done()
+			PublishSnapshot(&Snapshot{})
+		}()
+
+		go func() {
+			defer wg.Done()
+
+			// readers never see a version go backwards or an unindexed snapshot
+			if snapshot := CurrentSnapshot(); snapshot.Version < first || snapshot.Index == nil {
+				t.Errorf("read version %d with index %v", snapshot.Version, snapshot.Index)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	if version := CurrentSnapshot().Version; version != first+10 {
+		t.Fatalf("10 publishes took the version from %d to %d", first, version)
+	}
+}
+
+func TestTimeUntilNextRefresh(t *testing.T) {
+	// a stale snapshot could be replaced any moment
+	PublishSnapshot(&Snapshot{UpdatedAt: time.Now(), Stale: true})
+
+	if remaining := TimeUntilNextRefresh(); remaining != 0 {
+		t.Fatalf("a stale snapshot has %s to go", remaining)
+	}
+
+	PublishSnapshot(&Snapshot{UpdatedAt: time.Now()})
+
+	now := time.Now()
+
+	for _, source := range AllSources {
+		RefreshScheduler.Done(source, SourceResult{}, false, now)
+	}
+
+	next := RefreshScheduler.NextRun()
+
+	if remaining := TimeUntilNextRefresh(); remaining <= 0 || remaining > next.Sub(now) {
+		t.Fatalf("TimeUntilNextRefresh() = %s, next run is %s after %s", remaining, next, now)
+	}
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// how often (at most) we look at the cert files to see if they've been renewed
+const certCheckInterval = 10 * time.Second
+
+// CertReloader serves the certificate from disk, picking up a renewed cert/key without a restart
+type CertReloader struct {
+	certFile string
+	keyFile  string
+
+	lock        sync.Mutex
+	cert        *tls.Certificate
+	certModTime time.Time
+	keyModTime  time.Time
+	lastChecked time.Time
+}
+
+// NewCertReloader loads the cert and key straight away, so a bad pair stops startup instead of the first handshake
+func NewCertReloader(certFile string, keyFile string) (*CertReloader, error) {
+	reloader := &CertReloader{certFile: certFile, keyFile: keyFile}
+
+	err := reloader.reload()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return reloader, nil
+}
+
+func (c *CertReloader) reload() error {
+	certInfo, err := os.Stat(c.certFile)
+
+	if err != nil {
+		return err
+	}
+
+	keyInfo, err := os.Stat(c.keyFile)
+
+	if err != nil {
+		return err
+	}
+
+	c.lastChecked = time.Now()
+
+	if certInfo.ModTime().Equal(c.certModTime) && keyInfo.ModTime().Equal(c.keyModTime) {
+		return nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(c.certFile, c.keyFile)
+
+	if err != nil {
+		return err
+	}
+
+	if c.cert != nil {
+		fmt.Println("reloaded tls certificate from", c.certFile)
+	}
+
+	c.cert = &cert
+	c.certModTime = certInfo.ModTime()
+	c.keyModTime = keyInfo.ModTime()
+
+	return nil
+}
+
+// GetCertificate is for tls.Config, if a reload fails (e.g. we caught the files mid renewal) the old cert keeps being served
+func (c *CertReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if time.Since(c.lastChecked) > certCheckInterval {
+		err := c.reload()
+
+		if err != nil {
+			fmt.Println("error reloading tls certificate:", err)
+		}
+	}
+
+	return c.cert, nil
+}
+
+// NewTLSConfig gives modern defaults (TLS 1.2+, forward secret AEAD ciphers only)
+func NewTLSConfig(reloader *CertReloader) *tls.Config {
+	return &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: reloader.GetCertificate,
+		CurvePreferences: []tls.CurveID{
+			tls.X25519,
+			tls.CurveP256,
+		},
+		// only used for TLS 1.2, TLS 1.3 suites aren't configurable
+		CipherSuites: []uint16{
+			tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+			tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+			tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256,
+			tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256,
+		},
+		// http/2 first, ServeTLS would add it anyway but this makes it obvious
+		NextProtos: []string{"h2", "http/1.1"},
+	}
+}
+
+// RedirectToHTTPS sends plain http requests to the same path on the https listener
+func RedirectToHTTPS(httpsAddr string) http.Handler {
+	_, httpsPort, _ := net.SplitHostPort(httpsAddr)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, _, err := net.SplitHostPort(r.Host)
+
+		if err != nil {
+			// no port in the host header
+			host = r.Host
+		}
+
+		if httpsPort != "" && httpsPort != "443" {
+			host = net.JoinHostPort(host, httpsPort)
+		}
+
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusMovedPermanently)
+	})
+}
+
+// Serve runs the server with TLS if there's a cert configured (plus the redirect listener if asked for), or plain http if not
+func Serve(server *http.Server, config ServerConfig) error {
+	if config.TLSCertFile == "" && config.TLSKeyFile == "" {
+		return server.ListenAndServe()
+	}
+
+	if config.TLSCertFile == "" || config.TLSKeyFile == "" {
+		return errors.New("both tls_cert_file and tls_key_file need to be set for https")
+	}
+
+	reloader, err := NewCertReloader(config.TLSCertFile, config.TLSKeyFile)
+
+	if err != nil {
+		return err
+	}
+
+	server.TLSConfig = NewTLSConfig(reloader)
+
+	if config.RedirectHTTPAddr != "" {
+		go func() {
+			redirectServer := http.Server{
+				Addr:              config.RedirectHTTPAddr,
+				ReadHeaderTimeout: 5 * time.Second,
+				ReadTimeout:       5 * time.Second,
+				Handler:           RedirectToHTTPS(server.Addr),
+			}
+
+			err := redirectServer.ListenAndServe()
+
+			if err != nil && !errors.Is(err, http.ErrServerClosed) {
+				fmt.Println("error starting http redirect server:", err)
+			}
+		}()
+	}
+
+	// the cert comes from GetCertificate, not the files here
+	return server.ListenAndServeTLS("", "")
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeTestCert writes a self signed cert for commonName, with the files' mtime set to modTime
+func writeTestCert(t *testing.T, certFile string, keyFile string, commonName string, modTime time.Time) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writeTestFile(t, certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), modTime)
+	writeTestFile(t, keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), modTime)
+}
+
+func writeTestFile(t *testing.T, path string, data []byte, modTime time.Time) {
+	t.Helper()
+
+	err := os.WriteFile(path, data, 0600)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = os.Chtimes(path, modTime, modTime)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func certName(t *testing.T, reloader *CertReloader) string {
+	t.Helper()
+
+	cert, err := reloader.GetCertificate(nil)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parsed, err := x509.ParseCertificate(cert.Certificate[0])
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return parsed.Subject.CommonName
+}
+
+func TestCertReloader(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	start := time.Now().Add(-time.Hour)
+
+	writeTestCert(t, certFile, keyFile, "first", start)
+
+	reloader, err := NewCertReloader(certFile, keyFile)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if name := certName(t, reloader); name != "first" {
+		t.Fatalf("serving %s, want first", name)
+	}
+
+	// renewed, but it's not time to look yet
+	writeTestCert(t, certFile, keyFile, "second", start.Add(time.Minute))
+
+	if name := certName(t, reloader); name != "first" {
+		t.Fatalf("serving %s before the check interval, want first", name)
+	}
+
+	reloader.lastChecked = time.Now().Add(-2 * certCheckInterval)
+
+	if name := certName(t, reloader); name != "second" {
+		t.Fatalf("serving %s, want the renewed cert", name)
+	}
+
+	// caught mid renewal, the old cert keeps being served
+	writeTestFile(t, keyFile, []byte("not a key"), start.Add(2*time.Minute))
+	reloader.lastChecked = time.Now().Add(-2 * certCheckInterval)
+
+	if name := certName(t, reloader); name != "second" {
+		t.Fatalf("serving %s after a bad reload, want second", name)
+	}
+}
+
+func TestNewCertReloaderFailsOnBadPair(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+
+	if _, err := NewCertReloader(certFile, keyFile); err == nil {
+		t.Fatal("expected an error for missing files")
+	}
+
+	writeTestCert(t, certFile, keyFile, "cert", time.Now())
+	writeTestFile(t, keyFile, []byte("not a key"), time.Now())
+
+	if _, err := NewCertReloader(certFile, keyFile); err == nil {
+		t.Fatal("expected an error for a bad key")
+	}
+}
+
+func TestRedirectToHTTPS(t *testing.T) {
+	tests := []struct {
+		httpsAddr string
+		host      string
+		want      string
+	}{
+		{":443", "swap.example.com", "https://swap.example.com/prices?symbols=BTC"},
+		{":443", "swap.example.com:80", "https://swap.example.com/prices?symbols=BTC"},
+		{":8443", "swap.example.com:8080", "https://swap.example.com:8443/prices?symbols=BTC"},
+	}
+
+	for _, test := range tests {
+		r := httptest.NewRequest("GET", "http://"+test.host+"/prices?symbols=BTC", nil)
+		w := httptest.NewRecorder()
+
+		RedirectToHTTPS(test.httpsAddr).ServeHTTP(w, r)
+
+		if w.Code != http.StatusMovedPermanently || w.Header().Get("Location") != test.want {
+			t.Errorf("%s via %s: got %d to %q, want %q", test.host, test.httpsAddr, w.Code, w.Header().Get("Location"), test.want)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"strings"
+	"time"
+
+	"github.com/CADawg/hive-swap-calculator/engine"
+	"github.com/CADawg/hive-swap-calculator/market"
+	"github.com/CADawg/hive-swap-calculator/pricing"
+)
+
+// IndexedOrder is an order along with where it came from
+type IndexedOrder struct {
+	Order  engine.MarketOrder
+	Side   string
+	Symbol string
+}
+
+// TokenIndex lets the api look up tokens and orders without scanning Tokens on every request.
+// It's built once per refresh and never modified after, so it's safe to read without a lock (once you have it).
+type TokenIndex struct {
+	tokens map[string]*pricing.TokenData
+	orders map[string]IndexedOrder
+}
+
+func NewTokenIndex(tokens []pricing.TokenData) *TokenIndex {
+	index := &TokenIndex{
+		tokens: make(map[string]*pricing.TokenData, len(tokens)),
+		orders: map[string]IndexedOrder{},
+	}
+
+	for i := range tokens {
+		token := &tokens[i]
+
+		index.tokens[token.Symbol] = token
+
+		for _, order := range token.SellOrders {
+			index.orders[order.TransactionID] = IndexedOrder{Order: order, Side: market.SideSell, Symbol: token.Symbol}
+		}
+
+		for _, order := range token.BuyOrders {
+			index.orders[order.TransactionID] = IndexedOrder{Order: order, Side: market.SideBuy, Symbol: token.Symbol}
+		}
+	}
+
+	return index
+}
+
+// Token finds a token by symbol, either BTC or SWAP.BTC (any case)
+func (i *TokenIndex) Token(symbol string) (pricing.TokenData, bool) {
+	if i == nil {
+		return pricing.TokenData{}, false
+	}
+
+	token, ok := i.tokens[strings.TrimPrefix(strings.ToUpper(symbol), "SWAP.")]
+
+	if !ok {
+		return pricing.TokenData{}, false
+	}
+
+	return *token, true
+}
+
+// Order finds an order by its transaction id
+func (i *TokenIndex) Order(transactionID string) (IndexedOrder, bool) {
+	if i == nil {
+		return IndexedOrder{}, false
+	}
+
+	order, ok := i.orders[transactionID]
+
+	return order, ok
+}
+
+// FilteredOrder finds an order by its transaction id, as long as filter keeps it at now. The index has every order
+// past the reference price, this hides the same ones /prices does.
+func (i *TokenIndex) FilteredOrder(transactionID string, filter market.OrderFilter, now time.Time) (IndexedOrder, bool) {
+	order, ok := i.Order(transactionID)
+
+	if !ok {
+		return IndexedOrder{}, false
+	}
+
+	token, _ := i.Token(order.Symbol)
+
+	if !filter.Keep(token, order.Order, now) {
+		return IndexedOrder{}, false
+	}
+
+	return order, true
+}
+
+// Filtered is a new index with only the orders filter keeps at now (every token is still there, orders or not)
+func (i *TokenIndex) Filtered(filter market.OrderFilter, now time.Time) *TokenIndex {
+	if i == nil {
+		return nil
+	}
+
+	tokens := make([]pricing.TokenData, 0, len(i.tokens))
+
+	for _, token := range i.tokens {
+		tokens = append(tokens, *token)
+	}
+
+	return NewTokenIndex(filter.FilterTokens(tokens, now))
+}
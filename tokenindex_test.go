@@ -0,0 +1,223 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/CADawg/hive-swap-calculator/engine"
+	"github.com/CADawg/hive-swap-calculator/market"
+	"github.com/CADawg/hive-swap-calculator/pricing"
+	hiveswapv1 "github.com/CADawg/hive-swap-calculator/proto/hiveswap/v1"
+	"github.com/goccy/go-json"
+	"github.com/shopspring/decimal"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func indexTestTokens() []pricing.TokenData {
+	return []pricing.TokenData{
+		{
+			Symbol:     "BTC",
+			SwapSymbol: "SWAP.BTC",
+			HIVEPrice:  decimal.NewFromInt(100),
+			SellOrders: []engine.MarketOrder{{TransactionID: "sell-1", Symbol: "SWAP.BTC", Account: "alice", Price: decimal.NewFromInt(90), Quantity: decimal.NewFromInt(1)}},
+			BuyOrders:  []engine.MarketOrder{{TransactionID: "buy-1", Symbol: "SWAP.BTC", Account: "bob", Price: decimal.NewFromInt(110), Quantity: decimal.NewFromInt(1)}},
+		},
+		{Symbol: "ETH", SwapSymbol: "SWAP.ETH", HIVEPrice: decimal.NewFromInt(10)},
+	}
+}
+
+func TestTokenIndex(t *testing.T) {
+	index := NewTokenIndex(indexTestTokens())
+
+	for _, symbol := range []string{"BTC", "btc", "SWAP.BTC", "swap.btc"} {
+		if token, ok := index.Token(symbol); !ok || token.Symbol != "BTC" {
+			t.Errorf("Token(%s) = %s, %v", symbol, token.Symbol, ok)
+		}
+	}
+
+	if _, ok := index.Token("DOGE"); ok {
+		t.Error("found a token that isn't there")
+	}
+
+	if order, ok := index.Order("sell-1"); !ok || order.Side != market.SideSell || order.Symbol != "BTC" {
+		t.Errorf("Order(sell-1) = %+v, %v", order, ok)
+	}
+
+	if order, ok := index.Order("buy-1"); !ok || order.Side != market.SideBuy || order.Order.Account != "bob" {
+		t.Errorf("Order(buy-1) = %+v, %v", order, ok)
+	}
+
+	var nilIndex *TokenIndex
+
+	if _, ok := nilIndex.Token("BTC"); ok {
+		t.Error("a nil index found a token")
+	}
+
+	if _, ok := nilIndex.Order("sell-1"); ok {
+		t.Error("a nil index found an order")
+	}
+}
+
+func TestTokenAndOrderHandlers(t *testing.T) {
+	PublishSnapshot(&Snapshot{Tokens: indexTestTokens()})
+
+	mux, _, key := adminTestMux(t)
+
+	get := func(path string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", path, nil)
+		r.Header.Set(APIKeyHeader, key)
+
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+
+		return w
+	}
+
+	token := get("/api/v1/tokens/swap.btc")
+
+	var apiToken APIToken
+
+	if err := json.Unmarshal(token.Body.Bytes(), &apiToken); err != nil || apiToken.Symbol != "BTC" || len(apiToken.SellOrders) != 1 {
+		t.Fatalf("token got %d %s", token.Code, token.Body.String())
+	}
+
+	// it's only for keys with read_prices, so shared caches mustn't keep it
+	if cacheControl := token.Header().Get("Cache-Control"); !strings.HasPrefix(cacheControl, "private,") {
+		t.Fatalf("Cache-Control = %q, want private", cacheControl)
+	}
+
+	sparse := get("/api/v1/tokens/BTC?fields=symbol,hive")
+
+	if strings.TrimSpace(sparse.Body.String()) != `{"hive":"100","symbol":"BTC"}` {
+		t.Fatalf("sparse token got %d %s", sparse.Code, sparse.Body.String())
+	}
+
+	var orders []APIOrder
+
+	ordersResponse := get("/api/v1/tokens/BTC/orders?side=buy")
+
+	if err := json.Unmarshal(ordersResponse.Body.Bytes(), &orders); err != nil || len(orders) != 1 || orders[0].TransactionID != "buy-1" || orders[0].Side != market.SideBuy {
+		t.Fatalf("orders got %d %s", ordersResponse.Code, ordersResponse.Body.String())
+	}
+
+	var order APIOrder
+
+	orderResponse := get("/api/v1/orders/sell-1")
+
+	if err := json.Unmarshal(orderResponse.Body.Bytes(), &order); err != nil || order.Account != "alice" || order.Side != market.SideSell {
+		t.Fatalf("order got %d %s", orderResponse.Code, orderResponse.Body.String())
+	}
+
+	if code := get("/api/v1/tokens/ETH/orders").Code; code != http.StatusOK {
+		t.Fatalf("a token without orders got %d", code)
+	}
+}
+
+func TestWriteFirstTokenUnexpectedOutput(t *testing.T) {
+	r := httptest.NewRequest("GET", "/api/v1/tokens/BTC", nil)
+	w := httptest.NewRecorder()
+
+	writeFirstToken(w, r, []string{"BTC"})
+
+	if w.Code != http.StatusInternalServerError || w.Body.Len() == 0 {
+		t.Fatalf("got %d %q, want a 500 with a body", w.Code, w.Body.String())
+	}
+}
+
+func TestFilteredOrder(t *testing.T) {
+	tokens := indexTestTokens()
+	tokens[0].BuyOrders[0].Expiration = 1000
+
+	index := NewTokenIndex(tokens)
+	now := time.Unix(900, 0)
+
+	if _, ok := index.FilteredOrder("sell-1", market.OrderFilter{DenyAccounts: []string{"alice"}}, now); ok {
+		t.Error("found a denied account's order")
+	}
+
+	if _, ok := index.FilteredOrder("buy-1", market.OrderFilter{MinSecondsToExpiry: 200}, now); ok {
+		t.Error("found an order that's about to expire")
+	}
+
+	if order, ok := index.FilteredOrder("buy-1", market.OrderFilter{MinSecondsToExpiry: 50}, now); !ok || order.Order.Account != "bob" {
+		t.Errorf("FilteredOrder(buy-1) = %+v, %v", order, ok)
+	}
+
+	if _, ok := index.FilteredOrder("nope", market.OrderFilter{}, now); ok {
+		t.Error("found an order that isn't there")
+	}
+}
+
+func TestOrderLookupsHideFilteredOrders(t *testing.T) {
+	previous := GetConfig()
+	defer SetConfig(previous)
+
+	config := DefaultConfig()
+	config.Filters.DenyAccounts = []string{"alice"}
+	SetConfig(config)
+
+	PublishSnapshot(&Snapshot{Tokens: indexTestTokens()})
+
+	mux, _, key := adminTestMux(t)
+
+	r := httptest.NewRequest("GET", "/api/v1/orders/sell-1", nil)
+	r.Header.Set(APIKeyHeader, key)
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, r)
+
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("a denied account's order got %d %s", w.Code, w.Body.String())
+	}
+
+	server := &GRPCServer{}
+
+	if _, err := server.GetOrder(context.Background(), &hiveswapv1.GetOrderRequest{TxId: "sell-1"}); status.Code(err) != codes.NotFound {
+		t.Fatalf("GetOrder of a denied account's order got %v", err)
+	}
+
+	if order, err := server.GetOrder(context.Background(), &hiveswapv1.GetOrderRequest{TxId: "buy-1"}); err != nil || order.Account != "bob" {
+		t.Fatalf("GetOrder(buy-1) = %+v, %v", order, err)
+	}
+}
+
+func TestReplayedSnapshotsFilterAtTheirClock(t *testing.T) {
+	previous := GetConfig()
+	defer SetConfig(previous)
+
+	config := DefaultConfig()
+	config.Filters.MinSecondsToExpiry = 60
+	SetConfig(config)
+
+	// recorded a year ago, when the order had a day left
+	recordedAt := time.Now().AddDate(-1, 0, 0)
+
+	tokens := indexTestTokens()
+	tokens[0].SellOrders[0].Expiration = recordedAt.Add(24 * time.Hour).Unix()
+
+	PublishSnapshot(&Snapshot{Tokens: tokens, Clock: recordedAt})
+
+	mux, _, key := adminTestMux(t)
+
+	r := httptest.NewRequest("GET", "/api/v1/orders/sell-1", nil)
+	r.Header.Set(APIKeyHeader, key)
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("an order that hadn't expired when it was recorded got %d %s", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/prices?symbols=BTC", nil))
+
+	if !strings.Contains(w.Body.String(), "sell-1") {
+		t.Fatalf("/prices filtered at the real time: %s", w.Body.String())
+	}
+}
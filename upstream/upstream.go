@@ -0,0 +1,65 @@
+// Package upstream has what the clients for the services we poll (CoinGecko, the engine, Hive and the gateways) share
+package upstream
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/goccy/go-json"
+)
+
+// ErrRateLimited is what an upstream answering 429 gives, callers can back off harder for it than for other errors
+var ErrRateLimited = errors.New("rate limited")
+
+// CheckRateLimited turns a 429 into ErrRateLimited (anything else is left to the json decoding to complain about)
+func CheckRateLimited(resp *http.Response) error {
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return fmt.Errorf("%w by %s", ErrRateLimited, resp.Request.URL.Host)
+	}
+
+	return nil
+}
+
+// GetJSON fetches url with client and decodes the json it returns
+func GetJSON[T any](client *http.Client, url string) (*T, error) {
+	req, err := http.NewRequest("GET", url, nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+
+	err = CheckRateLimited(resp)
+
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := io.ReadAll(resp.Body)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var output T
+
+	err = json.Unmarshal(data, &output)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &output, nil
+}
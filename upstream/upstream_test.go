@@ -0,0 +1,53 @@
+package upstream
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type testPayload struct {
+	Name string `json:"name"`
+}
+
+func TestGetJSON(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ok":
+			if r.Header.Get("Accept") != "application/json" {
+				http.Error(w, "json only", http.StatusNotAcceptable)
+				return
+			}
+
+			_, _ = w.Write([]byte(`{"name":"hive"}`))
+		case "/limited":
+			w.WriteHeader(http.StatusTooManyRequests)
+		default:
+			_, _ = w.Write([]byte(`<html>not json</html>`))
+		}
+	}))
+	defer server.Close()
+
+	payload, err := GetJSON[testPayload](server.Client(), server.URL+"/ok")
+
+	if err != nil || payload.Name != "hive" {
+		t.Fatalf("GetJSON() = %+v, %v", payload, err)
+	}
+
+	_, err = GetJSON[testPayload](server.Client(), server.URL+"/limited")
+
+	// the host is in the message so the logs say who's limiting us
+	if !errors.Is(err, ErrRateLimited) || !strings.Contains(err.Error(), strings.TrimPrefix(server.URL, "http://")) {
+		t.Fatalf("got %v, want a rate limit", err)
+	}
+
+	if _, err = GetJSON[testPayload](server.Client(), server.URL+"/html"); err == nil || errors.Is(err, ErrRateLimited) {
+		t.Fatalf("got %v, want a json error", err)
+	}
+
+	if _, err = GetJSON[testPayload](server.Client(), "http://[::1"); err == nil {
+		t.Fatal("a bad url worked")
+	}
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/CADawg/hive-swap-calculator/engine"
+	"github.com/CADawg/hive-swap-calculator/gateway"
+	"github.com/CADawg/hive-swap-calculator/hive"
+	"github.com/CADawg/hive-swap-calculator/pricing"
+)
+
+// upstreamTimeout is the longest any one upstream request can take. Every source is polled from the one refresh
+// loop, so without it an upstream that never answers would hold up all the others (and never count as failed).
+const upstreamTimeout = 20 * time.Second
+
+// upstreamClient is what every upstream request (CoinGecko, the engine, Hive and the gateways) goes through, so the
+// traffic can be recorded, replayed or simulated underneath them
+var upstreamClient = &http.Client{Transport: http.DefaultTransport, Timeout: upstreamTimeout}
+
+// the clients the refresh uses for each upstream
+var (
+	CoinGecko = pricing.NewClient(pricing.DefaultCoinGeckoAPI, upstreamClient)
+	Engine    = engine.NewClient(engine.DefaultNode, upstreamClient)
+	Hive      = hive.NewClient(hive.DefaultNode, upstreamClient)
+	Gateways  = gateway.NewClient(upstreamClient, gateway.DefaultNetworks)
+
+	// WithdrawalFees are the gateway tokens and their flat fees, kept up to date by the fees stage
+	WithdrawalFees = gateway.NewStore(Gateways)
+)